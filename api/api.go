@@ -22,6 +22,16 @@ type Client interface {
 	UpdatePolicies(context.Context, policy.Updates, update.Cause) (job.ID, error)
 	Export(context.Context) ([]byte, error)
 	PublicSSHKey(ctx context.Context, regenerate bool) (ssh.PublicKey, error)
+	Diff(context.Context, update.ResourceSpec) ([]flux.ResourceDiff, error)
+	SyncOutcome(context.Context) (flux.SyncOutcome, error)
+	ListResources(ctx context.Context, namespace string) ([]flux.ResourceStatus, error)
+	GitRepoConfig(context.Context) (flux.GitConfig, error)
+	Rollback(context.Context, update.RollbackSpec, update.Cause) (job.ID, error)
+	Suspend(context.Context, flux.Suspension) (flux.Suspension, error)
+	Resume(context.Context, flux.Suspension) (flux.Suspension, error)
+	ListPending(context.Context) ([]update.PendingRelease, error)
+	ApprovePending(context.Context, string, update.Cause) (job.ID, error)
+	RejectPending(context.Context, string, update.Cause) error
 }
 
 // API for daemons connecting to an upstream service
@@ -0,0 +1,89 @@
+// Package changerequest is for asking for changes that fluxd has
+// pushed to a branch of their own to be merged into the branch it
+// syncs from; for example, by opening a pull request.
+package changerequest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/job"
+)
+
+// Request describes the changes to be merged.
+type Request struct {
+	JobID    job.ID               `json:"jobID"`
+	Repo     flux.GitRemoteConfig `json:"repo"`
+	Branch   string               `json:"branch"` // the branch with the changes
+	Base     string               `json:"base"`   // the branch to merge them into
+	Revision string               `json:"revision"`
+	Title    string               `json:"title"`
+	Message  string               `json:"message"`
+}
+
+// Requester makes change requests. It's called after the branch has
+// been pushed.
+type Requester interface {
+	// Open makes a request, and returns a URL or some other reference
+	// to it, if there is one.
+	Open(context.Context, Request) (string, error)
+}
+
+// PushOnly doesn't do anything beyond pushing the branch; e.g.,
+// because the git host offers to open a pull request for new
+// branches, or because something else is watching for them.
+type PushOnly struct{}
+
+func (PushOnly) Open(context.Context, Request) (string, error) {
+	return "", nil
+}
+
+// Webhook POSTs each request, as JSON, to a URL. If the response has
+// a JSON body with a `url` field, that is used as the reference to
+// the request.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w Webhook) Open(ctx context.Context, r Request) (string, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "sending change request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("change request webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var reply struct {
+		URL string `json:"url"`
+	}
+	// Any body is optional, so don't fail if it's not what we expect
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", nil
+	}
+	return reply.URL, nil
+}
@@ -0,0 +1,67 @@
+package changerequest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	var got Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"url": "https://example.com/pulls/1"}`))
+	}))
+	defer server.Close()
+
+	req := Request{
+		JobID:    "job1",
+		Branch:   "flux/release-job1",
+		Base:     "master",
+		Revision: "abcdef",
+		Title:    "Release",
+	}
+	url, err := Webhook{URL: server.URL}.Open(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://example.com/pulls/1" {
+		t.Errorf("expected URL from response, got %q", url)
+	}
+	if got.Branch != req.Branch || got.Base != req.Base || got.Revision != req.Revision {
+		t.Errorf("expected request to be sent as given, got %#v", got)
+	}
+}
+
+func TestWebhookNoBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	url, err := Webhook{URL: server.URL}.Open(context.Background(), Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "" {
+		t.Errorf("expected no URL, got %q", url)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if _, err := (Webhook{URL: server.URL}).Open(context.Background(), Request{}); err == nil {
+		t.Error("expected error from webhook returning 403")
+	}
+}
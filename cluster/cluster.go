@@ -29,10 +29,31 @@ type Cluster interface {
 type Controller struct {
 	ID     flux.ResourceID
 	Status string // A status summary for display
+	// The progress of the controller's rollout, where that applies
+	Rollout RolloutStatus
 
 	Containers ContainersOrExcuse
 }
 
+// RolloutStatus gives the progress of rolling out a controller's
+// latest definition; e.g., a new image.
+type RolloutStatus struct {
+	// The generation of the definition, and the generation the
+	// cluster has acted on
+	Generation, ObservedGeneration int64
+	// Desired, Updated and Available count replicas (or pods, for a
+	// DaemonSet)
+	Desired, Updated, Available int32
+}
+
+// Complete says whether the controller's latest definition has been
+// rolled out, and all of its replicas are updated and available.
+func (r RolloutStatus) Complete() bool {
+	return r.ObservedGeneration >= r.Generation &&
+		r.Updated == r.Desired &&
+		r.Available == r.Desired
+}
+
 // A Container represents a container specification in a pod. The Name
 // identifies it within the pod, and the Image says which image it's
 // configured to run.
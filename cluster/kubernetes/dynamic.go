@@ -0,0 +1,212 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	k8syaml "github.com/ghodss/yaml"
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/weaveworks/flux/cluster"
+)
+
+// This is the annotation kubectl uses to record the configuration
+// it last applied; using the same one means we can take over from
+// kubectl (or vice versa) without spurious changes.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// DynamicApplier is an Applier that talks to the API server
+// directly, rather than running kubectl. It finds the API resource
+// for each kind using discovery, and creates or patches objects with
+// the dynamic client.
+type DynamicApplier struct {
+	discovery discovery.DiscoveryInterface
+	clients   dynamic.ClientPool
+}
+
+func NewDynamicApplier(discovery discovery.DiscoveryInterface, clients dynamic.ClientPool) *DynamicApplier {
+	return &DynamicApplier{
+		discovery: discovery,
+		clients:   clients,
+	}
+}
+
+// ObjectError is the error recorded in a cluster.SyncError for each
+// object the DynamicApplier fails to apply or delete. Err is the
+// error as returned from the API, so it can be examined with
+// e.g., apierrors.IsInvalid.
+type ObjectError struct {
+	ID   string
+	Op   string // "apply" or "delete"
+	Kind schema.GroupVersionKind
+	Err  error
+}
+
+func (err *ObjectError) Error() string {
+	return fmt.Sprintf("%s %s: %s", err.Op, err.ID, err.Err.Error())
+}
+
+func (err *ObjectError) Cause() error {
+	return err.Err
+}
+
+func (a *DynamicApplier) apply(logger log.Logger, cs changeSet, errs cluster.SyncError) {
+	resources := apiResources{discovery: a.discovery}
+	f := func(objs []obj, op string) {
+		for _, o := range objs {
+			begin := time.Now()
+			kind, err := a.do(&resources, op, o)
+			if err != nil {
+				errs[o.id] = &ObjectError{ID: o.id, Op: op, Kind: kind, Err: err}
+			}
+			logger.Log("op", op, "resource", o.id, "took", time.Since(begin), "err", err)
+		}
+	}
+
+	for _, phase := range cs.phases("delete") {
+		f(phase, "delete")
+	}
+	for _, phase := range cs.phases("apply") {
+		f(phase, "apply")
+	}
+}
+
+func (a *DynamicApplier) do(resources *apiResources, op string, o obj) (schema.GroupVersionKind, error) {
+	var desired unstructured.Unstructured
+	if err := yamlToObject(o.bytes, &desired.Object); err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	kind := desired.GroupVersionKind()
+
+	resource, err := resources.forKind(kind)
+	if err != nil {
+		return kind, err
+	}
+	client, err := a.clients.ClientForGroupVersionKind(kind)
+	if err != nil {
+		return kind, errors.Wrap(err, "getting dynamic client")
+	}
+	namespace := desired.GetNamespace()
+	switch {
+	case !resource.Namespaced:
+		namespace = ""
+	case namespace == "":
+		namespace = "default"
+	}
+	rc := client.Resource(resource, namespace)
+
+	switch op {
+	case "delete":
+		err = rc.Delete(desired.GetName(), &meta_v1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			err = nil
+		}
+		return kind, err
+	default:
+		return kind, applyObject(rc, &desired)
+	}
+}
+
+// applyObject creates the object if it doesn't exist, and otherwise
+// patches it with a three-way merge of the last applied
+// configuration, the desired configuration, and what's in the
+// cluster. Like kubectl does for kinds it doesn't know the schema
+// of, this uses a JSON merge patch, so lists are replaced rather
+// than merged.
+func applyObject(rc dynamic.ResourceInterface, desired *unstructured.Unstructured) error {
+	annotations := desired.GetAnnotations()
+	if _, ok := annotations[lastAppliedAnnotation]; ok {
+		delete(annotations, lastAppliedAnnotation)
+		desired.SetAnnotations(annotations)
+	}
+	config, err := json.Marshal(desired.Object)
+	if err != nil {
+		return err
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastAppliedAnnotation] = string(config)
+	desired.SetAnnotations(annotations)
+
+	current, err := rc.Get(desired.GetName(), meta_v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = rc.Create(desired)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	original := []byte(current.GetAnnotations()[lastAppliedAnnotation])
+	modified, err := json.Marshal(desired.Object)
+	if err != nil {
+		return err
+	}
+	currentBytes, err := json.Marshal(current.Object)
+	if err != nil {
+		return err
+	}
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, currentBytes)
+	if err != nil {
+		return errors.Wrap(err, "calculating patch")
+	}
+	if string(patch) == "{}" {
+		return nil
+	}
+	_, err = rc.Patch(desired.GetName(), types.MergePatchType, patch)
+	return err
+}
+
+func yamlToObject(def []byte, into *map[string]interface{}) error {
+	j, err := k8syaml.YAMLToJSON(def)
+	if err != nil {
+		return errors.Wrap(err, "parsing YAML")
+	}
+	return json.Unmarshal(j, into)
+}
+
+// apiResources looks up the API resource for a kind, remembering the
+// answers for each group version.
+type apiResources struct {
+	discovery discovery.DiscoveryInterface
+	byGroup   map[schema.GroupVersion]*meta_v1.APIResourceList
+}
+
+func (r *apiResources) forKind(kind schema.GroupVersionKind) (*meta_v1.APIResource, error) {
+	gv := kind.GroupVersion()
+	list, ok := r.byGroup[gv]
+	if !ok {
+		var err error
+		list, err = r.discovery.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "discovering resources for %s", gv)
+		}
+		if list == nil {
+			list = &meta_v1.APIResourceList{}
+		}
+		if r.byGroup == nil {
+			r.byGroup = map[schema.GroupVersion]*meta_v1.APIResourceList{}
+		}
+		r.byGroup[gv] = list
+	}
+	for i, res := range list.APIResources {
+		// Subresources (e.g., deployments/scale) have the same kind
+		// as their parent, so skip those
+		if res.Kind == kind.Kind && !strings.Contains(res.Name, "/") {
+			return &list.APIResources[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no API resource found for kind %s", kind)
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/weaveworks/flux/cluster"
+)
+
+const (
+	fooDeployment = `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: bar
+spec:
+  replicas: 2
+`
+	barNamespace = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: bar
+`
+	unknownKind = `apiVersion: example.com/v1
+kind: Frobnicator
+metadata:
+  name: frob
+`
+)
+
+func setupDynamic(t *testing.T) (*DynamicApplier, *dynamicfake.FakeClientPool, map[string]*unstructured.Unstructured) {
+	disco := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	disco.Resources = []*meta_v1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []meta_v1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+			},
+		},
+		{
+			GroupVersion: "extensions/v1beta1",
+			APIResources: []meta_v1.APIResource{
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			},
+		},
+	}
+
+	// A very simple object store, enough to see what was created and
+	// patched.
+	objects := map[string]*unstructured.Unstructured{}
+	pool := &dynamicfake.FakeClientPool{}
+	pool.AddReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		key := get.GetNamespace() + "/" + get.GetName()
+		if obj, ok := objects[key]; ok {
+			return true, obj, nil
+		}
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: get.GetResource().Resource}, get.GetName())
+	})
+	pool.AddReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		objects[action.GetNamespace()+"/"+obj.GetName()] = obj
+		return true, obj, nil
+	})
+	pool.AddReactor("*", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{}, nil
+	})
+	return NewDynamicApplier(disco, pool), pool, objects
+}
+
+func stageDynamic(t *testing.T, cs changeSet, cmd, id, def string) {
+	o, err := definitionObj([]byte(def))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.stage(cmd, id, o)
+}
+
+func TestDynamicApplyCreates(t *testing.T) {
+	applier, pool, objects := setupDynamic(t)
+
+	cs := makeChangeSet()
+	stageDynamic(t, cs, "apply", "bar:deployment/foo", fooDeployment)
+	stageDynamic(t, cs, "apply", "<cluster>:namespace/bar", barNamespace)
+	errs := cluster.SyncError{}
+	applier.apply(log.NewNopLogger(), cs, errs)
+	if len(errs) > 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	// The namespace goes first
+	var creates []string
+	for _, action := range pool.Actions() {
+		if action.GetVerb() == "create" {
+			creates = append(creates, action.GetResource().Resource)
+		}
+	}
+	if len(creates) != 2 || creates[0] != "namespaces" || creates[1] != "deployments" {
+		t.Errorf("expected the namespace then the deployment to be created, got %v", creates)
+	}
+
+	deployment, ok := objects["bar/foo"]
+	if !ok {
+		t.Fatalf("expected deployment to be created, got %v", objects)
+	}
+	if _, ok := deployment.GetAnnotations()[lastAppliedAnnotation]; !ok {
+		t.Errorf("expected created object to record the applied config")
+	}
+	if _, ok := objects["/bar"]; !ok {
+		t.Errorf("expected namespace to be created without a namespace, got %v", objects)
+	}
+}
+
+func TestDynamicApplyPatches(t *testing.T) {
+	applier, pool, objects := setupDynamic(t)
+
+	cs := makeChangeSet()
+	stageDynamic(t, cs, "apply", "bar:deployment/foo", fooDeployment)
+	applier.apply(log.NewNopLogger(), cs, cluster.SyncError{})
+
+	// Something else sets a field we don't know about; then we
+	// change the replicas.
+	existing := objects["bar/foo"]
+	unstructured.SetNestedField(existing.Object, "Recreate", "spec", "strategy", "type")
+
+	cs = makeChangeSet()
+	stageDynamic(t, cs, "apply", "bar:deployment/foo", fooDeployment[:len(fooDeployment)-len("2\n")]+"3\n")
+	errs := cluster.SyncError{}
+	applier.apply(log.NewNopLogger(), cs, errs)
+	if len(errs) > 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	var patch []byte
+	for _, action := range pool.Actions() {
+		if action.GetVerb() == "patch" {
+			patch = action.(k8stesting.PatchAction).GetPatch()
+		}
+	}
+	if patch == nil {
+		t.Fatal("expected the deployment to be patched")
+	}
+	var p map[string]interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := p["spec"].(map[string]interface{})
+	if spec["replicas"] != float64(3) {
+		t.Errorf("expected replicas to be patched, got %s", patch)
+	}
+	if _, ok := spec["strategy"]; ok {
+		t.Errorf("expected field set by something else to be left alone, got %s", patch)
+	}
+}
+
+func TestDynamicApplyNoChange(t *testing.T) {
+	applier, pool, _ := setupDynamic(t)
+
+	cs := makeChangeSet()
+	stageDynamic(t, cs, "apply", "bar:deployment/foo", fooDeployment)
+	applier.apply(log.NewNopLogger(), cs, cluster.SyncError{})
+	applier.apply(log.NewNopLogger(), cs, cluster.SyncError{})
+
+	for _, action := range pool.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("expected no patch when nothing has changed, got %s", action.(k8stesting.PatchAction).GetPatch())
+		}
+	}
+}
+
+func TestDynamicApplyErrors(t *testing.T) {
+	applier, _, _ := setupDynamic(t)
+
+	cs := makeChangeSet()
+	stageDynamic(t, cs, "apply", "<cluster>:frobnicator/frob", unknownKind)
+	stageDynamic(t, cs, "apply", "bar:deployment/foo", fooDeployment)
+	errs := cluster.SyncError{}
+	applier.apply(log.NewNopLogger(), cs, errs)
+
+	if len(errs) != 1 {
+		t.Fatalf("expected exactly one error, got %v", errs)
+	}
+	err, ok := errs["<cluster>:frobnicator/frob"].(*ObjectError)
+	if !ok {
+		t.Fatalf("expected an *ObjectError, got %#v", errs["<cluster>:frobnicator/frob"])
+	}
+	if err.Op != "apply" || err.Kind.Kind != "Frobnicator" {
+		t.Errorf("unexpected error %#v", err)
+	}
+}
@@ -9,11 +9,11 @@ import (
 )
 
 // FindDefinedServices finds all the services defined under the
-// directory given, and returns a map of service IDs (from its
+// directories given, and returns a map of service IDs (from its
 // specified namespace and name) to the paths of resource definition
 // files.
-func (c *Manifests) FindDefinedServices(path string) (map[flux.ResourceID][]string, error) {
-	objects, err := resource.Load(path)
+func (c *Manifests) FindDefinedServices(paths ...string) (map[flux.ResourceID][]string, error) {
+	objects, err := resource.Load(paths...)
 	if err != nil {
 		return nil, errors.Wrap(err, "loading resources")
 	}
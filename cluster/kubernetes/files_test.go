@@ -1,9 +1,12 @@
 package kubernetes
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
 )
 
@@ -24,3 +27,34 @@ func TestDefinedServices(t *testing.T) {
 		t.Errorf("Expected:\n%#v\ngot:\n%#v\n", testfiles.ServiceMap(dir), services)
 	}
 }
+
+func TestDefinedServicesMultiplePaths(t *testing.T) {
+	dir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	base, prod := filepath.Join(dir, "base"), filepath.Join(dir, "prod")
+	for _, d := range []string{base, prod} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Everything in base, except for one service in prod
+	if err := testfiles.WriteTestFiles(base); err != nil {
+		t.Fatal(err)
+	}
+	moved := "helloworld-deploy.yaml"
+	if err := os.Rename(filepath.Join(base, moved), filepath.Join(prod, moved)); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := (&Manifests{}).FindDefinedServices(base, prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := testfiles.ServiceMap(base)
+	expected[flux.MustParseResourceID("default:deployment/helloworld")] = []string{filepath.Join(prod, moved)}
+	if !reflect.DeepEqual(expected, services) {
+		t.Errorf("Expected:\n%#v\ngot:\n%#v\n", expected, services)
+	}
+}
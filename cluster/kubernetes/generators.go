@@ -0,0 +1,389 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+)
+
+// ConfigFilename is the name of the file that, when present in a
+// directory given as a git path, says how to generate the manifests
+// for that directory rather than reading them from files.
+const ConfigFilename = ".flux.yaml"
+
+// DefaultGeneratorTimeout is how long a generator or updater command
+// is given to run, if not otherwise specified.
+const DefaultGeneratorTimeout = time.Minute
+
+// GeneratorConfig is the content of a config file, e.g.,
+//
+//	version: 1
+//	generators:
+//	- command: kustomize build .
+//	updaters:
+//	- containerImage:
+//	    command: ./set-image.sh
+//	  policy:
+//	    command: ./set-policy.sh
+//
+// Each generator command is run in the directory with the config
+// file, and is expected to print a multi-doc YAML stream to
+// stdout. The updater commands are run, in the same directory, to
+// change an image or a policy; they get what to change from the
+// environment variables FLUX_WORKLOAD, FLUX_CONTAINER, FLUX_IMG,
+// FLUX_TAG, FLUX_DIGEST, FLUX_POLICY and FLUX_POLICY_VALUE.
+// FLUX_DIGEST is empty unless the image is to be pinned to a digest,
+// in which case the updater should include it (as in
+// `$FLUX_IMG:$FLUX_TAG@$FLUX_DIGEST`).
+type GeneratorConfig struct {
+	Version    int         `yaml:"version"`
+	Generators []Generator `yaml:"generators"`
+	Updaters   []Updater   `yaml:"updaters"`
+}
+
+type Generator struct {
+	Command string `yaml:"command"`
+}
+
+type Updater struct {
+	ContainerImage Command `yaml:"containerImage"`
+	Policy         Command `yaml:"policy"`
+}
+
+type Command struct {
+	Command string `yaml:"command"`
+}
+
+// ParseGeneratorConfig parses and checks the content of a config
+// file.
+func ParseGeneratorConfig(def []byte) (GeneratorConfig, error) {
+	var config GeneratorConfig
+	if err := yaml.Unmarshal(def, &config); err != nil {
+		return config, errors.Wrap(err, "parsing generator config")
+	}
+	if config.Version != 1 {
+		return config, fmt.Errorf("unsupported generator config version %d (expected 1)", config.Version)
+	}
+	if len(config.Generators) == 0 {
+		return config, errors.New("no generators given in generator config")
+	}
+	return config, nil
+}
+
+// GeneratorManifests is a cluster.Manifests (and
+// cluster.GeneratedManifests) that runs the commands given in a
+// config file to get the manifests for any directory that has one,
+// and otherwise reads manifests from files as Manifests does.
+type GeneratorManifests struct {
+	Manifests
+	Timeout time.Duration
+}
+
+func NewGeneratorManifests(timeout time.Duration) *GeneratorManifests {
+	if timeout <= 0 {
+		timeout = DefaultGeneratorTimeout
+	}
+	return &GeneratorManifests{Timeout: timeout}
+}
+
+// generatorDir finds the directory with a config file that applies
+// to the path given, if there is one; that is, the path itself, or
+// the closest directory above it. It doesn't look above the top of
+// the git repo.
+func generatorDir(path string) (string, bool) {
+	dir := path
+	if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ConfigFilename)); err == nil {
+			return dir, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// split sorts the paths given into those for which manifests are
+// generated, as the directories with config files, and the rest.
+func split(paths []string) (generated, files []string) {
+	seen := map[string]bool{}
+	for _, path := range paths {
+		dir, ok := generatorDir(path)
+		switch {
+		case !ok:
+			files = append(files, path)
+		case !seen[dir]:
+			seen[dir] = true
+			generated = append(generated, dir)
+		}
+	}
+	return generated, files
+}
+
+func (m *GeneratorManifests) LoadManifests(paths ...string) (map[string]resource.Resource, error) {
+	generated, files := split(paths)
+	result := map[string]resource.Resource{}
+	if len(files) > 0 {
+		var err error
+		if result, err = m.Manifests.LoadManifests(files...); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range generated {
+		resources, err := m.generate(dir)
+		if err != nil {
+			return nil, err
+		}
+		for id, res := range resources {
+			if other, ok := result[id]; ok {
+				return nil, fmt.Errorf(`resource '%s' defined more than once (in %s and %s)`, id, other.Source(), res.Source())
+			}
+			result[id] = res
+		}
+	}
+	return result, nil
+}
+
+// FindDefinedServices gives the path of the config file for each
+// generated service, since there's no file with the definition in
+// it.
+func (m *GeneratorManifests) FindDefinedServices(paths ...string) (map[flux.ResourceID][]string, error) {
+	generated, files := split(paths)
+	result := map[flux.ResourceID][]string{}
+	if len(files) > 0 {
+		var err error
+		if result, err = m.Manifests.FindDefinedServices(files...); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range generated {
+		resources, err := m.generate(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range resources {
+			id := res.ResourceID()
+			_, kind, _ := id.Components()
+			if _, ok := resourceKinds[kind]; ok {
+				result[id] = append(result[id], res.Source())
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *GeneratorManifests) ServicesWithPolicies(paths ...string) (policy.ResourceMap, error) {
+	generated, files := split(paths)
+	// Controllers inherit policies from their namespace, wherever
+	// each is defined; so generate everything before looking at
+	// either.
+	namespaces := map[string]policy.Set{}
+	if len(files) > 0 {
+		var err error
+		if namespaces, err = m.Manifests.NamespacesWithPolicies(files...); err != nil {
+			return nil, err
+		}
+	}
+	var resources []map[string]resource.Resource
+	for _, dir := range generated {
+		res, err := m.generate(dir)
+		if err != nil {
+			return nil, err
+		}
+		generatedNamespaces, err := namespacePolicies(res)
+		if err != nil {
+			return nil, err
+		}
+		for ns, policies := range generatedNamespaces {
+			namespaces[ns] = policies
+		}
+		resources = append(resources, res)
+	}
+
+	result := policy.ResourceMap{}
+	if len(files) > 0 {
+		var err error
+		if result, err = m.Manifests.servicesWithPolicies(namespaces, files...); err != nil {
+			return nil, err
+		}
+	}
+	for _, res := range resources {
+		for _, r := range res {
+			id := r.ResourceID()
+			ns, kind, _ := id.Components()
+			if _, ok := resourceKinds[kind]; !ok {
+				continue
+			}
+			manifest, err := parseManifest(r.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			if result[id], err = inheritPolicies(namespaces[ns], manifest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// NamespacesWithPolicies gives the policies annotated on each
+// namespace, whether defined in files or generated.
+func (m *GeneratorManifests) NamespacesWithPolicies(paths ...string) (map[string]policy.Set, error) {
+	resources, err := m.LoadManifests(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return namespacePolicies(resources)
+}
+
+func (m *GeneratorManifests) IsGenerated(path string) bool {
+	return filepath.Base(path) == ConfigFilename
+}
+
+func (m *GeneratorManifests) SetGeneratedImage(path string, id flux.ResourceID, container string, newImageID image.Ref) error {
+	config, err := readGeneratorConfig(path)
+	if err != nil {
+		return err
+	}
+	env := []string{
+		"FLUX_WORKLOAD=" + id.String(),
+		"FLUX_CONTAINER=" + container,
+		"FLUX_IMG=" + newImageID.Name.String(),
+		"FLUX_TAG=" + newImageID.Tag,
+		"FLUX_DIGEST=" + newImageID.Digest,
+	}
+	return m.runUpdaters(filepath.Dir(path), config, func(u Updater) Command { return u.ContainerImage }, env)
+}
+
+// UpdateGeneratedPolicies runs the policy updater once for each
+// policy added or removed; for a removed policy, FLUX_POLICY_VALUE
+// is empty.
+func (m *GeneratorManifests) UpdateGeneratedPolicies(path string, id flux.ResourceID, update policy.Update) error {
+	config, err := readGeneratorConfig(path)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	f := func(p policy.Policy, value string) error {
+		env := []string{
+			"FLUX_WORKLOAD=" + id.String(),
+			"FLUX_POLICY=" + string(p),
+			"FLUX_POLICY_VALUE=" + value,
+		}
+		return m.runUpdaters(dir, config, func(u Updater) Command { return u.Policy }, env)
+	}
+	for p, value := range update.Add {
+		if err := f(p, value); err != nil {
+			return err
+		}
+	}
+	for p := range update.Remove {
+		if _, ok := update.Add[p]; ok {
+			continue
+		}
+		if err := f(p, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *GeneratorManifests) runUpdaters(dir string, config GeneratorConfig, which func(Updater) Command, env []string) error {
+	var ran bool
+	for _, u := range config.Updaters {
+		c := which(u)
+		if c.Command == "" {
+			continue
+		}
+		if _, err := m.run(dir, c.Command, env); err != nil {
+			return err
+		}
+		ran = true
+	}
+	if !ran {
+		return fmt.Errorf("no updater given in %s for this kind of update", filepath.Join(dir, ConfigFilename))
+	}
+	return nil
+}
+
+func (m *GeneratorManifests) generate(dir string) (map[string]resource.Resource, error) {
+	configPath := filepath.Join(dir, ConfigFilename)
+	config, err := readGeneratorConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]resource.Resource{}
+	for _, g := range config.Generators {
+		out, err := m.run(dir, g.Command, nil)
+		if err != nil {
+			return nil, err
+		}
+		resources, err := kresource.ParseMultidoc(out, configPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing output of generator %q", g.Command)
+		}
+		for id, res := range resources {
+			if _, ok := result[id]; ok {
+				return nil, fmt.Errorf(`resource '%s' generated more than once by %s`, id, configPath)
+			}
+			result[id] = res
+		}
+	}
+	return result, nil
+}
+
+func readGeneratorConfig(path string) (GeneratorConfig, error) {
+	def, err := ioutil.ReadFile(path)
+	if err != nil {
+		return GeneratorConfig{}, err
+	}
+	config, err := ParseGeneratorConfig(def)
+	return config, errors.Wrapf(err, "reading %s", path)
+}
+
+// run runs a command with the shell, in the directory given, and
+// returns what it printed to stdout.
+func (m *GeneratorManifests) run(dir, command string, env []string) ([]byte, error) {
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = DefaultGeneratorTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = ctx.Err()
+		}
+		return nil, errors.Wrapf(err, "running %q in %s: %s", command, dir, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,211 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
+)
+
+const (
+	generatorConfig = `version: 1
+generators:
+- command: cat deployment.tmpl
+updaters:
+- containerImage:
+    command: env | grep ^FLUX_ | sort > image.out
+  policy:
+    command: env | grep ^FLUX_ | sort >> policy.out
+`
+	generatedDeployment = `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: generated
+  namespace: default
+  annotations:
+    flux.weave.works/automated: "true"
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: example.com/app:1.0
+`
+)
+
+// setupGenerated makes a directory with a generator config in it,
+// and another with plain manifest files, and returns both.
+func setupGenerated(t *testing.T) (string, string, func()) {
+	dir, cleanup := testfiles.TempDir(t)
+	generated, files := filepath.Join(dir, "generated"), filepath.Join(dir, "files")
+	for _, d := range []string{generated, files} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		ConfigFilename:    generatorConfig,
+		"deployment.tmpl": generatedDeployment,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(generated, name), []byte(content), 0666); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	if err := testfiles.WriteTestFiles(files); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return generated, files, cleanup
+}
+
+func TestGeneratorLoadManifests(t *testing.T) {
+	generated, files, cleanup := setupGenerated(t)
+	defer cleanup()
+
+	m := NewGeneratorManifests(0)
+	resources, err := m.LoadManifests(generated, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := resources["default:deployment/generated"]
+	if !ok {
+		t.Fatalf("expected generated deployment to be loaded, got %v", resources)
+	}
+	if res.Source() != filepath.Join(generated, ConfigFilename) {
+		t.Errorf("expected source of generated resource to be the config file, got %q", res.Source())
+	}
+	if !res.Policy().Contains(policy.Automated) {
+		t.Errorf("expected policies from generated resource, got %v", res.Policy())
+	}
+	if _, ok := resources["default:deployment/helloworld"]; !ok {
+		t.Errorf("expected resources from files to be loaded too, got %v", resources)
+	}
+
+	// A file under the generated directory, as reported when it
+	// changes, means everything generated there
+	resources, err = m.LoadManifests(filepath.Join(generated, "deployment.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resources["default:deployment/generated"]; !ok || len(resources) != 1 {
+		t.Errorf("expected just the generated deployment, got %v", resources)
+	}
+}
+
+func TestGeneratorFindDefinedServices(t *testing.T) {
+	generated, files, cleanup := setupGenerated(t)
+	defer cleanup()
+
+	m := NewGeneratorManifests(0)
+	services, err := m.FindDefinedServices(generated, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := flux.MustParseResourceID("default:deployment/generated")
+	paths := services[id]
+	if len(paths) != 1 || !m.IsGenerated(paths[0]) {
+		t.Fatalf("expected generated service to be found at the config file, got %v", paths)
+	}
+	if len(services) != len(testfiles.ServiceMap(files))+1 {
+		t.Errorf("expected services from files as well, got %v", services)
+	}
+
+	policies, err := m.ServicesWithPolicies(generated, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policies[id].Contains(policy.Automated) {
+		t.Errorf("expected generated service to be automated, got %v", policies[id])
+	}
+}
+
+func TestGeneratorUpdaters(t *testing.T) {
+	generated, files, cleanup := setupGenerated(t)
+	defer cleanup()
+
+	m := NewGeneratorManifests(0)
+	id := flux.MustParseResourceID("default:deployment/generated")
+	ref, err := image.ParseRef("example.com/app:2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := []string{generated, files}
+	if err := cluster.WriteDefinition(m, filepath.Join(generated, ConfigFilename), id, nil, map[string]image.Ref{"app": ref}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(filepath.Join(generated, "image.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `FLUX_CONTAINER=app
+FLUX_DIGEST=
+FLUX_IMG=example.com/app
+FLUX_TAG=2.0
+FLUX_WORKLOAD=default:deployment/generated
+`
+	if string(out) != expected {
+		t.Errorf("expected image updater to be given\n%s\ngot\n%s", expected, out)
+	}
+
+	// An image pinned to a digest gets the digest passed along
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	if err := cluster.WriteDefinition(m, filepath.Join(generated, ConfigFilename), id, nil, map[string]image.Ref{"app": ref.WithDigest(digest)}); err != nil {
+		t.Fatal(err)
+	}
+	out, err = ioutil.ReadFile(filepath.Join(generated, "image.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "FLUX_DIGEST="+digest+"\nFLUX_IMG=example.com/app\nFLUX_TAG=2.0\n") {
+		t.Errorf("expected image updater to be given digest %s, got\n%s", digest, out)
+	}
+
+	changed, err := cluster.UpdatePolicies(m, dirs, id, policy.Update{
+		Remove: policy.Set{policy.Automated: "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected update to a generated service to count as a change")
+	}
+	out, err = ioutil.ReadFile(filepath.Join(generated, "policy.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "FLUX_POLICY=automated\nFLUX_POLICY_VALUE=\n") {
+		t.Errorf("expected policy updater to be told to remove policy, got\n%s", out)
+	}
+
+	// Files are updated as usual
+	changed, err = cluster.UpdatePolicies(m, dirs, flux.MustParseResourceID("default:deployment/helloworld"), policy.Update{
+		Add: policy.Set{policy.Locked: "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected file to be changed by adding a policy")
+	}
+}
+
+func TestParseGeneratorConfig(t *testing.T) {
+	for _, def := range []string{
+		"version: 2\ngenerators:\n- command: echo\n",
+		"version: 1\n",
+		"version: [1\n",
+	} {
+		if _, err := ParseGeneratorConfig([]byte(def)); err == nil {
+			t.Errorf("expected error parsing %q", def)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"sync"
 
 	k8syaml "github.com/ghodss/yaml"
@@ -41,9 +42,10 @@ type extendedClient struct {
 }
 
 type apiObject struct {
-	bytes    []byte
-	Kind     string `yaml:"kind"`
-	Metadata struct {
+	bytes      []byte
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
 		Name      string `yaml:"name"`
 		Namespace string `yaml:"namespace"`
 	} `yaml:"metadata"`
@@ -84,24 +86,87 @@ func isAddon(obj namespacedLabeled) bool {
 
 // --- /add ons
 
+// Objects are applied in phases, so that anything an object depends
+// on (its namespace, its custom resource definition, the service
+// account it runs as, and so on) is applied before it. Objects are
+// deleted in the reverse order.
+const (
+	phaseNamespaces = iota
+	phaseCRDs
+	phaseRBAC // including service accounts
+	phaseConfig
+	phaseWorkloads // and anything else built in
+	phaseCustomResources
+	numPhases
+)
+
+func (o *apiObject) phase() int {
+	group := o.APIVersion
+	if i := strings.Index(group, "/"); i >= 0 {
+		group = group[:i]
+	} else {
+		group = "" // the core group, e.g., apiVersion: v1
+	}
+
+	switch {
+	case group == "" && o.Kind == "Namespace":
+		return phaseNamespaces
+	case group == "apiextensions.k8s.io" && o.Kind == "CustomResourceDefinition":
+		return phaseCRDs
+	case group == "rbac.authorization.k8s.io",
+		group == "" && o.Kind == "ServiceAccount":
+		return phaseRBAC
+	case group == "" && (o.Kind == "ConfigMap" || o.Kind == "Secret"):
+		return phaseConfig
+	case isCustomGroup(group):
+		return phaseCustomResources
+	default:
+		return phaseWorkloads
+	}
+}
+
+// isCustomGroup says whether an API group is for custom resources,
+// rather than being built in to Kubernetes. Built-in groups either
+// have no dots in the name (e.g., "apps") or are under k8s.io.
+func isCustomGroup(group string) bool {
+	return strings.Contains(group, ".") && !strings.HasSuffix(group, ".k8s.io")
+}
+
 type changeSet struct {
-	nsObjs   map[string][]obj
-	noNsObjs map[string][]obj
+	objs map[string][]obj
 }
 
 func makeChangeSet() changeSet {
 	return changeSet{
-		nsObjs:   make(map[string][]obj),
-		noNsObjs: make(map[string][]obj),
+		objs: make(map[string][]obj),
 	}
 }
 
 func (c *changeSet) stage(cmd, id string, o *apiObject) {
-	if o.hasNamespace() {
-		c.nsObjs[cmd] = append(c.nsObjs[cmd], obj{id, o})
-	} else {
-		c.noNsObjs[cmd] = append(c.noNsObjs[cmd], obj{id, o})
+	c.objs[cmd] = append(c.objs[cmd], obj{id, o})
+}
+
+// phases gives the objects staged for the command, grouped into the
+// phases in which they should be acted on, in order. Empty phases
+// are left out.
+func (c *changeSet) phases(cmd string) [][]obj {
+	byPhase := make([][]obj, numPhases)
+	for _, o := range c.objs[cmd] {
+		p := o.phase()
+		byPhase[p] = append(byPhase[p], o)
+	}
+
+	var res [][]obj
+	for i := range byPhase {
+		p := i
+		if cmd == "delete" {
+			p = numPhases - 1 - i
+		}
+		if len(byPhase[p]) > 0 {
+			res = append(res, byPhase[p])
+		}
 	}
+	return res
 }
 
 type obj struct {
@@ -4,6 +4,7 @@ package kubernetes
 // adequate. Starting with Sync.
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/go-kit/kit/log"
@@ -16,7 +17,7 @@ type mockApplier struct {
 }
 
 func (m *mockApplier) apply(_ log.Logger, c changeSet, _ cluster.SyncError) {
-	if len(c.nsObjs) != 0 || len(c.noNsObjs) != 0 {
+	if len(c.objs) != 0 {
 		m.commandRun = true
 	}
 }
@@ -66,3 +67,83 @@ func TestSyncMalformed(t *testing.T) {
 		t.Error("expected no commands run")
 	}
 }
+
+func TestObjectPhase(t *testing.T) {
+	for _, c := range []struct {
+		apiVersion, kind string
+		phase            int
+	}{
+		{"v1", "Namespace", phaseNamespaces},
+		{"apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", phaseCRDs},
+		{"v1", "ServiceAccount", phaseRBAC},
+		{"rbac.authorization.k8s.io/v1", "ClusterRole", phaseRBAC},
+		{"rbac.authorization.k8s.io/v1beta1", "RoleBinding", phaseRBAC},
+		{"v1", "ConfigMap", phaseConfig},
+		{"v1", "Secret", phaseConfig},
+		{"extensions/v1beta1", "Deployment", phaseWorkloads},
+		{"apps/v1beta1", "StatefulSet", phaseWorkloads},
+		{"batch/v1beta1", "CronJob", phaseWorkloads},
+		{"v1", "Service", phaseWorkloads},
+		{"networking.k8s.io/v1", "NetworkPolicy", phaseWorkloads},
+		{"helm.integrations.flux.weave.works/v1alpha", "FluxHelmRelease", phaseCustomResources},
+		{"example.com/v1", "Namespace", phaseCustomResources},
+	} {
+		o := &apiObject{APIVersion: c.apiVersion, Kind: c.kind}
+		if p := o.phase(); p != c.phase {
+			t.Errorf("%s %s: expected phase %d, got %d", c.apiVersion, c.kind, c.phase, p)
+		}
+	}
+}
+
+func TestChangeSetPhases(t *testing.T) {
+	defs := []struct{ id, def string }{
+		{"default:deployment/app", "apiVersion: extensions/v1beta1\nkind: Deployment\nmetadata:\n  name: app\n  namespace: default\n"},
+		{"default:widget/w", "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\n  namespace: default\n"},
+		{"default:configmap/config", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"},
+		{"<cluster>:customresourcedefinition/widgets.example.com", "apiVersion: apiextensions.k8s.io/v1beta1\nkind: CustomResourceDefinition\nmetadata:\n  name: widgets.example.com\n"},
+		{"default:serviceaccount/app", "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: app\n"},
+		{"<cluster>:namespace/default", "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: default\n"},
+		{"default:service/app", "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n"},
+	}
+
+	cs := makeChangeSet()
+	for _, cmd := range []string{"apply", "delete"} {
+		for _, d := range defs {
+			o, err := definitionObj([]byte(d.def))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cs.stage(cmd, d.id, o)
+		}
+	}
+
+	expectedApply := [][]string{
+		{"<cluster>:namespace/default"},
+		{"<cluster>:customresourcedefinition/widgets.example.com"},
+		{"default:serviceaccount/app"},
+		{"default:configmap/config"},
+		{"default:deployment/app", "default:service/app"},
+		{"default:widget/w"},
+	}
+	var expectedDelete [][]string
+	for i := len(expectedApply) - 1; i >= 0; i-- {
+		expectedDelete = append(expectedDelete, expectedApply[i])
+	}
+
+	for cmd, expected := range map[string][][]string{
+		"apply":  expectedApply,
+		"delete": expectedDelete,
+	} {
+		var got [][]string
+		for _, phase := range cs.phases(cmd) {
+			var ids []string
+			for _, o := range phase {
+				ids = append(ids, o.id)
+			}
+			got = append(got, ids)
+		}
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("%s: expected phases\n%v\ngot\n%v", cmd, expected, got)
+		}
+	}
+}
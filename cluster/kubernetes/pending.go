@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	fluxupdate "github.com/weaveworks/flux/update" // not update, which is taken in the tests
+)
+
+// PendingDataKey is the key in the secret under which the releases
+// waiting for approval are kept.
+const PendingDataKey = "pending"
+
+// SecretPendingStore keeps the releases waiting for approval in a
+// kubernetes secret.
+type SecretPendingStore secretStore
+
+func (s SecretPendingStore) LoadPending() ([]fluxupdate.PendingRelease, error) {
+	var pending []fluxupdate.PendingRelease
+	err := secretStore(s).load(PendingDataKey, &pending)
+	return pending, err
+}
+
+// SavePending records the pending releases given, or removes them
+// from the secret if there are none.
+func (s SecretPendingStore) SavePending(pending []fluxupdate.PendingRelease) error {
+	if len(pending) == 0 {
+		return secretStore(s).save(PendingDataKey, nil)
+	}
+	return secretStore(s).save(PendingDataKey, pending)
+}
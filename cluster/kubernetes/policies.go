@@ -11,6 +11,7 @@ import (
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster/kubernetes/resource"
 	"github.com/weaveworks/flux/policy"
+	fluxresource "github.com/weaveworks/flux/resource"
 )
 
 func (m *Manifests) UpdatePolicies(in []byte, update policy.Update) ([]byte, error) {
@@ -35,7 +36,19 @@ func updateAnnotations(def []byte, tagAll string, f func(map[string]string) map[
 		return nil, err
 	}
 	annotations := manifest.Metadata.AnnotationsOrNil()
-	if tagAll != "" {
+	switch {
+	case tagAll == "":
+	case manifest.Kind == "Namespace":
+		// There are no containers to give tag filters to; the
+		// controllers in the namespace inherit it instead (see
+		// inheritPolicies)
+		p := resource.PolicyPrefix + string(policy.TagAll)
+		if tagAll != "glob:*" {
+			annotations[p] = tagAll
+		} else {
+			delete(annotations, p)
+		}
+	default:
 		containers := manifest.Spec.Template.Spec.Containers
 		for _, c := range containers {
 			p := resource.PolicyPrefix + string(policy.TagPrefix(c.Name))
@@ -104,6 +117,7 @@ func updateAnnotations(def []byte, tagAll string, f func(map[string]string) map[
 }
 
 type Manifest struct {
+	Kind     string   `yaml:"kind"`
 	Metadata Metadata `yaml:"metadata"`
 	Spec     struct {
 		Template struct {
@@ -135,6 +149,12 @@ type Metadata struct {
 	Annotations map[string]string `yaml:"annotations"`
 }
 
+// Containers gives the containers in the manifest's pod template,
+// wherever that is for its kind.
+func (m Manifest) Containers() []Container {
+	return append(m.Spec.Template.Spec.Containers, m.Spec.JobTemplate.Spec.Template.Spec.Containers...)
+}
+
 type Container struct {
 	Name  string `yaml:"name"`
 	Image string `yaml:"image"`
@@ -148,15 +168,27 @@ func parseManifest(def []byte) (Manifest, error) {
 	return m, nil
 }
 
-func (m *Manifests) ServicesWithPolicies(root string) (policy.ResourceMap, error) {
-	all, err := m.FindDefinedServices(root)
+// ServicesWithPolicies gives the services under the roots given,
+// each with its policies in effect: those annotated on it, and those
+// it inherits from its namespace (see inheritPolicies).
+func (m *Manifests) ServicesWithPolicies(roots ...string) (policy.ResourceMap, error) {
+	namespaces, err := m.NamespacesWithPolicies(roots...)
+	if err != nil {
+		return nil, err
+	}
+	return m.servicesWithPolicies(namespaces, roots...)
+}
+
+func (m *Manifests) servicesWithPolicies(namespaces map[string]policy.Set, roots ...string) (policy.ResourceMap, error) {
+	all, err := m.FindDefinedServices(roots...)
 	if err != nil {
 		return nil, err
 	}
 
 	result := map[flux.ResourceID]policy.Set{}
 	err = iterateManifests(all, func(s flux.ResourceID, m Manifest) error {
-		ps, err := policiesFrom(m)
+		ns, _, _ := s.Components()
+		ps, err := inheritPolicies(namespaces[ns], m)
 		if err != nil {
 			return err
 		}
@@ -169,6 +201,32 @@ func (m *Manifests) ServicesWithPolicies(root string) (policy.ResourceMap, error
 	return result, nil
 }
 
+// NamespacesWithPolicies gives the policies annotated on each
+// namespace defined under the roots given, by namespace name.
+func (m *Manifests) NamespacesWithPolicies(roots ...string) (map[string]policy.Set, error) {
+	resources, err := m.LoadManifests(roots...)
+	if err != nil {
+		return nil, err
+	}
+	return namespacePolicies(resources)
+}
+
+func namespacePolicies(resources map[string]fluxresource.Resource) (map[string]policy.Set, error) {
+	result := map[string]policy.Set{}
+	for _, res := range resources {
+		if _, kind, name := res.ResourceID().Components(); kind == "namespace" {
+			manifest, err := parseManifest(res.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			if result[name], err = policiesFrom(manifest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
 func iterateManifests(services map[flux.ResourceID][]string, f func(flux.ResourceID, Manifest) error) error {
 	for serviceID, paths := range services {
 		if len(paths) != 1 {
@@ -191,6 +249,40 @@ func iterateManifests(services map[flux.ResourceID][]string, f func(flux.Resourc
 	return nil
 }
 
+// inheritPolicies gives the policies in effect for a controller:
+// those of its namespace, overridden by those annotated on the
+// controller itself. A boolean policy annotated with anything but
+// "true" (e.g., `flux.weave.works/automated: "false"`) overrides
+// the namespace's; and the namespace's tag_all gives a tag filter to
+// each container without one of its own.
+func inheritPolicies(namespace policy.Set, m Manifest) (policy.Set, error) {
+	own, err := policiesFrom(m)
+	if err != nil {
+		return nil, err
+	}
+	policies := policy.Set{}
+	if tagAll, ok := namespace.Get(policy.TagAll); ok {
+		for _, c := range m.Containers() {
+			policies[policy.TagPrefix(c.Name)] = tagAll
+		}
+	}
+	for p, v := range namespace {
+		if p != policy.TagAll {
+			policies[p] = v
+		}
+	}
+	for k, v := range m.Metadata.AnnotationsOrNil() {
+		p := policy.Policy(strings.TrimPrefix(k, resource.PolicyPrefix))
+		if strings.HasPrefix(k, resource.PolicyPrefix) && policy.Boolean(p) && v != "true" {
+			delete(policies, p)
+		}
+	}
+	for p, v := range own {
+		policies[p] = v
+	}
+	return policies, nil
+}
+
 func policiesFrom(m Manifest) (policy.Set, error) {
 	var policies policy.Set
 	for k, v := range m.Metadata.AnnotationsOrNil() {
@@ -2,9 +2,14 @@ package kubernetes
 
 import (
 	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"text/template"
 
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
 	"github.com/weaveworks/flux/policy"
 )
 
@@ -137,3 +142,128 @@ func templToString(t *testing.T, templ *template.Template, data interface{}) str
 	}
 	return out.String()
 }
+
+func TestUpdateNamespacePolicies(t *testing.T) {
+	in := `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo
+`
+	out, err := (&Manifests{}).UpdatePolicies([]byte(in), policy.Update{
+		Add: policy.Set{policy.Automated: "true", policy.TagAll: "semver:~1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With no containers of its own, the namespace keeps tag_all
+	// for its controllers to inherit
+	expected := `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  annotations:
+    flux.weave.works/automated: "true"
+    flux.weave.works/tag_all: semver:~1
+  name: foo
+`
+	if string(out) != expected {
+		t.Errorf("Did not get expected result:\n\n%s\n\nInstead got:\n\n%s", expected, string(out))
+	}
+}
+
+func TestServicesWithInheritedPolicies(t *testing.T) {
+	dir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+
+	for name, content := range map[string]string{
+		"namespace.yaml": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo
+  annotations:
+    flux.weave.works/automated: "true"
+    flux.weave.works/tag_all: glob:master-*
+    flux.weave.works/tag.sidecar: glob:1.*
+`,
+		"plain.yaml": `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: plain
+  namespace: foo
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:master-a000001
+      - name: sidecar
+        image: sidecar:1.0
+`,
+		"overridden.yaml": `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: overridden
+  namespace: foo
+  annotations:
+    flux.weave.works/automated: "false"
+    flux.weave.works/locked: "true"
+    flux.weave.works/tag.app: semver:~1
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0.0
+`,
+		"elsewhere.yaml": `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: elsewhere
+  namespace: bar
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:master-a000001
+`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &Manifests{}
+	namespaces, err := m.NamespacesWithPolicies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNamespaces := map[string]policy.Set{
+		"foo": {policy.Automated: "true", policy.TagAll: "glob:master-*", policy.TagPrefix("sidecar"): "glob:1.*"},
+	}
+	if !reflect.DeepEqual(expectedNamespaces, namespaces) {
+		t.Errorf("Expected namespace policies:\n%#v\ngot:\n%#v", expectedNamespaces, namespaces)
+	}
+
+	services, err := m.ServicesWithPolicies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := policy.ResourceMap{
+		flux.MustParseResourceID("foo:deployment/plain"): {
+			policy.Automated:            "true",
+			policy.TagPrefix("app"):     "glob:master-*",
+			policy.TagPrefix("sidecar"): "glob:1.*",
+		},
+		flux.MustParseResourceID("foo:deployment/overridden"): {
+			policy.Locked:               "true",
+			policy.TagPrefix("app"):     "semver:~1",
+			policy.TagPrefix("sidecar"): "glob:1.*",
+		},
+		flux.MustParseResourceID("bar:deployment/elsewhere"): {},
+	}
+	if !reflect.DeepEqual(expected, services) {
+		t.Errorf("Expected:\n%#v\ngot:\n%#v", expected, services)
+	}
+}
@@ -53,8 +53,7 @@ func (c *Kubectl) connectArgs() []string {
 }
 
 func (c *Kubectl) apply(logger log.Logger, cs changeSet, errs cluster.SyncError) {
-	f := func(m map[string][]obj, cmd string, args ...string) {
-		objs := m[cmd]
+	f := func(objs []obj, cmd string, args ...string) {
 		if len(objs) == 0 {
 			return
 		}
@@ -70,16 +69,30 @@ func (c *Kubectl) apply(logger log.Logger, cs changeSet, errs cluster.SyncError)
 		}
 	}
 
-	// When deleting resources we must ensure any resource in a non-default
-	// namespace is deleted before the namespace that it is in. Since namespace
-	// resources don't specify a namespace, this ordering guarantees that.
-	f(cs.nsObjs, "delete")
-	f(cs.noNsObjs, "delete", "--namespace", "default")
-	// Likewise, when applying resources we must ensure the namespace is applied
-	// first, so we run the commands the other way round.
-	f(cs.noNsObjs, "apply", "--namespace", "default")
-	f(cs.nsObjs, "apply")
+	// The phases take care of applying anything an object depends on
+	// before the object (and deleting it after). Within each phase,
+	// objects that don't give a namespace go in the default namespace.
+	for _, phase := range cs.phases("delete") {
+		nsObjs, noNsObjs := splitByNamespace(phase)
+		f(nsObjs, "delete")
+		f(noNsObjs, "delete", "--namespace", "default")
+	}
+	for _, phase := range cs.phases("apply") {
+		nsObjs, noNsObjs := splitByNamespace(phase)
+		f(noNsObjs, "apply", "--namespace", "default")
+		f(nsObjs, "apply")
+	}
+}
 
+func splitByNamespace(objs []obj) (nsObjs, noNsObjs []obj) {
+	for _, o := range objs {
+		if o.hasNamespace() {
+			nsObjs = append(nsObjs, o)
+		} else {
+			noNsObjs = append(noNsObjs, o)
+		}
+	}
+	return nsObjs, noNsObjs
 }
 
 func (c *Kubectl) doCommand(logger log.Logger, r io.Reader, args ...string) error {
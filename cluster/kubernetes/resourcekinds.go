@@ -37,6 +37,7 @@ type podController struct {
 	kind        string
 	name        string
 	status      string
+	rollout     cluster.RolloutStatus
 	podTemplate apiv1.PodTemplateSpec
 	apiObject   interface{}
 }
@@ -50,6 +51,7 @@ func (pc podController) toClusterController(resourceID flux.ResourceID) cluster.
 	return cluster.Controller{
 		ID:         resourceID,
 		Status:     pc.status,
+		Rollout:    pc.rollout,
 		Containers: cluster.ContainersOrExcuse{Containers: clusterContainers},
 	}
 }
@@ -108,11 +110,20 @@ func makeDeploymentPodController(deployment *apiext.Deployment) podController {
 		status = StatusUpdating
 	}
 
+	rollout := cluster.RolloutStatus{
+		Generation:         objectMeta.Generation,
+		ObservedGeneration: deploymentStatus.ObservedGeneration,
+		Desired:            *deployment.Spec.Replicas,
+		Updated:            deploymentStatus.UpdatedReplicas,
+		Available:          deploymentStatus.AvailableReplicas,
+	}
+
 	return podController{
 		apiVersion:  "extensions/v1beta1",
 		kind:        "Deployment",
 		name:        deployment.ObjectMeta.Name,
 		status:      status,
+		rollout:     rollout,
 		podTemplate: deployment.Spec.Template,
 		apiObject:   deployment}
 }
@@ -160,11 +171,20 @@ func makeDaemonSetPodController(daemonSet *apiext.DaemonSet) podController {
 		status = StatusUpdating
 	}
 
+	rollout := cluster.RolloutStatus{
+		Generation:         objectMeta.Generation,
+		ObservedGeneration: daemonSetStatus.ObservedGeneration,
+		Desired:            daemonSetStatus.DesiredNumberScheduled,
+		Updated:            daemonSetStatus.UpdatedNumberScheduled,
+		Available:          daemonSetStatus.NumberAvailable,
+	}
+
 	return podController{
 		apiVersion:  "extensions/v1beta1",
 		kind:        "DaemonSet",
 		name:        daemonSet.ObjectMeta.Name,
 		status:      status,
+		rollout:     rollout,
 		podTemplate: daemonSet.Spec.Template,
 		apiObject:   daemonSet}
 }
@@ -212,11 +232,20 @@ func makeStatefulSetPodController(statefulSet *apiapps.StatefulSet) podControlle
 		status = StatusUpdating
 	}
 
+	rollout := cluster.RolloutStatus{
+		Generation:         objectMeta.Generation,
+		ObservedGeneration: *statefulSetStatus.ObservedGeneration,
+		Desired:            *statefulSet.Spec.Replicas,
+		Updated:            statefulSetStatus.UpdatedReplicas,
+		Available:          statefulSetStatus.ReadyReplicas,
+	}
+
 	return podController{
 		apiVersion:  "apps/v1beta1",
 		kind:        "StatefulSet",
 		name:        statefulSet.ObjectMeta.Name,
 		status:      status,
+		rollout:     rollout,
 		podTemplate: statefulSet.Spec.Template,
 		apiObject:   statefulSet}
 }
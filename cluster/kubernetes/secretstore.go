@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// secretStore keeps values the daemon needs to survive a restart in
+// a kubernetes secret, each under a key of its own; usually, the same
+// secret as holds the SSH key, since that's already there and
+// writable by fluxd. The typed stores (e.g., SecretSuspensionStore)
+// are conversions of it.
+type secretStore struct {
+	SecretAPI  v1.SecretInterface
+	SecretName string
+}
+
+// load decodes the value under the key given into the value pointed
+// to, leaving it as it is if there's nothing under the key.
+func (s secretStore) load(key string, into interface{}) error {
+	secret, err := s.SecretAPI.Get(s.SecretName, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	data, ok := secret.Data[key]
+	if !ok || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, into)
+}
+
+// save puts the value given in the secret, under the key given; or,
+// if the value is nil, removes the key.
+func (s secretStore) save(key string, value interface{}) error {
+	var data interface{} // null removes the key
+	if value != nil {
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		data = base64.StdEncoding.EncodeToString(bytes)
+	}
+	patch := map[string]map[string]interface{}{
+		"data": map[string]interface{}{
+			key: data,
+		},
+	}
+	jsonPatch, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = s.SecretAPI.Patch(s.SecretName, types.StrategicMergePatchType, jsonPatch)
+	return err
+}
@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	fluxupdate "github.com/weaveworks/flux/update" // not update, which is taken in the tests
+)
+
+// SoakingDataKey is the key in the secret under which the images
+// soaking in controllers that images are promoted from are kept.
+const SoakingDataKey = "soaking"
+
+// SecretSoakStore keeps the images soaking in controllers that
+// images are promoted from, and since when, in a kubernetes secret.
+type SecretSoakStore secretStore
+
+func (s SecretSoakStore) LoadSoaking() ([]fluxupdate.SoakingImage, error) {
+	var soaking []fluxupdate.SoakingImage
+	err := secretStore(s).load(SoakingDataKey, &soaking)
+	return soaking, err
+}
+
+// SaveSoaking records the soaking images given, or removes them from
+// the secret if there are none.
+func (s SecretSoakStore) SaveSoaking(soaking []fluxupdate.SoakingImage) error {
+	if len(soaking) == 0 {
+		return secretStore(s).save(SoakingDataKey, nil)
+	}
+	return secretStore(s).save(SoakingDataKey, soaking)
+}
@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"github.com/weaveworks/flux"
+)
+
+// SuspensionDataKey is the key in the secret under which the
+// suspension is kept.
+const SuspensionDataKey = "suspension"
+
+// SecretSuspensionStore keeps the daemon's suspension (if anything is
+// suspended) in a kubernetes secret.
+type SecretSuspensionStore secretStore
+
+func (s SecretSuspensionStore) LoadSuspension() (flux.Suspension, error) {
+	var suspension flux.Suspension
+	err := secretStore(s).load(SuspensionDataKey, &suspension)
+	return suspension, err
+}
+
+// SaveSuspension records the suspension given, or removes it from
+// the secret if nothing is suspended.
+func (s SecretSuspensionStore) SaveSuspension(suspension flux.Suspension) error {
+	if !suspension.Suspended() {
+		return secretStore(s).save(SuspensionDataKey, nil)
+	}
+	return secretStore(s).save(SuspensionDataKey, suspension)
+}
@@ -131,8 +131,8 @@ func tryUpdate(def []byte, container string, newImage image.Ref, out io.Writer)
 	containersRE := regexp.MustCompile(`(?m:^` + indent + `containers:\s*(?:#.*)*$(?:\n(?:` + indent + `[-\s#].*)?)*)`)
 	// Parse out an individual container blog
 	containerRE := regexp.MustCompile(`(?m:` + indent + `-.*(?:\n(?:` + indent + `\s+.*)?)*)`)
-	// Parse out the image ID
-	imageRE := regexp.MustCompile(`(` + indent + `[-\s]\s*"?image"?:\s*)"?(?:[\w\.\-/:]+\s*?)*"?([\t\f #]+.*)?`)
+	// Parse out the image ID, which may have a digest
+	imageRE := regexp.MustCompile(`(` + indent + `[-\s]\s*"?image"?:\s*)"?(?:[\w\.\-/:@]+\s*?)*"?([\t\f #]+.*)?`)
 	imageReplacement := fmt.Sprintf("${1}%s${2}", maybeQuote(newImage.String()))
 	// Find the block of container specs
 	newDef = containersRE.ReplaceAllStringFunc(newDef, func(containers string) string {
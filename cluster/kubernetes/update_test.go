@@ -49,6 +49,8 @@ func TestUpdates(t *testing.T) {
 		{"minimal dockerhub image name", case5container, case5image, case5, case5out},
 		{"reordered keys", case6containers, case6image, case6, case6out},
 		{"from prod", case7containers, case7image, case7, case7out},
+		{"pinned by digest", case8containers, case8image, case8, case8out},
+		{"unpinned", case8containers, case6image, case8out, case8unpinned},
 	} {
 		testUpdate(t, c)
 	}
@@ -534,3 +536,46 @@ spec:
         - name: FLUENTD_CONF
           value: fluent.conf
 `
+
+const case8 = `---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.10-alpine@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa # pinned
+`
+
+const case8image = "nginx:1.11-alpine@sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+
+var case8containers = []string{"nginx"}
+
+const case8out = `---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.11-alpine@sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb # pinned
+`
+
+const case8unpinned = `---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.10-alpine # pinned
+`
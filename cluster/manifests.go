@@ -14,9 +14,9 @@ import (
 // resources, e.g., in Kubernetes, YAML files describing Kubernetes
 // resources.
 type Manifests interface {
-	// Given a directory with manifest files, find which files define
+	// Given directories with manifest files, find which files define
 	// which services.
-	FindDefinedServices(path string) (map[flux.ResourceID][]string, error)
+	FindDefinedServices(paths ...string) (map[flux.ResourceID][]string, error)
 	// Update the definitions in a manifests bytes according to the
 	// spec given.
 	UpdateDefinition(def []byte, container string, newImageID image.Ref) ([]byte, error)
@@ -26,27 +26,140 @@ type Manifests interface {
 	ParseManifests([]byte) (map[string]resource.Resource, error)
 	// UpdatePolicies modifies a manifest to apply the policy update specified
 	UpdatePolicies([]byte, policy.Update) ([]byte, error)
-	// ServicesWithPolicies returns all services with their associated policies
-	ServicesWithPolicies(path string) (policy.ResourceMap, error)
+	// ServicesWithPolicies returns all services under the paths
+	// given, with their associated policies, including those
+	// inherited from their namespace
+	ServicesWithPolicies(paths ...string) (policy.ResourceMap, error)
+	// NamespacesWithPolicies returns the policies given to each
+	// namespace defined under the paths, which the services in the
+	// namespace inherit unless they override them
+	NamespacesWithPolicies(paths ...string) (map[string]policy.Set, error)
 }
 
-// UpdateManifest looks for the manifest for a given service, reads
-// its contents, applies f(contents), and writes the results back to
-// the file.
-func UpdateManifest(m Manifests, root string, serviceID flux.ResourceID, f func(manifest []byte) ([]byte, error)) error {
-	services, err := m.FindDefinedServices(root)
+// GeneratedManifests is implemented by Manifests for which some
+// resources are generated rather than read from files, e.g., by
+// running a command. The paths given to these methods are those
+// returned from FindDefinedServices; since there's no file to rewrite
+// for a generated resource, updates are done by other means.
+type GeneratedManifests interface {
+	Manifests
+	// IsGenerated says whether the path given for a resource refers
+	// to something that generates the resource, rather than a file
+	// defining it
+	IsGenerated(path string) bool
+	// SetGeneratedImage updates the image used by a container in a
+	// generated resource
+	SetGeneratedImage(path string, id flux.ResourceID, container string, newImageID image.Ref) error
+	// UpdateGeneratedPolicies applies a policy update to a generated
+	// resource
+	UpdateGeneratedPolicies(path string, id flux.ResourceID, update policy.Update) error
+}
+
+// isGenerated is a shortcut for checking whether the Manifests given
+// generate the resource found at the path.
+func isGenerated(m Manifests, path string) (GeneratedManifests, bool) {
+	if gm, ok := m.(GeneratedManifests); ok && gm.IsGenerated(path) {
+		return gm, true
+	}
+	return nil, false
+}
+
+// UpdatePolicies applies a policy update to the manifest for the
+// service given, found under any of the roots. It reports whether the
+// manifest was changed.
+func UpdatePolicies(m Manifests, roots []string, serviceID flux.ResourceID, u policy.Update) (bool, error) {
+	path, err := findManifest(m, roots, serviceID)
+	if err != nil {
+		return false, err
+	}
+	if gm, ok := isGenerated(m, path); ok {
+		// There's no way to tell whether anything changed without
+		// generating everything again; assume it did.
+		return true, gm.UpdateGeneratedPolicies(path, serviceID, u)
+	}
+	var changed bool
+	err = updateFile(path, func(def []byte) ([]byte, error) {
+		newDef, err := m.UpdatePolicies(def, u)
+		if err != nil {
+			return nil, err
+		}
+		changed = string(newDef) != string(def)
+		return newDef, nil
+	})
+	return changed, err
+}
+
+// WriteDefinition writes the result of updating a service's manifest
+// back to the path it was found at. If the service is generated, the
+// container image updates given are applied instead.
+func WriteDefinition(m Manifests, path string, serviceID flux.ResourceID, def []byte, images map[string]image.Ref) error {
+	if gm, ok := isGenerated(m, path); ok {
+		for container, ref := range images {
+			if err := gm.SetGeneratedImage(path, serviceID, container, ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return updateFile(path, func([]byte) ([]byte, error) {
+		return def, nil
+	})
+}
+
+// UpdateManifest looks for the manifest for a given service under
+// any of the roots given, reads its contents, applies f(contents),
+// and writes the results back to the file.
+func UpdateManifest(m Manifests, roots []string, serviceID flux.ResourceID, f func(manifest []byte) ([]byte, error)) error {
+	path, err := findManifest(m, roots, serviceID)
 	if err != nil {
 		return err
 	}
+	return updateFile(path, f)
+}
+
+func findManifest(m Manifests, roots []string, serviceID flux.ResourceID) (string, error) {
+	if _, kind, name := serviceID.Components(); kind == "namespace" {
+		return findNamespaceManifest(m, roots, name)
+	}
+	services, err := m.FindDefinedServices(roots...)
+	if err != nil {
+		return "", err
+	}
 	paths := services[serviceID]
 	if len(paths) == 0 {
-		return ErrNoResourceFilesFoundForService
+		return "", ErrNoResourceFilesFoundForService
+	}
+	if len(paths) > 1 {
+		return "", ErrMultipleResourceFilesFoundForService
+	}
+	return paths[0], nil
+}
+
+// findNamespaceManifest looks for the manifest defining the namespace
+// named. Namespaces aren't themselves namespaced, so they are found
+// by name alone.
+func findNamespaceManifest(m Manifests, roots []string, name string) (string, error) {
+	resources, err := m.LoadManifests(roots...)
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	for _, res := range resources {
+		if _, kind, n := res.ResourceID().Components(); kind == "namespace" && n == name {
+			paths = append(paths, res.Source())
+		}
+	}
+	if len(paths) == 0 {
+		return "", ErrNoResourceFilesFoundForService
 	}
 	if len(paths) > 1 {
-		return ErrMultipleResourceFilesFoundForService
+		return "", ErrMultipleResourceFilesFoundForService
 	}
+	return paths[0], nil
+}
 
-	def, err := ioutil.ReadFile(paths[0])
+func updateFile(path string, f func(manifest []byte) ([]byte, error)) error {
+	def, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
@@ -56,9 +169,9 @@ func UpdateManifest(m Manifests, root string, serviceID flux.ResourceID, f func(
 		return err
 	}
 
-	fi, err := os.Stat(paths[0])
+	fi, err := os.Stat(path)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(paths[0], newDef, fi.Mode())
+	return ioutil.WriteFile(path, newDef, fi.Mode())
 }
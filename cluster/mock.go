@@ -10,19 +10,20 @@ import (
 
 // Doubles as a cluster.Cluster and cluster.Manifests implementation
 type Mock struct {
-	AllServicesFunc          func(maybeNamespace string) ([]Controller, error)
-	SomeServicesFunc         func([]flux.ResourceID) ([]Controller, error)
-	PingFunc                 func() error
-	ExportFunc               func() ([]byte, error)
-	SyncFunc                 func(SyncDef) error
-	PublicSSHKeyFunc         func(regenerate bool) (ssh.PublicKey, error)
-	FindDefinedServicesFunc  func(path string) (map[flux.ResourceID][]string, error)
-	UpdateDefinitionFunc     func(def []byte, container string, newImageID image.Ref) ([]byte, error)
-	LoadManifestsFunc        func(paths ...string) (map[string]resource.Resource, error)
-	ParseManifestsFunc       func([]byte) (map[string]resource.Resource, error)
-	UpdateManifestFunc       func(path, resourceID string, f func(def []byte) ([]byte, error)) error
-	UpdatePoliciesFunc       func([]byte, policy.Update) ([]byte, error)
-	ServicesWithPoliciesFunc func(path string) (policy.ResourceMap, error)
+	AllServicesFunc            func(maybeNamespace string) ([]Controller, error)
+	SomeServicesFunc           func([]flux.ResourceID) ([]Controller, error)
+	PingFunc                   func() error
+	ExportFunc                 func() ([]byte, error)
+	SyncFunc                   func(SyncDef) error
+	PublicSSHKeyFunc           func(regenerate bool) (ssh.PublicKey, error)
+	FindDefinedServicesFunc    func(paths ...string) (map[flux.ResourceID][]string, error)
+	UpdateDefinitionFunc       func(def []byte, container string, newImageID image.Ref) ([]byte, error)
+	LoadManifestsFunc          func(paths ...string) (map[string]resource.Resource, error)
+	ParseManifestsFunc         func([]byte) (map[string]resource.Resource, error)
+	UpdateManifestFunc         func(path, resourceID string, f func(def []byte) ([]byte, error)) error
+	UpdatePoliciesFunc         func([]byte, policy.Update) ([]byte, error)
+	ServicesWithPoliciesFunc   func(paths ...string) (policy.ResourceMap, error)
+	NamespacesWithPoliciesFunc func(paths ...string) (map[string]policy.Set, error)
 }
 
 func (m *Mock) AllControllers(maybeNamespace string) ([]Controller, error) {
@@ -49,8 +50,8 @@ func (m *Mock) PublicSSHKey(regenerate bool) (ssh.PublicKey, error) {
 	return m.PublicSSHKeyFunc(regenerate)
 }
 
-func (m *Mock) FindDefinedServices(path string) (map[flux.ResourceID][]string, error) {
-	return m.FindDefinedServicesFunc(path)
+func (m *Mock) FindDefinedServices(paths ...string) (map[flux.ResourceID][]string, error) {
+	return m.FindDefinedServicesFunc(paths...)
 }
 
 func (m *Mock) UpdateDefinition(def []byte, container string, newImageID image.Ref) ([]byte, error) {
@@ -73,6 +74,10 @@ func (m *Mock) UpdatePolicies(def []byte, p policy.Update) ([]byte, error) {
 	return m.UpdatePoliciesFunc(def, p)
 }
 
-func (m *Mock) ServicesWithPolicies(path string) (policy.ResourceMap, error) {
-	return m.ServicesWithPoliciesFunc(path)
+func (m *Mock) ServicesWithPolicies(paths ...string) (policy.ResourceMap, error) {
+	return m.ServicesWithPoliciesFunc(paths...)
+}
+
+func (m *Mock) NamespacesWithPolicies(paths ...string) (map[string]policy.Set, error) {
+	return m.NamespacesWithPoliciesFunc(paths...)
 }
@@ -33,6 +33,15 @@ func await(ctx context.Context, stdout, stderr io.Writer, client api.Client, job
 		fmt.Fprintf(stderr, "Nothing to do\n")
 		return nil
 	}
+	// The commit won't be applied until someone merges it, so
+	// there's no point waiting for that
+	if metadata.Branch != "" {
+		fmt.Fprintf(stderr, "Awaiting merge:\t%s\n", metadata.Branch)
+		if metadata.ChangeRequest != "" {
+			fmt.Fprintf(stderr, "Change request:\t%s\n", metadata.ChangeRequest)
+		}
+		return nil
+	}
 
 	if apply && metadata.Revision != "" {
 		if err := awaitSync(ctx, client, metadata.Revision); err != nil {
@@ -56,7 +65,7 @@ func awaitJob(ctx context.Context, client api.Client, jobID job.ID) (event.Commi
 		switch j.StatusString {
 		case job.StatusFailed:
 			return false, j
-		case job.StatusSucceeded:
+		case job.StatusSucceeded, job.StatusAwaitingMerge:
 			if j.Err != "" {
 				// How did we succeed but still get an error!?
 				return false, j
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/update"
+)
+
+type diffOpts struct {
+	*rootOpts
+	namespace  string
+	controller string
+}
+
+func newDiff(parent *rootOpts) *diffOpts {
+	return &diffOpts{rootOpts: parent}
+}
+
+func (opts *diffOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show how the cluster differs from the git repo.",
+		Example: makeExample(
+			"fluxctl diff",
+			"fluxctl diff --namespace default --controller=deployment/foo",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
+	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Show differences for this controller only")
+	return cmd
+}
+
+func (opts *diffOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+
+	var resourceSpec update.ResourceSpec
+	if len(opts.controller) == 0 {
+		resourceSpec = update.ResourceSpecAll
+	} else {
+		id, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
+		if err != nil {
+			return err
+		}
+		resourceSpec = update.MakeResourceSpec(id)
+	}
+
+	ctx := context.Background()
+
+	diffs, err := opts.API.Diff(ctx, resourceSpec)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if len(diffs) == 0 {
+		fmt.Fprintln(out, "No differences between the cluster and the repo.")
+		return nil
+	}
+	for _, diff := range diffs {
+		fmt.Fprintf(out, "%s %s\n", diff.Type, diff.ID)
+		fmt.Fprint(out, diff.Diff)
+	}
+	return nil
+}
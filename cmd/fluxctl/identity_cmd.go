@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
 )
 
 type identityOpts struct {
@@ -12,6 +15,8 @@ type identityOpts struct {
 	regenerate  bool
 	fingerprint bool
 	visual      bool
+	repos       bool
+	gpg         bool
 }
 
 func newIdentity(parent *rootOpts) *identityOpts {
@@ -27,6 +32,8 @@ func (opts *identityOpts) Command() *cobra.Command {
 	cmd.Flags().BoolVarP(&opts.regenerate, "regenerate", "r", false, `Generate a new identity`)
 	cmd.Flags().BoolVarP(&opts.fingerprint, "fingerprint", "l", false, `Show fingerprint of public key`)
 	cmd.Flags().BoolVarP(&opts.visual, "visual", "v", false, `Show ASCII art representation with fingerprint (implies -l)`)
+	cmd.Flags().BoolVar(&opts.gpg, "gpg", false, `Show the GPG key used to sign commits and tags, if there is one`)
+	cmd.Flags().BoolVar(&opts.repos, "repos", false, `List the git repos the daemon syncs from, each of which needs the key as a deploy key`)
 	return cmd
 }
 
@@ -37,6 +44,34 @@ func (opts *identityOpts) RunE(_ *cobra.Command, args []string) error {
 
 	ctx := context.Background()
 
+	if opts.gpg {
+		config, err := opts.API.GitRepoConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if config.SigningKey == nil {
+			return errors.New("fluxd is not signing commits (see its --git-signing-key flag)")
+		}
+		fmt.Println(config.SigningKey.ID)
+		fmt.Print(config.SigningKey.Key)
+		return nil
+	}
+
+	if opts.repos {
+		config, err := opts.API.GitRepoConfig(ctx)
+		if err != nil {
+			return err
+		}
+		remotes := config.Remotes
+		if len(remotes) == 0 { // an older daemon, with only the one repo
+			remotes = []flux.GitRemoteConfig{config.Remote}
+		}
+		for _, remote := range remotes {
+			fmt.Println(remote.URL)
+		}
+		return nil
+	}
+
 	publicSSHKey, err := opts.API.PublicSSHKey(ctx, opts.regenerate)
 	if err != nil {
 		return err
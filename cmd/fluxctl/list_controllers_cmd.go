@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -53,16 +54,16 @@ func (opts *controllerListOpts) RunE(cmd *cobra.Command, args []string) error {
 	sort.Sort(controllerStatusByName(controllers))
 
 	w := newTabwriter()
-	fmt.Fprintf(w, "CONTROLLER\tCONTAINER\tIMAGE\tRELEASE\tPOLICY\n")
+	fmt.Fprintf(w, "CONTROLLER\tCONTAINER\tIMAGE\tRELEASE\tPOLICY\tSYNC\n")
 	for _, controller := range controllers {
 		if len(controller.Containers) > 0 {
 			c := controller.Containers[0]
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", controller.ID, c.Name, c.Current.ID, controller.Status, policies(controller))
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", controller.ID, c.Name, c.Current.ID, controller.Status, policies(controller), syncState(controller.Sync))
 			for _, c := range controller.Containers[1:] {
-				fmt.Fprintf(w, "\t%s\t%s\t\t\n", c.Name, c.Current.ID)
+				fmt.Fprintf(w, "\t%s\t%s\t\t\t\n", c.Name, c.Current.ID)
 			}
 		} else {
-			fmt.Fprintf(w, "%s\t\t\t\t\n", controller.ID)
+			fmt.Fprintf(w, "%s\t\t\t\t\t%s\n", controller.ID, syncState(controller.Sync))
 		}
 	}
 	w.Flush()
@@ -84,16 +85,60 @@ func (s controllerStatusByName) Swap(a, b int) {
 }
 
 func policies(s flux.ControllerStatus) string {
+	inherited := map[string]bool{}
+	for _, p := range s.Inherited {
+		inherited[p] = true
+	}
+	// e.g., "locked (3h59m left, inherited)"
+	describe := func(p policy.Policy, notes ...string) string {
+		if inherited[string(p)] {
+			notes = append(notes, "inherited")
+		}
+		if len(notes) == 0 {
+			return string(p)
+		}
+		return fmt.Sprintf("%s (%s)", p, strings.Join(notes, ", "))
+	}
+
 	var ps []string
 	if s.Automated {
-		ps = append(ps, string(policy.Automated))
+		ps = append(ps, describe(policy.Automated))
 	}
 	if s.Locked {
-		ps = append(ps, string(policy.Locked))
+		var notes []string
+		if until, err := time.Parse(time.RFC3339, s.Policies[string(policy.LockedUntil)]); err == nil {
+			notes = append(notes, fmt.Sprintf("%s left", remaining(time.Until(until))))
+		}
+		ps = append(ps, describe(policy.Locked, notes...))
 	}
 	if s.Ignore {
-		ps = append(ps, string(policy.Ignore))
+		ps = append(ps, describe(policy.Ignore))
 	}
 	sort.Strings(ps)
 	return strings.Join(ps, ",")
 }
+
+// remaining gives a duration to the minute, e.g., "3h59m".
+func remaining(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	minutes := int(d / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%dm", minutes/60, minutes%60)
+}
+
+// syncState summarises the sync status of a controller: "error" if
+// the last attempt to apply it failed, or the revision last applied.
+func syncState(s flux.ResourceSyncStatus) string {
+	switch {
+	case s.Error != "":
+		return "error"
+	case len(s.Revision) > 7:
+		return s.Revision[:7]
+	default:
+		return s.Revision
+	}
+}
@@ -90,6 +90,9 @@ func (opts *controllerShowOpts) RunE(cmd *cobra.Command, args []string) error {
 			if reg != "" {
 				reg += "/"
 			}
+			if container.Ordering != "" {
+				repo += fmt.Sprintf(" (latest by %s)", container.Ordering)
+			}
 			if len(container.Available) == 0 {
 				availableErr := container.AvailableError
 				if availableErr == "" {
@@ -99,6 +102,10 @@ func (opts *controllerShowOpts) RunE(cmd *cobra.Command, args []string) error {
 			} else {
 				fmt.Fprintf(out, "%s\t%s\t%s%s\t\n", controllerName, containerName, reg, repo)
 			}
+			var candidateTag string
+			if container.Candidate != nil {
+				_, _, candidateTag = container.Candidate.ID.Components()
+			}
 			foundRunning := false
 			for _, available := range container.Available {
 				running := "|  "
@@ -125,7 +132,13 @@ func (opts *controllerShowOpts) RunE(cmd *cobra.Command, args []string) error {
 					if !available.CreatedAt.IsZero() {
 						createdAt = available.CreatedAt.Format(time.RFC822)
 					}
-					fmt.Fprintf(out, "\t\t%s %s\t%s\n", running, tag, createdAt)
+					// The image automation would release, if it's not
+					// the one running already
+					candidate := ""
+					if candidateTag == tag && tag != currentTag {
+						candidate = " (candidate)"
+					}
+					fmt.Fprintf(out, "\t\t%s %s%s\t%s\n", running, tag, candidate, createdAt)
 				}
 			}
 			controllerName = ""
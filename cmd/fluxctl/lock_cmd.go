@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 
 	"github.com/weaveworks/flux/update"
@@ -11,7 +14,9 @@ type controllerLockOpts struct {
 	namespace  string
 	controller string
 	outputOpts
-	cause update.Cause
+	cause    update.Cause
+	duration time.Duration
+	until    string
 
 	// Deprecated
 	service string
@@ -27,6 +32,8 @@ func (opts *controllerLockOpts) Command() *cobra.Command {
 		Short: "Lock a controller, so it cannot be deployed.",
 		Example: makeExample(
 			"fluxctl lock --controller=deployment/helloworld",
+			"fluxctl lock --controller=deployment/helloworld --for=4h -m 'investigating memory use'",
+			"fluxctl lock --controller=deployment/helloworld --until='2018-03-12 17:00'",
 		),
 		RunE: opts.RunE,
 	}
@@ -34,6 +41,8 @@ func (opts *controllerLockOpts) Command() *cobra.Command {
 	AddCauseFlags(cmd, &opts.cause)
 	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
 	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Controller to lock")
+	cmd.Flags().DurationVar(&opts.duration, "for", 0, "unlock automatically after this long; e.g., 4h")
+	cmd.Flags().StringVar(&opts.until, "until", "", "unlock automatically at this time; e.g., '2018-03-12 17:00' (local time), or '2018-03-12T17:00:00Z'")
 
 	// Deprecated
 	cmd.Flags().StringVarP(&opts.service, "service", "s", "", "Service to lock")
@@ -47,6 +56,24 @@ func (opts *controllerLockOpts) RunE(cmd *cobra.Command, args []string) error {
 		return errorServiceFlagDeprecated
 	}
 
+	var lockUntil time.Time
+	switch {
+	case opts.duration < 0:
+		return newUsageError("--for must not be negative")
+	case opts.duration > 0 && opts.until != "":
+		return newUsageError("--for and --until both specified")
+	case opts.duration > 0:
+		lockUntil = time.Now().Add(opts.duration)
+	case opts.until != "":
+		var err error
+		if lockUntil, err = parseTime(opts.until); err != nil {
+			return newUsageError("--until: " + err.Error())
+		}
+		if !lockUntil.After(time.Now()) {
+			return newUsageError("--until must be in the future")
+		}
+	}
+
 	policyOpts := &controllerPolicyOpts{
 		rootOpts:   opts.rootOpts,
 		outputOpts: opts.outputOpts,
@@ -54,6 +81,21 @@ func (opts *controllerLockOpts) RunE(cmd *cobra.Command, args []string) error {
 		controller: opts.controller,
 		cause:      opts.cause,
 		lock:       true,
+		lockUntil:  lockUntil,
 	}
 	return policyOpts.RunE(cmd, args)
 }
+
+// parseTime parses a time given on the command line, either in full,
+// or as a date and time (or just a date) in local time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time; expected e.g., '2018-03-12 17:00' or '2018-03-12T17:00:00Z'", s)
+}
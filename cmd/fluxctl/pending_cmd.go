@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/update"
+)
+
+type pendingOpts struct {
+	*rootOpts
+}
+
+func newPending(parent *rootOpts) *pendingOpts {
+	return &pendingOpts{rootOpts: parent}
+}
+
+func (opts *pendingOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pending",
+		Short: "List, approve or reject automated releases waiting for approval.",
+	}
+	cmd.AddCommand(
+		newPendingList(opts.rootOpts).Command(),
+		newPendingApprove(opts.rootOpts).Command(),
+		newPendingReject(opts.rootOpts).Command(),
+	)
+	return cmd
+}
+
+type pendingListOpts struct {
+	*rootOpts
+}
+
+func newPendingList(parent *rootOpts) *pendingListOpts {
+	return &pendingListOpts{rootOpts: parent}
+}
+
+func (opts *pendingListOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the automated releases waiting for approval.",
+		Example: makeExample("fluxctl pending list"),
+		RunE:    opts.RunE,
+	}
+	return cmd
+}
+
+func (opts *pendingListOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errorWantedNoArgs
+	}
+
+	pending, err := opts.API.ListPending(context.Background())
+	if err != nil {
+		return err
+	}
+
+	out := newTabwriter()
+	fmt.Fprintln(out, "ID\tCONTROLLER\tCONTAINER\tIMAGE\tPROPOSED")
+	for _, p := range pending {
+		for i, change := range p.Spec.Changes {
+			id, controller, proposed := p.ID, p.ServiceID.String(), p.ProposedAt.Local().Format(time.RFC822)
+			if i > 0 {
+				id, controller, proposed = "", "", ""
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", id, controller, change.Container.Name, change.ImageID, proposed)
+		}
+	}
+	out.Flush()
+	return nil
+}
+
+type pendingApproveOpts struct {
+	*rootOpts
+	outputOpts
+	cause update.Cause
+}
+
+func newPendingApprove(parent *rootOpts) *pendingApproveOpts {
+	return &pendingApproveOpts{rootOpts: parent}
+}
+
+func (opts *pendingApproveOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "approve <id>",
+		Short:   "Approve an automated release, so that it is made.",
+		Example: makeExample("fluxctl pending approve 0b1c0ffee"),
+		RunE:    opts.RunE,
+	}
+	AddOutputFlags(cmd, &opts.outputOpts)
+	AddCauseFlags(cmd, &opts.cause)
+	return cmd
+}
+
+func (opts *pendingApproveOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return newUsageError("please supply the ID of the release to approve, as shown by `fluxctl pending list`")
+	}
+
+	fmt.Fprintf(cmd.OutOrStderr(), "Submitting release ...\n")
+
+	ctx := context.Background()
+	jobID, err := opts.API.ApprovePending(ctx, args[0], opts.cause)
+	if err != nil {
+		return err
+	}
+	return await(ctx, cmd.OutOrStdout(), cmd.OutOrStderr(), opts.API, jobID, true, opts.verbosity)
+}
+
+type pendingRejectOpts struct {
+	*rootOpts
+	cause update.Cause
+}
+
+func newPendingReject(parent *rootOpts) *pendingRejectOpts {
+	return &pendingRejectOpts{rootOpts: parent}
+}
+
+func (opts *pendingRejectOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "reject <id>",
+		Short:   "Reject an automated release, so that it is not made or proposed again.",
+		Example: makeExample("fluxctl pending reject 0b1c0ffee -m 'breaks the login page'"),
+		RunE:    opts.RunE,
+	}
+	AddCauseFlags(cmd, &opts.cause)
+	return cmd
+}
+
+func (opts *pendingRejectOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return newUsageError("please supply the ID of the release to reject, as shown by `fluxctl pending list`")
+	}
+
+	if err := opts.API.RejectPending(context.Background(), args[0], opts.cause); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStderr(), "Rejected release %s\n", args[0])
+	return nil
+}
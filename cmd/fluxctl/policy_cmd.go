@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/weaveworks/flux"
@@ -19,9 +20,14 @@ type controllerPolicyOpts struct {
 	controller string
 	tagAll     string
 	tags       []string
+	orders     []string
+	window     string
+	freeze     string
 
 	automate, deautomate bool
 	lock, unlock         bool
+	pin, unpin           bool
+	lockUntil            time.Time // if locking, when to unlock
 
 	cause update.Cause
 
@@ -40,18 +46,43 @@ func (opts *controllerPolicyOpts) Command() *cobra.Command {
 		Long: `
 Manage policies for a controller.
 
+Given a namespace (with --namespace) but no controller, the policies are
+those of the namespace, and go in its manifest; the controllers in the
+namespace inherit them, unless they have their own.
+
 Tag filter patterns must be specified as 'container=pattern', such as 'foo=1.*'
-where an asterisk means 'match anything'.
+where an asterisk means 'match anything'. A pattern such as 'foo=semver:~1.4'
+instead matches tags that are semantic versions meeting the constraint, and
+picks the highest version rather than the most recently created image; give
+more than one comparison with spaces, as in 'semver:>=2.0.0 <3'. A pattern
+such as 'foo=regex:^main-(\d+)' matches tags with a regular expression
+(which must not contain commas).
 Surrounding these with single-quotes are recommended to avoid shell expansion.
 
+Orderings say which of the images matching a container's pattern is the
+latest, given as 'container=ordering': 'created' (the default, except for
+semver patterns), 'alphabetical' or 'numeric' by tag, or 'semver'. With a
+regex pattern having a capture group, the tags are ordered by what the
+first group captures. Use 'container=default' to remove an ordering.
+
 If both --tag-all and --tag are specified, --tag-all will apply to all
 containers which aren't explicitly named.
+
+Deployment windows are given as days and/or times, and optionally a
+timezone, such as 'Mon-Fri 09:00-17:00 Europe/London'; freezes as date
+ranges, such as '2017-12-22/2018-01-02'. Either can be a comma-separated
+list, or 'none' to remove it.
         `,
 		Example: makeExample(
 			"fluxctl policy --controller=deployment/foo --automate",
 			"fluxctl policy --controller=deployment/foo --lock",
+			"fluxctl policy --controller=deployment/foo --pin-digest",
 			"fluxctl policy --controller=deployment/foo --tag='bar=1.*' --tag='baz=2.*'",
 			"fluxctl policy --controller=deployment/foo --tag-all='master-*' --tag='bar=1.*'",
+			"fluxctl policy --controller=deployment/foo --tag='bar=semver:~1.4'",
+			"fluxctl policy --controller=deployment/foo --tag='bar=regex:^main-(\\d+\\.\\d+)-' --order='bar=numeric'",
+			"fluxctl policy --controller=deployment/foo --deploy-window='Mon-Fri 09:00-17:00 UTC'",
+			"fluxctl policy --namespace=foo --automate --tag-all='semver:~1'",
 		),
 		RunE: opts.RunE,
 	}
@@ -63,10 +94,15 @@ containers which aren't explicitly named.
 	flags.StringVarP(&opts.controller, "controller", "c", "", "Controller to modify")
 	flags.StringVar(&opts.tagAll, "tag-all", "", "Tag filter pattern to apply to all containers")
 	flags.StringSliceVar(&opts.tags, "tag", nil, "Tag filter container/pattern pairs")
+	flags.StringSliceVar(&opts.orders, "order", nil, "Container/ordering pairs, saying which image is latest")
 	flags.BoolVar(&opts.automate, "automate", false, "Automate controller")
 	flags.BoolVar(&opts.deautomate, "deautomate", false, "Deautomate controller")
 	flags.BoolVar(&opts.lock, "lock", false, "Lock controller")
 	flags.BoolVar(&opts.unlock, "unlock", false, "Unlock controller")
+	flags.BoolVar(&opts.pin, "pin-digest", false, "Pin images released to the controller by digest")
+	flags.BoolVar(&opts.unpin, "unpin-digest", false, "Stop pinning images released to the controller by digest")
+	flags.StringVar(&opts.window, "deploy-window", "", "When the controller may be released")
+	flags.StringVar(&opts.freeze, "freeze", "", "Dates during which the controller must not be released")
 
 	// Deprecated
 	flags.StringVarP(&opts.service, "service", "s", "", "Service to modify")
@@ -82,19 +118,29 @@ func (opts *controllerPolicyOpts) RunE(cmd *cobra.Command, args []string) error
 	if len(args) > 0 {
 		return errorWantedNoArgs
 	}
-	if opts.controller == "" {
-		return newUsageError("-c, --controller is required")
-	}
 	if opts.automate && opts.deautomate {
 		return newUsageError("automate and deautomate both specified")
 	}
 	if opts.lock && opts.unlock {
 		return newUsageError("lock and unlock both specified")
 	}
+	if opts.pin && opts.unpin {
+		return newUsageError("pin-digest and unpin-digest both specified")
+	}
 
-	resourceID, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
-	if err != nil {
-		return err
+	var resourceID flux.ResourceID
+	switch {
+	case opts.controller != "":
+		var err error
+		if resourceID, err = flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller); err != nil {
+			return err
+		}
+	case cmd.Flags().Changed("namespace"):
+		// The namespace's own policies, which the controllers in it
+		// inherit
+		resourceID = flux.MakeResourceID(opts.namespace, "namespace", opts.namespace)
+	default:
+		return newUsageError("-c, --controller is required, or -n, --namespace on its own for a namespace's policies")
 	}
 
 	update, err := calculatePolicyChanges(opts)
@@ -126,35 +172,96 @@ func calculatePolicyChanges(opts *controllerPolicyOpts) (policy.Update, error) {
 				Set(policy.LockedMsg, opts.cause.Message)
 		}
 	}
+	if opts.lock && !opts.lockUntil.IsZero() {
+		add = add.Set(policy.LockedUntil, opts.lockUntil.UTC().Format(time.RFC3339))
+	}
+
+	if opts.pin {
+		add = add.Add(policy.PinDigest)
+	}
 
 	remove := policy.Set{}
 	if opts.deautomate {
 		remove = remove.Add(policy.Automated)
 	}
+	if opts.unpin {
+		remove = remove.Add(policy.PinDigest)
+	}
 	if opts.unlock {
 		remove = remove.
 			Add(policy.Locked).
 			Add(policy.LockedMsg).
-			Add(policy.LockedUser)
+			Add(policy.LockedUser).
+			Add(policy.LockedUntil)
+	}
+	// Locking again without an expiry makes the lock stay
+	if opts.lock && opts.lockUntil.IsZero() {
+		remove = remove.Add(policy.LockedUntil)
 	}
 	if opts.tagAll != "" {
-		add = add.Set(policy.TagAll, "glob:"+opts.tagAll)
+		pattern, err := policy.ParsePattern(opts.tagAll)
+		if err != nil {
+			return policy.Update{}, err
+		}
+		add = add.Set(policy.TagAll, pattern.String())
+	}
+
+	switch opts.window {
+	case "":
+	case "none":
+		remove = remove.Add(policy.DeployWindow)
+	default:
+		if _, err := policy.ParseWindows(opts.window); err != nil {
+			return policy.Update{}, err
+		}
+		add = add.Set(policy.DeployWindow, opts.window)
+	}
+	switch opts.freeze {
+	case "":
+	case "none":
+		remove = remove.Add(policy.Freeze)
+	default:
+		if _, err := policy.ParseFreezes(opts.freeze); err != nil {
+			return policy.Update{}, err
+		}
+		add = add.Set(policy.Freeze, opts.freeze)
 	}
 
 	for _, tagPair := range opts.tags {
-		parts := strings.Split(tagPair, "=")
+		parts := strings.SplitN(tagPair, "=", 2)
 		if len(parts) != 2 {
 			return policy.Update{}, fmt.Errorf("invalid container/tag pair: %q. Expected format is 'container=filter'", tagPair)
 		}
 
 		container, tag := parts[0], parts[1]
 		if tag != "*" {
-			add = add.Set(policy.TagPrefix(container), "glob:"+tag)
+			pattern, err := policy.ParsePattern(tag)
+			if err != nil {
+				return policy.Update{}, err
+			}
+			add = add.Set(policy.TagPrefix(container), pattern.String())
 		} else {
 			remove = remove.Add(policy.TagPrefix(container))
 		}
 	}
 
+	for _, orderPair := range opts.orders {
+		parts := strings.SplitN(orderPair, "=", 2)
+		if len(parts) != 2 {
+			return policy.Update{}, fmt.Errorf("invalid container/ordering pair: %q. Expected format is 'container=ordering'", orderPair)
+		}
+
+		container, order := parts[0], parts[1]
+		if order != "default" {
+			if _, err := policy.ParseOrdering(order); err != nil {
+				return policy.Update{}, err
+			}
+			add = add.Set(policy.OrderPrefix(container), order)
+		} else {
+			remove = remove.Add(policy.OrderPrefix(container))
+		}
+	}
+
 	return policy.Update{
 		Add:    add,
 		Remove: remove,
@@ -19,6 +19,7 @@ type controllerReleaseOpts struct {
 	allImages      bool
 	exclude        []string
 	dryRun         bool
+	force          bool
 	outputOpts
 	cause update.Cause
 
@@ -51,6 +52,7 @@ func (opts *controllerReleaseOpts) Command() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.allImages, "update-all-images", false, "update all images to latest versions")
 	cmd.Flags().StringSliceVar(&opts.exclude, "exclude", []string{}, "exclude a controller")
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "do not release anything; just report back what would have been done")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "release even controllers that are outside their deployment window, or in a change freeze")
 
 	// Deprecated
 	cmd.Flags().StringSliceVarP(&opts.services, "service", "s", []string{}, "service to release")
@@ -130,6 +132,7 @@ func (opts *controllerReleaseOpts) RunE(cmd *cobra.Command, args []string) error
 		ImageSpec:    image,
 		Kind:         kind,
 		Excludes:     excludes,
+		Force:        opts.force,
 	}, opts.cause)
 	if err != nil {
 		return err
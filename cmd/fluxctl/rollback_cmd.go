@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/update"
+)
+
+type controllerRollbackOpts struct {
+	*rootOpts
+	namespace  string
+	controller string
+	to         string
+	lock       bool
+	outputOpts
+	cause update.Cause
+}
+
+func newControllerRollback(parent *rootOpts) *controllerRollbackOpts {
+	return &controllerRollbackOpts{rootOpts: parent}
+}
+
+func (opts *controllerRollbackOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll a controller back to the images it had before a release.",
+		Example: makeExample(
+			"fluxctl rollback --controller=deployment/helloworld",
+			"fluxctl rollback --controller=deployment/helloworld --to=8f2e2b1 --lock",
+		),
+		RunE: opts.RunE,
+	}
+	AddOutputFlags(cmd, &opts.outputOpts)
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
+	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Controller to roll back")
+	cmd.Flags().StringVar(&opts.to, "to", "", "the release to roll back, as a job ID or commit; the controller's latest release if not given")
+	cmd.Flags().BoolVar(&opts.lock, "lock", false, "also lock the controller, so it isn't released again until unlocked")
+	return cmd
+}
+
+func (opts *controllerRollbackOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errorWantedNoArgs
+	}
+	if opts.controller == "" {
+		return newUsageError("-c, --controller is required")
+	}
+
+	id, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStderr(), "Submitting rollback ...\n")
+
+	ctx := context.Background()
+	jobID, err := opts.API.Rollback(ctx, update.RollbackSpec{
+		ServiceID: id,
+		To:        opts.to,
+		Lock:      opts.lock,
+	}, opts.cause)
+	if err != nil {
+		return err
+	}
+	return await(ctx, cmd.OutOrStdout(), cmd.OutOrStderr(), opts.API, jobID, true, opts.verbosity)
+}
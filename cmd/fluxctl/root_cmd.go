@@ -61,6 +61,7 @@ func (opts *rootOpts) Command() *cobra.Command {
 		newControllerShow(opts).Command(),
 		newControllerList(opts).Command(),
 		newControllerRelease(opts).Command(),
+		newControllerRollback(opts).Command(),
 		newServiceAutomate(opts).Command(),
 		newControllerDeautomate(opts).Command(),
 		newControllerLock(opts).Command(),
@@ -68,6 +69,10 @@ func (opts *rootOpts) Command() *cobra.Command {
 		newControllerPolicy(opts).Command(),
 		newSave(opts).Command(),
 		newIdentity(opts).Command(),
+		newDiff(opts).Command(),
+		newSuspend(opts).Command(),
+		newResume(opts).Command(),
+		newPending(opts).Command(),
 	)
 
 	return cmd
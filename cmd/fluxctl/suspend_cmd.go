@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/update"
+)
+
+type suspendOpts struct {
+	*rootOpts
+	sync       bool
+	automation bool
+	duration   time.Duration
+	cause      update.Cause
+}
+
+func newSuspend(parent *rootOpts) *suspendOpts {
+	return &suspendOpts{rootOpts: parent}
+}
+
+func (opts *suspendOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "suspend",
+		Short: "Stop syncing the cluster and/or automated releases, until resumed.",
+		Example: makeExample(
+			"fluxctl suspend -m 'incident 123'",
+			"fluxctl suspend --automation --for=2h",
+		),
+		RunE: opts.RunE,
+	}
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().BoolVar(&opts.sync, "sync", false, "suspend syncing the cluster with the git repo")
+	cmd.Flags().BoolVar(&opts.automation, "automation", false, "suspend automated releases")
+	cmd.Flags().DurationVar(&opts.duration, "for", 0, "resume automatically after this long; e.g., 30m")
+	return cmd
+}
+
+func (opts *suspendOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errorWantedNoArgs
+	}
+	if opts.duration < 0 {
+		return newUsageError("--for must not be negative")
+	}
+
+	suspension := flux.Suspension{
+		Sync:       opts.sync,
+		Automation: opts.automation,
+		Reason:     opts.cause.Message,
+		User:       opts.cause.User,
+	}
+	// Suspend everything, unless told otherwise
+	if !opts.sync && !opts.automation {
+		suspension.Sync, suspension.Automation = true, true
+	}
+	if opts.duration > 0 {
+		suspension.Expires = time.Now().Add(opts.duration).UTC()
+	}
+
+	res, err := opts.API.Suspend(context.Background(), suspension)
+	if err != nil {
+		return err
+	}
+	printSuspension(cmd.OutOrStdout(), res)
+	return nil
+}
+
+type resumeOpts struct {
+	*rootOpts
+	sync       bool
+	automation bool
+	cause      update.Cause
+}
+
+func newResume(parent *rootOpts) *resumeOpts {
+	return &resumeOpts{rootOpts: parent}
+}
+
+func (opts *resumeOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Start syncing the cluster and/or automated releases again.",
+		Example: makeExample(
+			"fluxctl resume",
+			"fluxctl resume --automation",
+		),
+		RunE: opts.RunE,
+	}
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().BoolVar(&opts.sync, "sync", false, "resume syncing the cluster with the git repo")
+	cmd.Flags().BoolVar(&opts.automation, "automation", false, "resume automated releases")
+	return cmd
+}
+
+func (opts *resumeOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errorWantedNoArgs
+	}
+
+	resume := flux.Suspension{
+		Sync:       opts.sync,
+		Automation: opts.automation,
+		User:       opts.cause.User,
+	}
+	// Resume everything, unless told otherwise
+	if !opts.sync && !opts.automation {
+		resume.Sync, resume.Automation = true, true
+	}
+
+	res, err := opts.API.Resume(context.Background(), resume)
+	if err != nil {
+		return err
+	}
+	printSuspension(cmd.OutOrStdout(), res)
+	return nil
+}
+
+func printSuspension(out io.Writer, s flux.Suspension) {
+	if !s.Suspended() {
+		fmt.Fprintln(out, "Nothing suspended")
+		return
+	}
+	fmt.Fprintf(out, "Sync:\t\t%s\n", suspendedOrNot(s.Sync))
+	fmt.Fprintf(out, "Automation:\t%s\n", suspendedOrNot(s.Automation))
+	if s.Reason != "" {
+		fmt.Fprintf(out, "Reason:\t\t%s\n", s.Reason)
+	}
+	if s.User != "" {
+		fmt.Fprintf(out, "User:\t\t%s\n", s.User)
+	}
+	if !s.Expires.IsZero() {
+		fmt.Fprintf(out, "Until:\t\t%s\n", s.Expires.Local().Format(time.RFC822))
+	}
+}
+
+func suspendedOrNot(b bool) string {
+	if b {
+		return "suspended"
+	}
+	return "running"
+}
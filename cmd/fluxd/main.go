@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,12 +17,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/pflag"
 	"github.com/weaveworks/go-checkpoint"
+	"k8s.io/client-go/dynamic"
 	k8sclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"context"
 
 	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/changerequest"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/cluster/kubernetes"
 	"github.com/weaveworks/flux/daemon"
@@ -30,6 +34,7 @@ import (
 	daemonhttp "github.com/weaveworks/flux/http/daemon"
 	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/registry"
 	"github.com/weaveworks/flux/registry/cache"
 	registryMemcache "github.com/weaveworks/flux/registry/cache/memcached"
@@ -71,11 +76,12 @@ func main() {
 	var (
 		listenAddr        = fs.StringP("listen", "l", ":3030", "Listen address where /metrics and API will be served")
 		kubernetesKubectl = fs.String("kubernetes-kubectl", "", "Optional, explicit path to kubectl tool")
+		kubernetesApplier = fs.String("kubernetes-applier", "kubectl", `How to apply resources to the cluster: "kubectl" runs the kubectl tool; "dynamic" uses the API directly`)
 		versionFlag       = fs.Bool("version", false, "Get version number")
 		// Git repo & key etc.
 		gitURL       = fs.String("git-url", "", "URL of git repo with Kubernetes manifests; e.g., git@github.com:weaveworks/flux-example")
 		gitBranch    = fs.String("git-branch", "master", "branch of git repo to use for Kubernetes manifests")
-		gitPath      = fs.String("git-path", "", "path within git repo to locate Kubernetes manifests (relative path)")
+		gitPath      = fs.StringSlice("git-path", nil, "path within git repo to locate Kubernetes manifests (relative path); repeat, or give a comma-separated list, to use several paths")
 		gitUser      = fs.String("git-user", "Weave Flux", "username to use as git committer")
 		gitEmail     = fs.String("git-email", "support@weave.works", "email to use as git committer")
 		gitSetAuthor = fs.Bool("git-set-author", false, "If set, the author of git commits will reflect the user who initiated the commit and will differ from the git committer.")
@@ -83,8 +89,32 @@ func main() {
 		// Old git config; still used if --git-label is not supplied, but --git-label is preferred.
 		gitSyncTag  = fs.String("git-sync-tag", defaultGitSyncTag, "tag to use to mark sync progress for this cluster")
 		gitNotesRef = fs.String("git-notes-ref", defaultGitNotesRef, "ref to use for keeping commit annotations in git notes")
+		// More git repos
+		gitExtraRepos = fs.StringArray("git-extra-repo", nil, "another git repo to sync from, given as comma-separated key=value pairs with keys url (required), branch (default master), path (may be repeated) and label (default the main repo's sync tag and notes ref, with the number of the extra repo appended). May be repeated")
+		// Commit signatures
+		gitVerifySignatures = fs.Bool("git-verify-signatures", false, "if set, only sync as far as the last commit with a valid signature from a key in the GPG keyring (see --git-gpg-key-import)")
+		gitGPGKeyImport     = fs.String("git-gpg-key-import", "", "path to a file, or directory of files, with GPG keys to import into the keyring at startup; e.g., a mounted secret. These can be public keys to trust, or the private key to sign with")
+		gitSigningKey       = fs.String("git-signing-key", "", "if set, sign commits and the sync tag with this GPG key (e.g., its fingerprint), which must be in the keyring")
+		// Change requests
+		gitChangeRequest        = fs.String("git-change-request", "none", `how to get commits for releases and policy changes merged: "none" pushes them to --git-branch; "push" pushes each to a branch of its own, to be merged by someone else; "webhook" does that and also POSTs the details to --git-change-request-webhook`)
+		gitChangeRequestWebhook = fs.String("git-change-request-webhook", "", "URL to POST change requests to, with --git-change-request=webhook")
 
 		gitPollInterval = fs.Duration("git-poll-interval", 5*time.Minute, "period at which to poll git repo for new commits")
+		// sync garbage collection
+		syncGC    = fs.Bool("sync-garbage-collection", false, "experimental; delete resources that were applied by fluxd (as marked using the git label), but are no longer in the git repo")
+		syncGCDry = fs.Bool("sync-garbage-collection-dry", false, "experimental; only log what would be deleted by --sync-garbage-collection, rather than deleting it")
+		// drift reporting
+		syncReportOnly = fs.Bool("sync-report-only", false, "don't apply anything to the cluster; only report how it differs from the git repo, as drift events and metrics")
+		// manifest generation
+		manifestGeneration        = fs.Bool("manifest-generation", false, "if set, use the commands in a "+kubernetes.ConfigFilename+" file in a git path, where there is one, to generate the manifests for that path (and to update them)")
+		manifestGenerationTimeout = fs.Duration("manifest-generation-timeout", kubernetes.DefaultGeneratorTimeout, "how long to wait for each command given in a "+kubernetes.ConfigFilename+" file")
+		// deployment windows
+		deployWindow = fs.String("deploy-window", "", `when releases may happen, for controllers without a `+string(policy.DeployWindow)+` policy of their own; e.g., "Mon-Fri 09:00-17:00 Europe/London". Any time, if not given`)
+		deployFreeze = fs.String("deploy-freeze", "", `dates during which releases must not happen, for all controllers; e.g., "2017-12-22/2018-01-02"`)
+		// digest pinning
+		pinDigests = fs.Bool("pin-image-digests", false, "pin every image released by the digest of its manifest (e.g., repo:1.2@sha256:...), rather than only those for controllers with a "+string(policy.PinDigest)+" policy")
+		// image promotion
+		promotionSoak = fs.Duration("promotion-soak-time", time.Hour, "how long an image must have been running healthily in a controller before it is promoted to controllers with a "+string(policy.PromoteFrom)+" policy naming it, unless they have a "+string(policy.PromoteSoak)+" policy of their own")
 		// registry
 		memcachedHostname    = fs.String("memcached-hostname", "memcached", "Hostname for memcached service.")
 		memcachedTimeout     = fs.Duration("memcached-timeout", time.Second, "Maximum time to wait before giving up on memcached requests.")
@@ -95,6 +125,9 @@ func main() {
 		registryBurst        = fs.Int("registry-burst", defaultRemoteConnections, "maximum number of warmer connections to remote and memcache")
 		registryTrace        = fs.Bool("registry-trace", false, "output trace of image registry requests to log")
 
+		// jobs
+		jobStorePath = fs.String("job-store-path", "", "file in which to keep jobs, e.g., on a persistent volume, so that queued jobs are resumed and finished jobs remembered after a restart. If not given, jobs are kept only in memory")
+
 		// k8s-secret backed ssh keyring configuration
 		k8sSecretName            = fs.String("k8s-secret-name", "flux-git-deploy", "Name of the k8s secret used to store the private SSH key")
 		k8sSecretVolumeMountPath = fs.String("k8s-secret-volume-mount-path", "/etc/fluxd/ssh", "Mount location of the k8s secret storing the private SSH key")
@@ -144,12 +177,68 @@ func main() {
 		}
 	}
 
+	// Set up the keyring for checking commit signatures. git is run
+	// with a minimal environment, so make sure it will look in the
+	// same place gpg imports keys into.
+	if *gitVerifySignatures || *gitGPGKeyImport != "" || *gitSigningKey != "" {
+		if _, ok := os.LookupEnv("GNUPGHOME"); !ok && os.Getenv("HOME") != "" {
+			os.Setenv("GNUPGHOME", filepath.Join(os.Getenv("HOME"), ".gnupg"))
+		}
+	}
+	if *gitGPGKeyImport != "" {
+		imported, err := git.ImportKeys(*gitGPGKeyImport)
+		if err != nil {
+			logger.Log("component", "gpg", "err", err)
+			os.Exit(1)
+		}
+		logger.Log("info", "imported GPG keys", "files", strings.Join(imported, ", "))
+	}
+	if *gitSigningKey != "" {
+		// Fail early, rather than at the first commit
+		if _, err := git.ExportKey(*gitSigningKey); err != nil {
+			logger.Log("component", "gpg", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	var changeRequests changerequest.Requester
+	switch *gitChangeRequest {
+	case "none":
+	case "push":
+		changeRequests = changerequest.PushOnly{}
+	case "webhook":
+		if *gitChangeRequestWebhook == "" {
+			logger.Log("err", "--git-change-request-webhook must be given with --git-change-request=webhook")
+			os.Exit(1)
+		}
+		changeRequests = changerequest.Webhook{URL: *gitChangeRequestWebhook}
+	default:
+		logger.Log("err", fmt.Sprintf("unknown value for --git-change-request %q; expected \"none\", \"push\" or \"webhook\"", *gitChangeRequest))
+		os.Exit(1)
+	}
+
+	var deploySchedule policy.Schedule
+	{
+		var err error
+		if deploySchedule.Windows, err = policy.ParseWindows(*deployWindow); err != nil {
+			logger.Log("flag", "--deploy-window", "err", err)
+			os.Exit(1)
+		}
+		if deploySchedule.Freezes, err = policy.ParseFreezes(*deployFreeze); err != nil {
+			logger.Log("flag", "--deploy-freeze", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	// Platform component.
 	var clusterVersion string
 	var sshKeyRing ssh.KeyRing
 	var k8s cluster.Cluster
 	var imageCreds func() registry.ImageCreds
 	var k8sManifests cluster.Manifests
+	var suspensions daemon.SuspensionStore
+	var pendingReleases daemon.PendingStore
+	var soakingImages daemon.SoakStore
 	{
 		restClientConfig, err := rest.InClusterConfig()
 		if err != nil {
@@ -192,6 +281,22 @@ func main() {
 			os.Exit(1)
 		}
 
+		// Suspending syncing and automation, releases waiting for
+		// approval, and images soaking before they're promoted, are
+		// kept in the same secret, so they survive a restart
+		suspensions = kubernetes.SecretSuspensionStore{
+			SecretAPI:  clientset.Core().Secrets(string(namespace)),
+			SecretName: *k8sSecretName,
+		}
+		pendingReleases = kubernetes.SecretPendingStore{
+			SecretAPI:  clientset.Core().Secrets(string(namespace)),
+			SecretName: *k8sSecretName,
+		}
+		soakingImages = kubernetes.SecretSoakStore{
+			SecretAPI:  clientset.Core().Secrets(string(namespace)),
+			SecretName: *k8sSecretName,
+		}
+
 		publicKey, privateKeyPath := sshKeyRing.KeyPair()
 
 		logger := log.With(logger, "component", "platform")
@@ -199,20 +304,30 @@ func main() {
 		logger.Log("identity.pub", publicKey.Key)
 		logger.Log("host", restClientConfig.Host, "version", clusterVersion)
 
-		kubectl := *kubernetesKubectl
-		if kubectl == "" {
-			kubectl, err = exec.LookPath("kubectl")
-		} else {
-			_, err = os.Stat(kubectl)
-		}
-		if err != nil {
-			logger.Log("err", err)
+		var applier kubernetes.Applier
+		switch *kubernetesApplier {
+		case "kubectl":
+			kubectl := *kubernetesKubectl
+			if kubectl == "" {
+				kubectl, err = exec.LookPath("kubectl")
+			} else {
+				_, err = os.Stat(kubectl)
+			}
+			if err != nil {
+				logger.Log("err", err)
+				os.Exit(1)
+			}
+			logger.Log("kubectl", kubectl)
+			applier = kubernetes.NewKubectl(kubectl, restClientConfig)
+		case "dynamic":
+			logger.Log("applier", "dynamic")
+			applier = kubernetes.NewDynamicApplier(clientset.Discovery(), dynamic.NewDynamicClientPool(restClientConfig))
+		default:
+			logger.Log("err", fmt.Sprintf("unknown applier %q; expected \"kubectl\" or \"dynamic\"", *kubernetesApplier))
 			os.Exit(1)
 		}
-		logger.Log("kubectl", kubectl)
 
-		kubectlApplier := kubernetes.NewKubectl(kubectl, restClientConfig)
-		k8sInst := kubernetes.NewCluster(clientset, kubectlApplier, sshKeyRing, logger)
+		k8sInst := kubernetes.NewCluster(clientset, applier, sshKeyRing, logger)
 
 		if err := k8sInst.Ping(); err != nil {
 			logger.Log("ping", err)
@@ -222,9 +337,13 @@ func main() {
 
 		imageCreds = k8sInst.ImagesToFetch
 		k8s = k8sInst
-		// There is only one way we currently interpret a repo of
-		// files as manifests, and that's as Kubernetes yamels.
-		k8sManifests = &kubernetes.Manifests{}
+		// Manifests are interpreted as Kubernetes yamels, either read
+		// from files or, if asked for, output by generator commands.
+		if *manifestGeneration {
+			k8sManifests = kubernetes.NewGeneratorManifests(*manifestGenerationTimeout)
+		} else {
+			k8sManifests = &kubernetes.Manifests{}
+		}
 	}
 
 	// Registry components
@@ -276,8 +395,36 @@ func main() {
 		logger.Log("err", err)
 		os.Exit(1)
 	}
+	gitConfig := git.Config{
+		SyncTag:    *gitSyncTag,
+		NotesRef:   *gitNotesRef,
+		UserName:   *gitUser,
+		UserEmail:  *gitEmail,
+		SetAuthor:  *gitSetAuthor,
+		SigningKey: *gitSigningKey,
+	}
+	repoConfigs := []repoConfig{{gitRemoteConfig, gitConfig}}
+	var extraRemotes []flux.GitRemoteConfig
+	for i, spec := range *gitExtraRepos {
+		rc, err := parseExtraRepo(spec, i+1, repoConfigs[0])
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		// Repos sharing a sync tag or notes ref would overwrite one
+		// another's, if they're the same repo
+		for _, other := range repoConfigs {
+			if rc.git.SyncTag == other.git.SyncTag || rc.git.NotesRef == other.git.NotesRef {
+				logger.Log("err", fmt.Errorf("label of --git-extra-repo %q is already used by the repo %s", spec, other.remote.URL))
+				os.Exit(1)
+			}
+		}
+		repoConfigs = append(repoConfigs, rc)
+		extraRemotes = append(extraRemotes, rc.remote)
+	}
+
 	// Indirect reference to a daemon, initially of the NotReady variety
-	notReadyDaemon := daemon.NewNotReadyDaemon(version, k8s, gitRemoteConfig)
+	notReadyDaemon := daemon.NewNotReadyDaemon(version, k8s, gitRemoteConfig, extraRemotes...)
 	daemonRef := daemon.NewRef(notReadyDaemon)
 
 	var eventWriter event.EventWriter
@@ -349,94 +496,126 @@ func main() {
 	var checker *checkpoint.Checker
 	updateCheckLogger := log.With(logger, "component", "checkpoint")
 
-	var repo git.Repo
-	var checkout *git.Checkout
+	var repos []daemon.Repository
 	{
-		repo = git.Repo{
-			GitRemoteConfig: gitRemoteConfig,
-		}
-		// TODO: should not need to supply all of this -- maybe just
-		// on clone?
-		gitConfig := git.Config{
-			SyncTag:   *gitSyncTag,
-			NotesRef:  *gitNotesRef,
-			UserName:  *gitUser,
-			UserEmail: *gitEmail,
-			SetAuthor: *gitSetAuthor,
-		}
-
 		// If there's no URL here, we will not be able to do anything else.
 		if gitRemoteConfig.URL == "" {
 			checker = checkForUpdates(clusterVersion, "false", updateCheckLogger)
 			return
 		}
 
-		for checkout == nil {
-			var stage flux.GitRepoStatus = flux.RepoNew
-			ctx, cancel := context.WithTimeout(context.Background(), git.DefaultCloneTimeout)
-			working, err := repo.Clone(ctx, gitConfig)
-			cancel()
-			if err == nil {
-				stage = flux.RepoCloned
-				ctx, cancel = context.WithTimeout(context.Background(), git.DefaultCloneTimeout)
-				err = working.CheckOriginWritable(ctx)
-				cancel()
+		for _, rc := range repoConfigs {
+			repo := git.Repo{
+				GitRemoteConfig: rc.remote,
 			}
-			if err == nil {
-				notReadyDaemon.UpdateStatus(flux.RepoReady, nil)
-				if checker != nil {
-					checker.Stop()
+			var checkout *git.Checkout
+			for checkout == nil {
+				var stage flux.GitRepoStatus = flux.RepoNew
+				ctx, cancel := context.WithTimeout(context.Background(), git.DefaultCloneTimeout)
+				working, err := repo.Clone(ctx, rc.git)
+				cancel()
+				if err == nil {
+					stage = flux.RepoCloned
+					ctx, cancel = context.WithTimeout(context.Background(), git.DefaultCloneTimeout)
+					err = working.CheckOriginWritable(ctx)
+					cancel()
+				}
+				if err == nil {
+					logger.Log("url", rc.remote.URL,
+						"working-dir", working.Dir,
+						"user", rc.git.UserName,
+						"email", rc.git.UserEmail,
+						"sync-tag", rc.git.SyncTag,
+						"notes-ref", rc.git.NotesRef,
+						"set-author", rc.git.SetAuthor)
+					checkout = working
+					break
 				}
-				checker = checkForUpdates(clusterVersion, "true", updateCheckLogger)
-				logger.Log("working-dir", working.Dir,
-					"user", *gitUser,
-					"email", *gitEmail,
-					"sync-tag", *gitSyncTag,
-					"notes-ref", *gitNotesRef,
-					"set-author", *gitSetAuthor)
-				checkout = working
-				break
-			}
 
-			notReadyDaemon.UpdateStatus(stage, err)
-			if checker == nil {
-				checker = checkForUpdates(clusterVersion, "false", updateCheckLogger)
-			}
-			logger.Log("component", "git", "err", err.Error())
-
-			tryAgain := time.NewTimer(10 * time.Second)
-			select {
-			case err := <-errc:
-				go func() { errc <- err }()
-				return
-			case <-tryAgain.C:
-				continue
+				notReadyDaemon.UpdateStatus(stage, err)
+				if checker == nil {
+					checker = checkForUpdates(clusterVersion, "false", updateCheckLogger)
+				}
+				logger.Log("component", "git", "url", rc.remote.URL, "err", err.Error())
+
+				tryAgain := time.NewTimer(10 * time.Second)
+				select {
+				case err := <-errc:
+					go func() { errc <- err }()
+					return
+				case <-tryAgain.C:
+					continue
+				}
 			}
+			repos = append(repos, daemon.Repository{Repo: repo, Checkout: checkout})
+		}
+
+		notReadyDaemon.UpdateStatus(flux.RepoReady, nil)
+		if checker != nil {
+			checker.Stop()
 		}
+		checker = checkForUpdates(clusterVersion, "true", updateCheckLogger)
 	}
 
 	var jobs *job.Queue
+	var jobStore job.Store
 	{
 		jobs = job.NewQueue(shutdown, shutdownWg)
+		if *jobStorePath != "" {
+			jobStore = &job.FileStore{Path: *jobStorePath, Size: 100}
+		}
 	}
 
 	daemon := &daemon.Daemon{
-		V:            version,
-		Cluster:      k8s,
-		Manifests:    k8sManifests,
-		Registry:     cacheRegistry,
-		ImageRefresh: make(chan image.Name, 100), // size chosen by fair dice roll
-		Repo:         repo, Checkout: checkout,
+		V:              version,
+		Cluster:        k8s,
+		Manifests:      k8sManifests,
+		Registry:       cacheRegistry,
+		ImageRefresh:   make(chan image.Name, 100), // size chosen by fair dice roll
+		Repos:          repos,
+		ChangeRequests: changeRequests,
+		DeploySchedule: deploySchedule,
+		PinDigests:     *pinDigests,
 		Jobs:           jobs,
 		JobStatusCache: &job.StatusCache{Size: 100},
+		JobStore:       jobStore,
 
 		EventWriter: eventWriter,
 		Logger:      log.With(logger, "component", "daemon"), LoopVars: &daemon.LoopVars{
-			GitPollInterval:      *gitPollInterval,
-			RegistryPollInterval: *registryPollInterval,
+			GitPollInterval:             *gitPollInterval,
+			RegistryPollInterval:        *registryPollInterval,
+			PromotionSoak:               *promotionSoak,
+			SyncGarbageCollection:       *syncGC,
+			SyncGarbageCollectionDryRun: *syncGCDry,
+			SyncReportOnly:              *syncReportOnly,
+			GitVerifySignatures:         *gitVerifySignatures,
+			Suspensions:                 suspensions,
+			PendingReleases:             pendingReleases,
+			SoakingImages:               soakingImages,
 		},
 	}
 
+	if err := daemon.LoadSuspension(); err != nil {
+		logger.Log("component", "daemon", "err", err)
+	}
+	if err := daemon.LoadPending(); err != nil {
+		logger.Log("component", "daemon", "err", err)
+	}
+	if err := daemon.LoadSoaking(); err != nil {
+		logger.Log("component", "daemon", "err", err)
+	}
+	if err := daemon.ResumeJobs(); err != nil {
+		logger.Log("component", "daemon", "err", err)
+	}
+
+	// Two repos defining the same resource is a mistake in
+	// configuration, so refuse to go any further.
+	if _, err := daemon.LoadResources(); err != nil {
+		logger.Log("component", "git", "err", err)
+		go func() { errc <- err }()
+		return
+	}
+
 	shutdownWg.Add(1)
 	go daemon.GitPollLoop(shutdown, shutdownWg, log.With(logger, "component", "sync-loop"))
 
@@ -450,3 +629,63 @@ func main() {
 
 	// Fall off the end, into the waiting procedure.
 }
+
+// repoConfig is what's needed to clone and use a git repo.
+type repoConfig struct {
+	remote flux.GitRemoteConfig
+	git    git.Config
+}
+
+// parseExtraRepo parses the value of a --git-extra-repo flag, which
+// is a comma-separated list of key=value pairs, e.g.,
+//
+//	url=git@github.com:example/apps,branch=prod,path=apps,path=base
+//
+// The url must be given; the branch is master unless given. The
+// label is used as both the sync tag and the notes ref, and defaults
+// to those of the main repo with the number of the extra repo
+// appended (so the first extra repo gets e.g., flux-sync-1), so that
+// each repo keeps its own. Only the committing and signing settings
+// are taken from the main repo.
+func parseExtraRepo(spec string, n int, main repoConfig) (repoConfig, error) {
+	rc := repoConfig{
+		remote: flux.GitRemoteConfig{Branch: "master"},
+		git: git.Config{
+			SyncTag:    fmt.Sprintf("%s-%d", main.git.SyncTag, n),
+			NotesRef:   fmt.Sprintf("%s-%d", main.git.NotesRef, n),
+			UserName:   main.git.UserName,
+			UserEmail:  main.git.UserEmail,
+			SetAuthor:  main.git.SetAuthor,
+			SigningKey: main.git.SigningKey,
+		},
+	}
+	var paths []string
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return rc, fmt.Errorf("expected key=value in --git-extra-repo %q, got %q", spec, pair)
+		}
+		switch kv[0] {
+		case "url":
+			rc.remote.URL = kv[1]
+		case "branch":
+			rc.remote.Branch = kv[1]
+		case "path":
+			paths = append(paths, kv[1])
+		case "label":
+			rc.git.SyncTag = kv[1]
+			rc.git.NotesRef = kv[1]
+		default:
+			return rc, fmt.Errorf("unknown key %q in --git-extra-repo %q", kv[0], spec)
+		}
+	}
+	if rc.remote.URL == "" {
+		return rc, fmt.Errorf("no url given in --git-extra-repo %q", spec)
+	}
+	remote, err := flux.NewGitRemoteConfig(rc.remote.URL, rc.remote.Branch, paths)
+	if err != nil {
+		return rc, err
+	}
+	rc.remote = remote
+	return rc, nil
+}
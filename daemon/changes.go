@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weaveworks/flux/changerequest"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/update"
+)
+
+// jobBranch is the branch to which the commit for a job is pushed,
+// when asking for changes to be merged rather than pushing them
+// straight to the synced branch.
+func jobBranch(jobID job.ID, spec update.Spec) string {
+	kind := "update"
+	switch spec.Type {
+	case update.Images:
+		kind = "release"
+	case update.Auto:
+		kind = "auto-release"
+	case update.Policy:
+		kind = "policy"
+	case update.Rollback:
+		kind = "rollback"
+	}
+	return fmt.Sprintf("flux/%s-%s", kind, jobID)
+}
+
+// commitAndPush commits the changes made by a job, and records the
+// revision in the metadata given. If the daemon is set up to make
+// change requests, the commit in each repo with changes is pushed to
+// a branch for the job, and a request opened to merge it; otherwise,
+// it's pushed to the synced branch.
+func (d *Daemon) commitAndPush(ctx context.Context, jobID job.ID, working workingClones, commitAction *git.CommitAction, note *git.Note, metadata *event.CommitEventMetadata) error {
+	if d.ChangeRequests == nil {
+		revision, err := working.CommitAndPush(ctx, commitAction, note)
+		if err != nil {
+			return err
+		}
+		metadata.Revision = revision
+		return nil
+	}
+
+	branch := jobBranch(jobID, note.Spec)
+	revisions, err := working.commitAndPushBranch(ctx, branch, commitAction, note)
+	if err != nil {
+		return err
+	}
+	title := commitAction.Message
+	if i := strings.Index(title, "\n"); i >= 0 {
+		title = title[:i]
+	}
+	var requests []string
+	for i, rev := range revisions {
+		if rev == "" {
+			continue
+		}
+		if metadata.Revision == "" {
+			metadata.Revision = rev
+		}
+		repo := d.Repos[i].Repo.GitRemoteConfig
+		ref, err := d.ChangeRequests.Open(ctx, changerequest.Request{
+			JobID:    jobID,
+			Repo:     repo,
+			Branch:   branch,
+			Base:     repo.Branch,
+			Revision: rev,
+			Title:    title,
+			Message:  commitAction.Message,
+		})
+		if err != nil {
+			return err
+		}
+		if ref != "" {
+			requests = append(requests, ref)
+		}
+	}
+	metadata.Branch = branch
+	metadata.ChangeRequest = strings.Join(requests, ", ")
+	return nil
+}
+
+// isMerged says whether a commit pushed to a branch of its own has
+// made it to the synced branch of any of the repos (as far as we've
+// seen, from the last pull). Since this looks for the commit itself,
+// a merge that rewrites it (e.g., squashing or rebasing) won't be
+// noticed.
+func (d *Daemon) isMerged(ctx context.Context, rev string) (bool, error) {
+	for _, r := range d.Repos {
+		merged, err := r.Checkout.IsAncestor(ctx, rev, "HEAD")
+		if err != nil {
+			return false, err
+		}
+		if merged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/changerequest"
 	"github.com/weaveworks/flux/cluster"
 	fluxerr "github.com/weaveworks/flux/errors"
 	"github.com/weaveworks/flux/event"
@@ -22,6 +23,7 @@ import (
 	"github.com/weaveworks/flux/registry"
 	"github.com/weaveworks/flux/release"
 	"github.com/weaveworks/flux/remote"
+	fluxsync "github.com/weaveworks/flux/sync"
 	"github.com/weaveworks/flux/update"
 )
 
@@ -42,10 +44,13 @@ type Daemon struct {
 	Manifests      cluster.Manifests
 	Registry       registry.Registry
 	ImageRefresh   chan image.Name
-	Repo           git.Repo
-	Checkout       *git.Checkout
+	Repos          []Repository            // at least one
+	ChangeRequests changerequest.Requester // if set, jobs push to a branch of their own and ask for it to be merged
+	DeploySchedule policy.Schedule         // default deployment windows and freezes
+	PinDigests     bool                    // pin every image released by digest, not just those for controllers with a pin_digest policy
 	Jobs           *job.Queue
 	JobStatusCache *job.StatusCache
+	JobStore       job.Store // if set, jobs are kept here too, so they survive a restart
 	EventWriter    event.EventWriter
 	Logger         log.Logger
 	// bookkeeping
@@ -67,37 +72,116 @@ func (d *Daemon) Export(ctx context.Context) ([]byte, error) {
 	return d.Cluster.Export()
 }
 
+// Diff reports how the resources in the cluster differ from those
+// defined in the repo, optionally restricted to a single resource.
+func (d *Daemon) Diff(ctx context.Context, spec update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	unlock := d.readLockRepos()
+	resources, byRepo, err := loadResources(d.Manifests, d.checkouts())
+	unlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "loading resources from repo")
+	}
+	diffs, err := fluxsync.Diff(d.Manifests, resources, d.Cluster, d.syncConfig(byRepo))
+	if err != nil {
+		return nil, err
+	}
+	if spec == update.ResourceSpecAll {
+		return diffs, nil
+	}
+
+	id, err := spec.AsID()
+	if err != nil {
+		return nil, errors.Wrap(err, "treating resource spec as ID")
+	}
+	var res []flux.ResourceDiff
+	for _, diff := range diffs {
+		if diff.ID == id {
+			res = append(res, diff)
+		}
+	}
+	return res, nil
+}
+
+func (d *Daemon) SyncOutcome(ctx context.Context) (flux.SyncOutcome, error) {
+	return d.LastSync(), nil
+}
+
+// ListResources gives the sync status of the resources defined in
+// the repo, including those that are not controllers.
+func (d *Daemon) ListResources(ctx context.Context, namespace string) ([]flux.ResourceStatus, error) {
+	var res []flux.ResourceStatus
+	for _, status := range d.ResourceSyncs() {
+		if namespace != "" {
+			ns, _, _ := status.ID.Components()
+			if ns != namespace {
+				continue
+			}
+		}
+		res = append(res, status)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID.String() < res[j].ID.String()
+	})
+	return res, nil
+}
+
 func (d *Daemon) ListServices(ctx context.Context, namespace string) ([]flux.ControllerStatus, error) {
 	clusterServices, err := d.Cluster.AllControllers(namespace)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting services from cluster")
 	}
 
-	d.Checkout.RLock()
-	defer d.Checkout.RUnlock()
-
-	services, err := d.Manifests.ServicesWithPolicies(d.Checkout.ManifestDir())
+	unlock := d.readLockRepos()
+	services, err := d.Manifests.ServicesWithPolicies(manifestDirs(d.checkouts())...)
 	if err != nil {
+		unlock()
 		return nil, errors.Wrap(err, "getting service policies")
 	}
+	namespaces, err := d.Manifests.NamespacesWithPolicies(manifestDirs(d.checkouts())...)
+	unlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting namespace policies")
+	}
+
+	syncs := d.ResourceSyncs()
+	now := time.Now()
 
 	var res []flux.ControllerStatus
 	for _, service := range clusterServices {
 		policies := services[service.ID]
+		ns, _, _ := service.ID.Components()
 		res = append(res, flux.ControllerStatus{
 			ID:         service.ID,
 			Containers: containers2containers(service.ContainersOrNil()),
 			Status:     service.Status,
 			Automated:  policies.Contains(policy.Automated),
-			Locked:     policies.Contains(policy.Locked),
+			Locked:     policies.Contains(policy.Locked) && !policies.LockExpired(now),
 			Ignore:     policies.Contains(policy.Ignore),
 			Policies:   policies.ToStringMap(),
+			Inherited:  inheritedPolicies(policies, namespaces[ns]),
+			Sync:       syncs[service.ID.String()].Sync,
 		})
 	}
 
 	return res, nil
 }
 
+// inheritedPolicies gives the names of the policies a controller has
+// from its namespace; that is, those it has with the same value as
+// the namespace (or, for a tag filter, as the namespace's tag_all).
+func inheritedPolicies(policies, namespace policy.Set) []string {
+	tagAll, hasTagAll := namespace.Get(policy.TagAll)
+	var inherited []string
+	for p, v := range policies {
+		if nv, ok := namespace.Get(p); ok && nv == v ||
+			!ok && hasTagAll && policy.Tag(p) && v == tagAll {
+			inherited = append(inherited, string(p))
+		}
+	}
+	sort.Strings(inherited)
+	return inherited
+}
+
 // List the images available for set of services
 func (d *Daemon) ListImages(ctx context.Context, spec update.ResourceSpec) ([]flux.ImageStatus, error) {
 	var services []cluster.Controller
@@ -117,9 +201,17 @@ func (d *Daemon) ListImages(ctx context.Context, spec update.ResourceSpec) ([]fl
 		return nil, errors.Wrap(err, "getting images for services")
 	}
 
+	// The tag policies say which image is the candidate for release
+	unlock := d.readLockRepos()
+	policies, err := d.Manifests.ServicesWithPolicies(manifestDirs(d.checkouts())...)
+	unlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting policies for services")
+	}
+
 	var res []flux.ImageStatus
 	for _, service := range services {
-		containers := containersWithAvailable(service, images)
+		containers := containersWithAvailable(service, images, policies)
 		res = append(res, flux.ImageStatus{
 			ID:         service.ID,
 			Containers: containers,
@@ -132,33 +224,60 @@ func (d *Daemon) ListImages(ctx context.Context, spec update.ResourceSpec) ([]fl
 // Let's use the CommitEventMetadata as a convenient transport for the
 // results of a job; if no commit was made (e.g., if it was a dry
 // run), leave the revision field empty.
-type DaemonJobFunc func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (*event.CommitEventMetadata, error)
+type DaemonJobFunc func(ctx context.Context, jobID job.ID, working workingClones, logger log.Logger) (*event.CommitEventMetadata, error)
 
-// executeJob runs a job func in a cloned working directory, keeping track of its status.
+// executeJob runs a job func in cloned working directories, keeping track of its status.
 func (d *Daemon) executeJob(id job.ID, do DaemonJobFunc, logger log.Logger) (*event.CommitEventMetadata, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultJobTimeout)
 	defer cancel()
-	d.JobStatusCache.SetStatus(id, job.Status{StatusString: job.StatusRunning})
-	// make a working clone so we don't mess with files we
+	d.setJobStatus(id, job.Status{StatusString: job.StatusRunning})
+	// make working clones so we don't mess with files we
 	// will be reading from elsewhere
-	working, err := d.Checkout.WorkingClone(ctx)
+	working, err := d.workingClones(ctx)
 	if err != nil {
-		d.JobStatusCache.SetStatus(id, job.Status{StatusString: job.StatusFailed, Err: err.Error()})
+		d.setJobStatus(id, job.Status{StatusString: job.StatusFailed, Err: err.Error()})
 		return nil, err
 	}
 	defer working.Clean()
 	metadata, err := do(ctx, id, working, logger)
 	if err != nil {
-		d.JobStatusCache.SetStatus(id, job.Status{StatusString: job.StatusFailed, Err: err.Error()})
+		d.setJobStatus(id, job.Status{StatusString: job.StatusFailed, Err: err.Error()})
 		return metadata, err
 	}
-	d.JobStatusCache.SetStatus(id, job.Status{StatusString: job.StatusSucceeded, Result: *metadata})
+	status := job.StatusSucceeded
+	if metadata.Branch != "" {
+		status = job.StatusAwaitingMerge
+	}
+	d.setJobStatus(id, job.Status{StatusString: status, Result: *metadata})
 	return metadata, nil
 }
 
-// queueJob queues a job func to be executed.
-func (d *Daemon) queueJob(do DaemonJobFunc) job.ID {
+// setJobStatus records the status of a job, in the cache and, if
+// there is one, the job store.
+func (d *Daemon) setJobStatus(id job.ID, status job.Status) {
+	d.JobStatusCache.SetStatus(id, status)
+	if d.JobStore != nil {
+		if err := d.JobStore.SetStatus(id, status); err != nil {
+			d.Logger.Log("job", id, "err", errors.Wrap(err, "storing job status"))
+		}
+	}
+}
+
+// queueJob queues a job to be executed, keeping its spec in the job
+// store, if there is one, so it can be resumed after a restart.
+func (d *Daemon) queueJob(spec update.Spec, do DaemonJobFunc) job.ID {
 	id := job.ID(guid.New())
+	if d.JobStore != nil {
+		if err := d.JobStore.Queued(id, spec); err != nil {
+			d.Logger.Log("job", id, "err", errors.Wrap(err, "storing job"))
+		}
+	}
+	d.enqueueJob(id, do)
+	return id
+}
+
+// enqueueJob puts a job on the queue, with the ID given.
+func (d *Daemon) enqueueJob(id job.ID, do DaemonJobFunc) {
 	enqueuedAt := time.Now()
 	d.Jobs.Enqueue(&job.Job{
 		ID: id,
@@ -191,7 +310,33 @@ func (d *Daemon) queueJob(do DaemonJobFunc) job.ID {
 	})
 	queueLength.Set(float64(d.Jobs.Len()))
 	d.JobStatusCache.SetStatus(id, job.Status{StatusString: job.StatusQueued})
-	return id
+}
+
+// ResumeJobs queues again the jobs in the job store that were queued
+// or running when the daemon was last stopped.
+func (d *Daemon) ResumeJobs() error {
+	if d.JobStore == nil {
+		return nil
+	}
+	unfinished, err := d.JobStore.Unfinished()
+	if err != nil {
+		return err
+	}
+	for _, r := range unfinished {
+		var do DaemonJobFunc
+		if r.Spec != nil {
+			do, err = d.jobFunc(*r.Spec)
+		} else {
+			err = errors.New("no spec kept for job")
+		}
+		if err != nil {
+			d.setJobStatus(r.ID, job.Status{StatusString: job.StatusFailed, Err: errors.Wrap(err, "resuming job").Error()})
+			continue
+		}
+		d.Logger.Log("msg", "resuming job", "job", r.ID, "queued", r.QueuedAt)
+		d.enqueueJob(r.ID, do)
+	}
+	return nil
 }
 
 // Apply the desired changes to the config files
@@ -200,23 +345,37 @@ func (d *Daemon) UpdateManifests(ctx context.Context, spec update.Spec) (job.ID,
 	if spec.Type == "" {
 		return id, errors.New("no type in update spec")
 	}
+	if s, ok := spec.Spec.(release.Changes); ok && s.ReleaseKind() == update.ReleaseKindPlan {
+		id := job.ID(guid.New())
+		_, err := d.executeJob(id, d.release(spec, s), d.Logger)
+		return id, err
+	}
+	do, err := d.jobFunc(spec)
+	if err != nil {
+		return id, err
+	}
+	return d.queueJob(spec, do), nil
+}
+
+// jobFunc gives the job func that carries out the spec given.
+func (d *Daemon) jobFunc(spec update.Spec) (DaemonJobFunc, error) {
 	switch s := spec.Spec.(type) {
+	case update.Automated:
+		// as it comes back from the job store
+		return d.release(spec, &s), nil
 	case release.Changes:
-		if s.ReleaseKind() == update.ReleaseKindPlan {
-			id := job.ID(guid.New())
-			_, err := d.executeJob(id, d.release(spec, s), d.Logger)
-			return id, err
-		}
-		return d.queueJob(d.release(spec, s)), nil
+		return d.release(spec, s), nil
 	case policy.Updates:
-		return d.queueJob(d.updatePolicy(spec, s)), nil
+		return d.updatePolicy(spec, s), nil
+	case update.RollbackSpec:
+		return d.rollback(spec, s), nil
 	default:
-		return id, fmt.Errorf(`unknown update type "%s"`, spec.Type)
+		return nil, fmt.Errorf(`unknown update type "%s"`, spec.Type)
 	}
 }
 
 func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) DaemonJobFunc {
-	return func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (*event.CommitEventMetadata, error) {
+	return func(ctx context.Context, jobID job.ID, working workingClones, logger log.Logger) (*event.CommitEventMetadata, error) {
 		// For each update
 		var serviceIDs []flux.ResourceID
 		metadata := &event.CommitEventMetadata{
@@ -233,28 +392,8 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) DaemonJo
 			if policy.Set(u.Add).Contains(policy.Automated) {
 				anythingAutomated = true
 			}
-			// find the service manifest
-			err := cluster.UpdateManifest(d.Manifests, working.ManifestDir(), serviceID, func(def []byte) ([]byte, error) {
-				newDef, err := d.Manifests.UpdatePolicies(def, u)
-				if err != nil {
-					metadata.Result[serviceID] = update.ControllerResult{
-						Status: update.ReleaseStatusFailed,
-						Error:  err.Error(),
-					}
-					return nil, err
-				}
-				if string(newDef) == string(def) {
-					metadata.Result[serviceID] = update.ControllerResult{
-						Status: update.ReleaseStatusSkipped,
-					}
-				} else {
-					serviceIDs = append(serviceIDs, serviceID)
-					metadata.Result[serviceID] = update.ControllerResult{
-						Status: update.ReleaseStatusSuccess,
-					}
-				}
-				return newDef, nil
-			})
+			// find the service manifest and update it
+			changed, err := cluster.UpdatePolicies(d.Manifests, working.ManifestDirs(), serviceID, u)
 			switch err {
 			case cluster.ErrNoResourceFilesFoundForService, cluster.ErrMultipleResourceFilesFoundForService:
 				metadata.Result[serviceID] = update.ControllerResult{
@@ -262,7 +401,16 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) DaemonJo
 					Error:  err.Error(),
 				}
 			case nil:
-				// continue
+				if changed {
+					serviceIDs = append(serviceIDs, serviceID)
+					metadata.Result[serviceID] = update.ControllerResult{
+						Status: update.ReleaseStatusSuccess,
+					}
+				} else {
+					metadata.Result[serviceID] = update.ControllerResult{
+						Status: update.ReleaseStatusSkipped,
+					}
+				}
 			default:
 				return nil, err
 			}
@@ -272,11 +420,11 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) DaemonJo
 		}
 
 		commitAuthor := ""
-		if d.Checkout.Config.SetAuthor {
+		if d.primary().Checkout.Config.SetAuthor {
 			commitAuthor = spec.Cause.User
 		}
 		commitAction := &git.CommitAction{Author: commitAuthor, Message: policyCommitMessage(updates, spec.Cause)}
-		if err := working.CommitAndPush(ctx, commitAction, &git.Note{JobID: jobID, Spec: spec}); err != nil {
+		if err := d.commitAndPush(ctx, jobID, working, commitAction, &git.Note{JobID: jobID, Spec: spec}, metadata); err != nil {
 			// On the chance pushing failed because it was not
 			// possible to fast-forward, ask for a sync so the
 			// next attempt is more likely to succeed.
@@ -287,51 +435,43 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) DaemonJo
 			d.AskForImagePoll()
 		}
 
-		var err error
-		metadata.Revision, err = working.HeadRevision(ctx)
-		if err != nil {
-			return nil, err
-		}
 		return metadata, nil
 	}
 }
 
 func (d *Daemon) release(spec update.Spec, c release.Changes) DaemonJobFunc {
-	return func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (*event.CommitEventMetadata, error) {
-		rc := release.NewReleaseContext(d.Cluster, d.Manifests, d.Registry, working)
+	return func(ctx context.Context, jobID job.ID, working workingClones, logger log.Logger) (*event.CommitEventMetadata, error) {
+		rc := release.NewReleaseContext(d.Cluster, d.Manifests, d.Registry, working...)
+		rc.Schedule = d.DeploySchedule
+		rc.PinDigests = d.PinDigests
 		result, err := release.Release(rc, c, logger)
 		if err != nil {
 			return nil, err
 		}
 
-		var revision string
+		metadata := &event.CommitEventMetadata{
+			Spec:   &spec,
+			Result: result,
+		}
 		if c.ReleaseKind() == update.ReleaseKindExecute {
 			commitMsg := spec.Cause.Message
 			if commitMsg == "" {
 				commitMsg = c.CommitMessage()
 			}
 			commitAuthor := ""
-			if d.Checkout.Config.SetAuthor {
+			if d.primary().Checkout.Config.SetAuthor {
 				commitAuthor = spec.Cause.User
 			}
 			commitAction := &git.CommitAction{Author: commitAuthor, Message: commitMsg}
-			if err := working.CommitAndPush(ctx, commitAction, &git.Note{JobID: jobID, Spec: spec, Result: result}); err != nil {
+			if err := d.commitAndPush(ctx, jobID, working, commitAction, &git.Note{JobID: jobID, Spec: spec, Result: result}, metadata); err != nil {
 				// On the chance pushing failed because it was not
 				// possible to fast-forward, ask for a sync so the
 				// next attempt is more likely to succeed.
 				d.AskForSync()
 				return nil, err
 			}
-			revision, err = working.HeadRevision(ctx)
-			if err != nil {
-				return nil, err
-			}
 		}
-		return &event.CommitEventMetadata{
-			Revision: revision,
-			Spec:     &spec,
-			Result:   result,
-		}, nil
+		return metadata, nil
 	}
 }
 
@@ -359,34 +499,60 @@ func (d *Daemon) NotifyChange(ctx context.Context, change remote.Change) error {
 func (d *Daemon) JobStatus(ctx context.Context, jobID job.ID) (job.Status, error) {
 	// Is the job queued, running, or recently finished?
 	status, ok := d.JobStatusCache.Status(jobID)
+	if !ok && d.JobStore != nil {
+		// If jobs are kept in a store, it knows about every job
+		// there is to know about
+		var err error
+		status, ok, err = d.JobStore.Status(jobID)
+		if err != nil {
+			return job.Status{}, errors.Wrap(err, "looking up job in store")
+		}
+		if !ok {
+			return job.Status{}, unknownJobError(jobID)
+		}
+	}
 	if ok {
+		// A commit awaiting merge is done with once it's reached
+		// the synced branch
+		if status.StatusString == job.StatusAwaitingMerge {
+			merged, err := d.isMerged(ctx, status.Result.Revision)
+			if err != nil {
+				return job.Status{}, err
+			}
+			if merged {
+				status.StatusString = job.StatusSucceeded
+				d.setJobStatus(jobID, status)
+			}
+		}
 		return status, nil
 	}
 
 	// Look through the commits for a note referencing this job.  This
 	// means that even if fluxd restarts, we will at least remember
 	// jobs which have pushed a commit.
-	notes, err := d.Checkout.NoteRevList(ctx)
-	if err != nil {
-		return job.Status{}, errors.Wrap(err, "enumerating commit notes")
-	}
-	commits, err := d.Checkout.CommitsBefore(ctx, "HEAD")
-	if err != nil {
-		return job.Status{}, errors.Wrap(err, "checking revisions for status")
-	}
-
-	for _, commit := range commits {
-		if _, ok := notes[commit.Revision]; ok {
-			note, _ := d.Checkout.GetNote(ctx, commit.Revision)
-			if note != nil && note.JobID == jobID {
-				return job.Status{
-					StatusString: job.StatusSucceeded,
-					Result: event.CommitEventMetadata{
-						Revision: commit.Revision,
-						Spec:     &note.Spec,
-						Result:   note.Result,
-					},
-				}, nil
+	for _, checkout := range d.checkouts() {
+		notes, err := checkout.NoteRevList(ctx)
+		if err != nil {
+			return job.Status{}, errors.Wrap(err, "enumerating commit notes")
+		}
+		commits, err := checkout.CommitsBefore(ctx, "HEAD")
+		if err != nil {
+			return job.Status{}, errors.Wrap(err, "checking revisions for status")
+		}
+
+		for _, commit := range commits {
+			if _, ok := notes[commit.Revision]; ok {
+				note, _ := checkout.GetNote(ctx, commit.Revision)
+				if note != nil && note.JobID == jobID {
+					return job.Status{
+						StatusString: job.StatusSucceeded,
+						Result: event.CommitEventMetadata{
+							Revision: commit.Revision,
+							Spec:     &note.Spec,
+							Result:   note.Result,
+						},
+					}, nil
+				}
 			}
 		}
 	}
@@ -399,16 +565,39 @@ func (d *Daemon) JobStatus(ctx context.Context, jobID job.ID) (job.Status, error
 // we have applied and the ref given, inclusive. E.g., if you send HEAD,
 // you'll get all the commits yet to be applied. If you send a hash
 // and it's applied _past_ it, you'll get an empty list.
+//
+// With more than one repo, the commits are from whichever repos know
+// about the ref given; e.g., HEAD will give the commits yet to be
+// applied from all repos, while a hash will (almost certainly) be
+// found in just one.
 func (d *Daemon) SyncStatus(ctx context.Context, commitRef string) ([]string, error) {
-	commits, err := d.Checkout.CommitsBetween(ctx, d.Checkout.SyncTag, commitRef)
-	if err != nil {
-		return nil, err
-	}
 	// NB we could use the messages too if we decide to change the
 	// signature of the API to include it.
-	revs := make([]string, len(commits))
-	for i, commit := range commits {
-		revs[i] = commit.Revision
+	revs := []string{}
+	var found bool
+	var lastErr error
+	for _, checkout := range d.checkouts() {
+		commits, err := checkout.CommitsBetween(ctx, checkout.SyncTag, commitRef)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		found = true
+		for _, commit := range commits {
+			revs = append(revs, commit.Revision)
+		}
+		// If any of the commits isn't signed properly, the ref will
+		// never be synced; say so, naming the first such commit.
+		if d.GitVerifySignatures {
+			for i := len(commits) - 1; i >= 0; i-- {
+				if !commits[i].Signature.Valid() {
+					return nil, unverifiedCommitError(checkout.Remote().URL, commits[i])
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, lastErr
 	}
 	return revs, nil
 }
@@ -418,9 +607,23 @@ func (d *Daemon) GitRepoConfig(ctx context.Context, regenerate bool) (flux.GitCo
 	if err != nil {
 		return flux.GitConfig{}, err
 	}
+	var remotes []flux.GitRemoteConfig
+	for _, r := range d.Repos {
+		remotes = append(remotes, r.Repo.GitRemoteConfig)
+	}
+	var signingKey *flux.GPGKey
+	if id := d.primary().Checkout.SigningKey; id != "" {
+		key, err := git.ExportKey(id)
+		if err != nil {
+			return flux.GitConfig{}, err
+		}
+		signingKey = &flux.GPGKey{ID: id, Key: key}
+	}
 	return flux.GitConfig{
-		Remote:       d.Repo.GitRemoteConfig,
+		Remote:       d.primary().Repo.GitRemoteConfig,
+		Remotes:      remotes,
 		PublicSSHKey: publicSSHKey,
+		SigningKey:   signingKey,
 		Status:       flux.RepoReady,
 	}, nil
 }
@@ -478,7 +681,7 @@ func containers2containers(cs []cluster.Container) []flux.Container {
 	return res
 }
 
-func containersWithAvailable(service cluster.Controller, images update.ImageMap) (res []flux.Container) {
+func containersWithAvailable(service cluster.Controller, images update.ImageMap, policies policy.ResourceMap) (res []flux.Container) {
 	for _, c := range service.ContainersOrNil() {
 		im, _ := image.ParseRef(c.Image)
 		available := images.Available(im.Name)
@@ -486,11 +689,26 @@ func containersWithAvailable(service cluster.Controller, images update.ImageMap)
 		if available == nil {
 			availableErr = registry.ErrNoImageData.Error()
 		}
+		pattern := getTagPattern(policies, service.ID, c.Name)
+		var candidate *image.Info
+		if latest, ok := images.LatestImage(im.Name, pattern); ok {
+			candidate = &latest
+		}
+		// The images are in order of creation; put them in the order
+		// the candidate was picked by, if that's different
+		ordering := policy.OrderingOf(pattern)
+		if ordering != policy.OrderCreated {
+			sort.SliceStable(available, func(i, j int) bool {
+				return pattern.Newer(&available[i], &available[j])
+			})
+		}
 		res = append(res, flux.Container{
 			Name: c.Name,
 			Current: image.Info{
 				ID: im,
 			},
+			Candidate:      candidate,
+			Ordering:       string(ordering),
 			Available:      available,
 			AvailableError: availableErr,
 		})
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -14,6 +16,7 @@ import (
 	"github.com/go-kit/kit/log"
 
 	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/changerequest"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/cluster/kubernetes"
 	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
@@ -233,7 +236,7 @@ func TestDaemon_Release(t *testing.T) {
 	// Wait and check that the git manifest has been altered
 	w.Eventually(func() bool {
 		// open a file
-		if file, err := os.Open(filepath.Join(d.Checkout.ManifestDir(), "helloworld-deploy.yaml")); err == nil {
+		if file, err := os.Open(filepath.Join(d.Repos[0].Checkout.Dir, "helloworld-deploy.yaml")); err == nil {
 
 			// make sure it gets closed
 			defer file.Close()
@@ -270,12 +273,12 @@ func TestDaemon_PolicyUpdate(t *testing.T) {
 
 	// Wait and check for new annotation
 	w.Eventually(func() bool {
-		d.Checkout.Lock()
-		m, err := d.Manifests.LoadManifests(d.Checkout.ManifestDir())
+		d.Repos[0].Checkout.Lock()
+		m, err := d.Manifests.LoadManifests(d.Repos[0].Checkout.ManifestDirs()...)
 		if err != nil {
 			t.Fatalf("Error: %s", err.Error())
 		}
-		d.Checkout.Unlock()
+		d.Repos[0].Checkout.Unlock()
 		return len(m[svc].Policy()) > 0
 	}, "Waiting for new annotation")
 }
@@ -322,6 +325,668 @@ func TestDaemon_JobStatusWithNoCache(t *testing.T) {
 	w.ForJobSucceeded(d, id)
 }
 
+func TestDaemon_JobStore(t *testing.T) {
+	d, clean, _, _ := mockDaemon(t)
+	defer clean()
+	dir, err := ioutil.TempDir("", "flux-jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &job.FileStore{Path: filepath.Join(dir, "jobs.json"), Size: 10}
+	d.JobStore = store
+	w := newWait(t)
+	ctx := context.Background()
+
+	// A job that fails is remembered after a restart
+	failedID := updateManifest(ctx, t, d, update.Spec{
+		Type: update.Rollback,
+		Spec: update.RollbackSpec{ServiceID: flux.MustParseResourceID(svc), To: "not-a-release"},
+	})
+	w.Eventually(func() bool {
+		stat, _ := d.JobStatus(ctx, failedID)
+		return stat.StatusString == job.StatusFailed
+	}, "Waiting for job to fail")
+	d.JobStatusCache = &job.StatusCache{Size: 100}
+	if stat, err := d.JobStatus(ctx, failedID); err != nil || stat.StatusString != job.StatusFailed {
+		t.Errorf("expected job to have failed, got %#v, %v", stat, err)
+	}
+	if _, err := d.JobStatus(ctx, "not-a-job"); err == nil {
+		t.Error("expected error for unknown job")
+	}
+
+	// A job that was queued when the daemon stopped is resumed
+	queuedID := job.ID("queued-job")
+	if err := store.Queued(queuedID, update.Spec{
+		Type: update.Policy,
+		Spec: policy.Updates{
+			flux.MustParseResourceID(svc): {Add: policy.Set{policy.Locked: "true"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ResumeJobs(); err != nil {
+		t.Fatal(err)
+	}
+	w.ForJobSucceeded(d, queuedID)
+	if unfinished, _ := store.Unfinished(); len(unfinished) != 0 {
+		t.Errorf("expected no unfinished jobs, got %#v", unfinished)
+	}
+}
+
+// When I roll back a release, the images from before it should be
+// restored
+func TestDaemon_Rollback(t *testing.T) {
+	d, clean, _, _ := mockDaemon(t)
+	defer clean()
+	w := newWait(t)
+
+	ctx := context.Background()
+	releaseID := updateImage(ctx, d, t)
+	w.ForJobSucceeded(d, releaseID)
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	id := updateManifest(ctx, t, d, update.Spec{
+		Type: update.Rollback,
+		Spec: update.RollbackSpec{
+			ServiceID: flux.MustParseResourceID(svc),
+			To:        string(releaseID),
+			Lock:      true,
+		},
+	})
+	stat := w.ForJobSucceeded(d, id)
+	result := stat.Result.Result[flux.MustParseResourceID(svc)]
+	if len(result.PerContainer) != 1 || result.PerContainer[0].Target.String() != currentHelloImage {
+		t.Errorf("expected %s to be rolled back to %s, got %#v", svc, currentHelloImage, result)
+	}
+
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	resources, err := d.Manifests.LoadManifests(d.Repos[0].Checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resources[svc]
+	if !res.Policy().Contains(policy.Locked) {
+		t.Errorf("expected %s to be locked, got policies %v", svc, res.Policy())
+	}
+	if def := string(res.Bytes()); !strings.Contains(def, currentHelloImage) || strings.Contains(def, newHelloImage) {
+		t.Errorf("expected manifest to have image %s, got\n%s", currentHelloImage, def)
+	}
+
+	// Nothing to roll back for a job that isn't a release of it
+	policyID := updatePolicy(ctx, t, d)
+	w.ForJobSucceeded(d, policyID)
+	id = updateManifest(ctx, t, d, update.Spec{
+		Type: update.Rollback,
+		Spec: update.RollbackSpec{
+			ServiceID: flux.MustParseResourceID(svc),
+			To:        string(policyID),
+		},
+	})
+	w.Eventually(func() bool {
+		stat, err := d.JobStatus(ctx, id)
+		return err == nil && stat.StatusString == job.StatusFailed
+	}, "Waiting for rollback of a policy change to fail")
+}
+
+// Rolling back again should go further back, rather than undoing the
+// last rollback
+func TestDaemon_RollbackTwice(t *testing.T) {
+	d, clean, _, _ := mockDaemon(t)
+	defer clean()
+	w := newWait(t)
+
+	ctx := context.Background()
+	w.ForJobSucceeded(d, updateImage(ctx, d, t))
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rollback := func() job.ID {
+		return updateManifest(ctx, t, d, update.Spec{
+			Type: update.Rollback,
+			Spec: update.RollbackSpec{
+				ServiceID: flux.MustParseResourceID(svc),
+			},
+		})
+	}
+	stat := w.ForJobSucceeded(d, rollback())
+	result := stat.Result.Result[flux.MustParseResourceID(svc)]
+	if len(result.PerContainer) != 1 || result.PerContainer[0].Target.String() != currentHelloImage {
+		t.Errorf("expected %s to be rolled back to %s, got %#v", svc, currentHelloImage, result)
+	}
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The only release has been rolled back, so there's nothing
+	// left to roll back (rather than the rollback to undo)
+	id := rollback()
+	w.Eventually(func() bool {
+		stat, err := d.JobStatus(ctx, id)
+		return err == nil && stat.StatusString == job.StatusFailed
+	}, "Waiting for a second rollback to fail")
+	if stat, _ := d.JobStatus(ctx, id); !strings.Contains(stat.Err, "no release") {
+		t.Errorf("expected the second rollback to find no release, got %q", stat.Err)
+	}
+	resources, err := d.Manifests.LoadManifests(d.Repos[0].Checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def := string(resources[svc].Bytes()); !strings.Contains(def, currentHelloImage) {
+		t.Errorf("expected manifest to still have image %s, got\n%s", currentHelloImage, def)
+	}
+}
+
+// When a controller's lock expires, it should be treated as unlocked
+// straight away, and the lock removed in a commit of its own
+func TestDaemon_LockExpiry(t *testing.T) {
+	d, clean, _, events := mockDaemon(t)
+	defer clean()
+	w := newWait(t)
+	ctx := context.Background()
+	id := flux.MustParseResourceID(svc)
+	locked := func() bool {
+		services, err := d.ListServices(ctx, ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range services {
+			if s.ID == id {
+				return s.Locked
+			}
+		}
+		t.Fatalf("%s not found", svc)
+		return false
+	}
+
+	policies := func() policy.Set {
+		if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+			t.Fatal(err)
+		}
+		resources, err := d.Manifests.LoadManifests(d.Repos[0].Checkout.ManifestDirs()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resources[svc].Policy()
+	}
+	lock := func(until time.Time) {
+		w.ForJobSucceeded(d, updateManifest(ctx, t, d, update.Spec{
+			Type: update.Policy,
+			Spec: policy.Updates{id: {Add: policy.Set{
+				policy.Locked:      "true",
+				policy.LockedUntil: until.UTC().Format(time.RFC3339),
+			}}},
+		}))
+		if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The loop looks for expired locks whenever it polls for images,
+	// which in the mock daemon is all the time.
+	lock(time.Now().Add(time.Hour))
+	if !locked() {
+		t.Fatalf("expected %s to be locked", svc)
+	}
+	if p := policies(); !p.Contains(policy.Locked) {
+		t.Errorf("expected %s to stay locked until the lock expires, got policies %v", svc, p)
+	}
+
+	lock(time.Now().Add(-time.Minute))
+	if locked() {
+		t.Errorf("expected %s to be unlocked once the lock has expired", svc)
+	}
+	w.Eventually(func() bool {
+		p := policies()
+		return !p.Contains(policy.Locked) && !p.Contains(policy.LockedUntil)
+	}, "Waiting for the expired lock to be removed")
+	if len(eventsOfType(events, event.EventUnlock)) == 0 {
+		t.Errorf("expected an unlock event")
+	}
+}
+
+// When I release a controller with a verify_rollout policy, and it
+// doesn't finish rolling out in time, the release should be rolled
+// back and the controller locked
+func TestDaemon_VerifyRollout(t *testing.T) {
+	d, clean, k8s, events := mockDaemon(t)
+	defer clean()
+	w := newWait(t)
+	defer func(interval time.Duration) { rolloutPollInterval = interval }(rolloutPollInterval)
+	rolloutPollInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	releaseID := updateImage(ctx, d, t)
+	stat := w.ForJobSucceeded(d, releaseID)
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	id := flux.MustParseResourceID(svc)
+	updates := stat.Result.Result[id].PerContainer
+	logger := log.NewNopLogger()
+
+	// Rolled out, so nothing to do
+	k8s.SomeServicesFunc = func([]flux.ResourceID) ([]cluster.Controller, error) {
+		return []cluster.Controller{{
+			ID:         id,
+			Rollout:    cluster.RolloutStatus{Generation: 2, ObservedGeneration: 2, Desired: 2, Updated: 2, Available: 2},
+			Containers: cluster.ContainersOrExcuse{Containers: []cluster.Container{{Name: container, Image: newHelloImage}}},
+		}}, nil
+	}
+	stop, wg := make(chan struct{}), &sync.WaitGroup{}
+	verify := func(timeout time.Duration) job.ID {
+		wg.Add(1)
+		return d.verifyRollout(stop, wg, id, stat.Result.Revision, updates, timeout, logger)
+	}
+	if jobID := verify(time.Second); jobID != "" {
+		t.Errorf("expected rollout to be verified, but it was rolled back in job %s", jobID)
+	}
+
+	// Never rolled out, so it's rolled back
+	k8s.SomeServicesFunc = func([]flux.ResourceID) ([]cluster.Controller, error) {
+		return []cluster.Controller{{
+			ID:         id,
+			Status:     "1 out of 2 updated",
+			Rollout:    cluster.RolloutStatus{Generation: 2, ObservedGeneration: 2, Desired: 2, Updated: 1, Available: 1},
+			Containers: cluster.ContainersOrExcuse{Containers: []cluster.Container{{Name: container, Image: newHelloImage}}},
+		}}, nil
+	}
+	rollbackID := verify(50 * time.Millisecond)
+	if rollbackID == "" {
+		t.Fatal("expected release to be rolled back")
+	}
+	w.ForJobSucceeded(d, rollbackID)
+
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	resources, err := d.Manifests.LoadManifests(d.Repos[0].Checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resources[svc]
+	if msg, _ := res.Policy().Get(policy.LockedMsg); !res.Policy().Contains(policy.Locked) || msg == "" {
+		t.Errorf("expected %s to be locked, with a message, got policies %v", svc, res.Policy())
+	}
+	if def := string(res.Bytes()); !strings.Contains(def, currentHelloImage) {
+		t.Errorf("expected manifest to have image %s, got\n%s", currentHelloImage, def)
+	}
+
+	reverts := eventsOfType(events, event.EventRevert)
+	if len(reverts) != 1 || reverts[0].LogLevel != event.LogLevelError {
+		t.Fatalf("expected an error event for the revert, got %#v", reverts)
+	}
+	if metadata := reverts[0].Metadata.(*event.RevertEventMetadata); metadata.JobID != string(rollbackID) || metadata.Status != "1 out of 2 updated" {
+		t.Errorf("unexpected revert event metadata %#v", metadata)
+	}
+
+	// Shutting down stops the verifying, without rolling back
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(stop)
+	}()
+	if jobID := verify(time.Minute); jobID != "" {
+		t.Errorf("expected verifying to stop on shutdown, but release was rolled back in job %s", jobID)
+	}
+	wg.Wait()
+	if reverts := eventsOfType(events, event.EventRevert); len(reverts) != 1 {
+		t.Errorf("expected no further revert events, got %#v", reverts)
+	}
+}
+
+type mockSuspensionStore struct {
+	sync.Mutex
+	suspension flux.Suspension
+}
+
+func (s *mockSuspensionStore) LoadSuspension() (flux.Suspension, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.suspension, nil
+}
+
+func (s *mockSuspensionStore) SaveSuspension(suspension flux.Suspension) error {
+	s.Lock()
+	defer s.Unlock()
+	s.suspension = suspension
+	return nil
+}
+
+func eventsOfType(events *mockEventWriter, eventType string) []event.Event {
+	all, _ := events.AllEvents(time.Time{}, -1, time.Time{})
+	var result []event.Event
+	for _, e := range all {
+		if e.Type == eventType {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// When I suspend syncing or automation, it should stay suspended
+// (including over a restart) until resumed, or until it expires
+func TestDaemon_SuspendResume(t *testing.T) {
+	d, clean, _, events := mockDaemon(t)
+	defer clean()
+	store := &mockSuspensionStore{}
+	d.Suspensions = store
+	logger := log.NewNopLogger()
+
+	ctx := context.Background()
+	if _, err := d.Suspend(ctx, flux.Suspension{}); err == nil {
+		t.Error("expected error when suspending nothing")
+	}
+	s, err := d.Suspend(ctx, flux.Suspension{Sync: true, Reason: "incident"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = d.Suspend(ctx, flux.Suspension{Automation: true, Reason: "still an incident"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Sync || !s.Automation || s.Reason != "still an incident" {
+		t.Errorf("expected sync and automation to be suspended, got %#v", s)
+	}
+	if len(eventsOfType(events, event.EventSuspend)) != 2 {
+		t.Errorf("expected an event for each suspension")
+	}
+
+	// As though restarted
+	d.LoopVars = &LoopVars{Suspensions: store}
+	if err := d.LoadSuspension(); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.suspended(logger); !s.Sync || !s.Automation {
+		t.Errorf("expected suspension to be loaded, got %#v", s)
+	}
+
+	s, err = d.Resume(ctx, flux.Suspension{Sync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Sync || !s.Automation {
+		t.Errorf("expected only automation to be suspended, got %#v", s)
+	}
+	s, err = d.Resume(ctx, flux.Suspension{Sync: true, Automation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Suspended() || s.Reason != "" {
+		t.Errorf("expected nothing to be suspended, got %#v", s)
+	}
+	if stored, _ := store.LoadSuspension(); stored.Suspended() {
+		t.Errorf("expected stored suspension to be cleared, got %#v", stored)
+	}
+	if len(eventsOfType(events, event.EventResume)) != 2 {
+		t.Errorf("expected an event for each resumption")
+	}
+
+	// A suspension that's expired is lifted when next looked at
+	if _, err = d.Suspend(ctx, flux.Suspension{Sync: true, Expires: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.suspended(logger); s.Suspended() {
+		t.Errorf("expected expired suspension to be lifted, got %#v", s)
+	}
+	resumes := eventsOfType(events, event.EventResume)
+	if len(resumes) != 3 || !resumes[2].Metadata.(*event.ResumeEventMetadata).Expired {
+		t.Errorf("expected an event for the suspension expiring, got %#v", resumes)
+	}
+}
+
+type mockPendingStore struct {
+	sync.Mutex
+	pending []update.PendingRelease
+}
+
+func (s *mockPendingStore) LoadPending() ([]update.PendingRelease, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.pending, nil
+}
+
+func (s *mockPendingStore) SavePending(pending []update.PendingRelease) error {
+	s.Lock()
+	defer s.Unlock()
+	s.pending = pending
+	return nil
+}
+
+func proposeImage(t *testing.T, d *Daemon, ref string) {
+	var changes update.Automated
+	changes.Add(flux.MustParseResourceID(svc), cluster.Container{Name: container, Image: currentHelloImage}, makeImageInfo(ref, time.Now()).ID)
+	if err := d.propose(&changes, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDaemon_Pending(t *testing.T) {
+	d, clean, _, _ := mockDaemon(t)
+	defer clean()
+	store := &mockPendingStore{}
+	d.PendingReleases = store
+	w := newWait(t)
+	ctx := context.Background()
+
+	proposeImage(t, d, newHelloImage)
+	proposeImage(t, d, newHelloImage) // the same again
+	pending, err := d.ListPending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ServiceID.String() != svc {
+		t.Fatalf("expected one release of %s to be pending, got %#v", svc, pending)
+	}
+
+	// As though restarted
+	d.LoopVars = &LoopVars{PendingReleases: store}
+	if err := d.LoadPending(); err != nil {
+		t.Fatal(err)
+	}
+
+	cause := update.Cause{User: "approver"}
+	id, err := d.ApprovePending(ctx, pending[0].ID, cause)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := w.ForJobSucceeded(d, id)
+	if stat.Result.Spec == nil || stat.Result.Spec.Cause != cause {
+		t.Errorf("expected release to be made as %#v, got %#v", cause, stat.Result.Spec)
+	}
+	result := stat.Result.Result[flux.MustParseResourceID(svc)]
+	if len(result.PerContainer) != 1 || result.PerContainer[0].Target.String() != newHelloImage {
+		t.Errorf("expected %s to be released with %s, got %#v", svc, newHelloImage, result)
+	}
+	if pending, _ := d.ListPending(ctx); len(pending) != 0 {
+		t.Errorf("expected approved release to be removed, got %#v", pending)
+	}
+	if _, err := d.ApprovePending(ctx, pending[0].ID, cause); err == nil {
+		t.Error("expected error approving a release twice")
+	}
+
+	proposeImage(t, d, "quay.io/weaveworks/helloworld:3")
+	pending, _ = d.ListPending(ctx)
+	if len(pending) != 1 {
+		t.Fatalf("expected one release to be pending, got %#v", pending)
+	}
+	if err := d.RejectPending(ctx, pending[0].ID, cause); err != nil {
+		t.Fatal(err)
+	}
+	proposeImage(t, d, "quay.io/weaveworks/helloworld:3")
+	if pending, _ := d.ListPending(ctx); len(pending) != 0 {
+		t.Errorf("expected rejected release not to be proposed again, got %#v", pending)
+	}
+	if stored, _ := store.LoadPending(); len(stored) != 1 || !stored[0].Rejected || stored[0].RejectedBy != "approver" {
+		t.Errorf("expected rejection to be stored, got %#v", stored)
+	}
+}
+
+type mockSoakStore struct {
+	sync.Mutex
+	soaking []update.SoakingImage
+}
+
+func (s *mockSoakStore) LoadSoaking() ([]update.SoakingImage, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.soaking, nil
+}
+
+func (s *mockSoakStore) SaveSoaking(soaking []update.SoakingImage) error {
+	s.Lock()
+	defer s.Unlock()
+	s.soaking = soaking
+	return nil
+}
+
+func TestDaemon_Promotion(t *testing.T) {
+	d, clean, k8s, _ := mockDaemon(t)
+	defer clean()
+	d.PromotionSoak = time.Hour
+	store := &mockSoakStore{}
+	d.SoakingImages = store
+	logger := log.NewNopLogger()
+
+	target := flux.MustParseResourceID(svc)
+	source := flux.MustParseResourceID("staging:deployment/helloworld")
+	healthy := cluster.RolloutStatus{Generation: 1, ObservedGeneration: 1, Desired: 1, Updated: 1, Available: 1}
+	rollout := healthy
+	k8s.SomeServicesFunc = func([]flux.ResourceID) ([]cluster.Controller, error) {
+		return []cluster.Controller{
+			{
+				ID:         target,
+				Rollout:    healthy,
+				Containers: cluster.ContainersOrExcuse{Containers: []cluster.Container{{Name: container, Image: currentHelloImage}}},
+			},
+			{
+				ID:         source,
+				Rollout:    rollout,
+				Containers: cluster.ContainersOrExcuse{Containers: []cluster.Container{{Name: container, Image: newHelloImage}}},
+			},
+		}, nil
+	}
+	promoted := policy.ResourceMap{target: policy.Set{policy.PromoteFrom: source.String()}}
+	promotions := func(at time.Time) (*update.Automated, *update.Automated) {
+		changes, proposals := &update.Automated{}, &update.Automated{}
+		d.addPromotions(promoted, changes, proposals, at, logger)
+		return changes, proposals
+	}
+
+	start := time.Now()
+	if changes, _ := promotions(start); len(changes.Changes) != 0 {
+		t.Errorf("expected no promotion before the image has soaked, got %#v", changes)
+	}
+	if stored, _ := store.LoadSoaking(); len(stored) != 1 || stored[0].ServiceID != source || !stored[0].Since.Equal(start) {
+		t.Errorf("expected soaking image to be stored, got %#v", stored)
+	}
+
+	// The soak time carries on over a restart
+	d.soaking = nil
+	if err := d.LoadSoaking(); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ := promotions(start.Add(2 * time.Hour))
+	if len(changes.Changes) != 1 || changes.Changes[0].ImageID.String() != newHelloImage || changes.Changes[0].Source != source {
+		t.Fatalf("expected %s to be promoted from %s, got %#v", newHelloImage, source, changes)
+	}
+	if msg := changes.CommitMessage(); msg != "Promote "+newHelloImage+" from "+source.String()+" to "+svc {
+		t.Errorf("unexpected commit message %q", msg)
+	}
+
+	// Not rolled out, so it starts soaking again once it is
+	rollout.Available = 0
+	if changes, _ := promotions(start.Add(3 * time.Hour)); len(changes.Changes) != 0 {
+		t.Errorf("expected no promotion of an unhealthy image, got %#v", changes)
+	}
+	rollout = healthy
+	if changes, _ := promotions(start.Add(3 * time.Hour)); len(changes.Changes) != 0 {
+		t.Errorf("expected no promotion before the image has soaked again, got %#v", changes)
+	}
+
+	// A soak time of its own, and releases that must be approved
+	promoted[target][policy.PromoteSoak] = "10m"
+	promoted[target][policy.AutomationMode] = policy.AutomationApprove
+	changes, proposals := promotions(start.Add(3*time.Hour + 15*time.Minute))
+	if len(changes.Changes) != 0 || len(proposals.Changes) != 1 {
+		t.Errorf("expected promotion to be proposed, got changes %#v and proposals %#v", changes, proposals)
+	}
+}
+
+type mockRequester struct {
+	sync.Mutex
+	requests []changerequest.Request
+}
+
+func (r *mockRequester) Open(ctx context.Context, req changerequest.Request) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.requests = append(r.requests, req)
+	return fmt.Sprintf("request-%d", len(r.requests)), nil
+}
+
+// When I ask for changes to be merged rather than pushed, the job
+// should await merge until the commit reaches the synced branch
+func TestDaemon_ChangeRequest(t *testing.T) {
+	d, clean, _, _ := mockDaemon(t)
+	defer clean()
+	w := newWait(t)
+	requester := &mockRequester{}
+	d.ChangeRequests = requester
+
+	ctx := context.Background()
+	before, err := d.Repos[0].Checkout.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := updatePolicy(ctx, t, d)
+
+	var stat job.Status
+	w.Eventually(func() bool {
+		stat, err = d.JobStatus(ctx, id)
+		return err == nil && stat.StatusString == job.StatusAwaitingMerge
+	}, "Waiting for job to await merge")
+
+	branch := "flux/policy-" + string(id)
+	if stat.Result.Branch != branch || stat.Result.ChangeRequest != "request-1" {
+		t.Errorf("expected branch %q and change request in job result, got %#v", branch, stat.Result)
+	}
+	requester.Lock()
+	if len(requester.requests) != 1 {
+		t.Fatalf("expected one change request, got %#v", requester.requests)
+	}
+	req := requester.requests[0]
+	requester.Unlock()
+	if req.Branch != branch || req.Base != "master" || req.Revision != stat.Result.Revision {
+		t.Errorf("unexpected change request %#v", req)
+	}
+
+	// Nothing should have been pushed to the synced branch
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	after, err := d.Repos[0].Checkout.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Errorf("expected synced branch to stay at %s, but it's at %s", before, after)
+	}
+
+	// Merge the branch, and the job is done once we've seen it
+	if err := exec.Command("git", "-C", d.Repos[0].Repo.URL, "update-ref", "refs/heads/master", "refs/heads/"+branch).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Repos[0].Checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	w.ForJobSucceeded(d, id)
+}
+
 func makeImageInfo(ref string, t time.Time) image.Info {
 	r, _ := image.ParseRef(ref)
 	return image.Info{ID: r, CreatedAt: t}
@@ -426,7 +1091,7 @@ func mockDaemon(t *testing.T) (*Daemon, func(), *cluster.Mock, *mockEventWriter)
 
 	// Finally, the daemon
 	d := &Daemon{
-		Checkout:       checkout,
+		Repos:          []Repository{{Repo: repo, Checkout: checkout}},
 		Cluster:        k8s,
 		Manifests:      &kubernetes.Manifests{},
 		Registry:       imageRegistry,
@@ -449,6 +1114,57 @@ func mockDaemon(t *testing.T) (*Daemon, func(), *cluster.Mock, *mockEventWriter)
 	}, k8s, events
 }
 
+// An event writer that doesn't return until told to, like a slow
+// upstream
+type blockingEventWriter struct {
+	mockEventWriter
+	unblock chan struct{}
+}
+
+func (w *blockingEventWriter) LogEvent(e event.Event) error {
+	<-w.unblock
+	return w.mockEventWriter.LogEvent(e)
+}
+
+// Logging the event for a suspension shouldn't hold up anything
+// checking whether it's suspended
+func TestDaemon_SuspendSlowUpstream(t *testing.T) {
+	d, clean, _, _ := mockDaemon(t)
+	defer clean()
+	events := &blockingEventWriter{unblock: make(chan struct{})}
+	d.EventWriter = events
+	logger := log.NewNopLogger()
+
+	suspended := make(chan error)
+	go func() {
+		_, err := d.Suspend(context.Background(), flux.Suspension{Sync: true})
+		suspended <- err
+	}()
+	checked := make(chan flux.Suspension)
+	go func() {
+		for {
+			if s := d.suspended(logger); s.Sync {
+				checked <- s
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	select {
+	case <-checked:
+	case <-time.After(timeout):
+		close(events.unblock)
+		t.Fatal("timed out checking suspension while the event was being logged")
+	}
+	close(events.unblock)
+	if err := <-suspended; err != nil {
+		t.Fatal(err)
+	}
+	if len(eventsOfType(&events.mockEventWriter, event.EventSuspend)) != 1 {
+		t.Error("expected an event for the suspension")
+	}
+}
+
 type mockEventWriter struct {
 	events []event.Event
 	sync.Mutex
@@ -2,13 +2,14 @@ package daemon
 
 import (
 	"context"
-	"strings"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
 
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/job"
 	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/update"
 )
@@ -19,6 +20,15 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 	// One day we may use this for operations other than the call at the end
 	ctx := context.Background()
 
+	if d.lastAutoRelease != "" {
+		status, err := d.JobStatus(ctx, d.lastAutoRelease)
+		if err == nil && status.StatusString != job.StatusSucceeded && status.StatusString != job.StatusFailed {
+			logger.Log("msg", "previous automated release not yet merged", "job", d.lastAutoRelease, "branch", status.Result.Branch)
+			return
+		}
+		d.lastAutoRelease = ""
+	}
+
 	candidateServices, err := d.unlockedAutomatedServices()
 	if err != nil {
 		logger.Log("error", errors.Wrap(err, "getting unlocked automated services"))
@@ -28,6 +38,47 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 		logger.Log("msg", "no automated services")
 		return
 	}
+	// Leave those outside their deployment window, or in a freeze,
+	// until a later poll
+	window := &update.WindowFilter{Policies: candidateServices, Default: d.DeploySchedule, Now: time.Now()}
+	for id := range candidateServices {
+		if result := window.Filter(update.ControllerUpdate{ResourceID: id}); result.Error != "" {
+			logger.Log("msg", "deferring automated release", "service", id, "reason", result.Error)
+			delete(candidateServices, id)
+		}
+	}
+	// Those promoting images from another controller get their new
+	// images from there, rather than from the registry
+	promotedServices := candidateServices.OnlyWithPolicy(policy.PromoteFrom)
+	candidateServices = candidateServices.Without(promotedServices)
+
+	changes := &update.Automated{}
+	proposals := &update.Automated{}
+	if len(promotedServices) > 0 {
+		d.addPromotions(promotedServices, changes, proposals, time.Now(), logger)
+	}
+	if len(candidateServices) > 0 {
+		d.addNewImages(candidateServices, changes, proposals, logger)
+	}
+
+	if len(proposals.Changes) > 0 {
+		if err := d.propose(proposals, logger); err != nil {
+			logger.Log("error", errors.Wrap(err, "proposing releases for approval"))
+		}
+	}
+
+	if len(changes.Changes) > 0 {
+		id, err := d.UpdateManifests(ctx, update.Spec{Type: update.Auto, Spec: changes})
+		if err == nil && d.ChangeRequests != nil {
+			d.lastAutoRelease = id
+		}
+	}
+}
+
+// addNewImages adds a change for each container of the controllers
+// given that has a newer image in the registry; to the proposals, if
+// the controller's releases must be approved.
+func (d *Daemon) addNewImages(candidateServices policy.ResourceMap, changes, proposals *update.Automated, logger log.Logger) {
 	// Find images to check
 	services, err := d.Cluster.SomeControllers(candidateServices.ToSlice())
 	if err != nil {
@@ -41,7 +92,6 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 		return
 	}
 
-	changes := &update.Automated{}
 	for _, service := range services {
 		for _, container := range service.ContainersOrNil() {
 			logger := log.With(logger, "service", service.ID, "container", container.Name, "currentimage", container.Image)
@@ -56,33 +106,65 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 			repo := currentImageID.Name
 			logger.Log("repo", repo, "pattern", pattern)
 
-			if latest, ok := imageMap.LatestImage(repo, pattern); ok && latest.ID != currentImageID {
-				newImage := currentImageID.WithNewTag(latest.ID.Tag)
+			latest, ok := imageMap.LatestImage(repo, pattern)
+			if !ok {
+				continue
+			}
+			// If pinning by digest, a tag pushed again is a new image
+			if newImage, changed := update.TargetImage(currentImageID, latest, d.pinDigest(candidateServices[service.ID])); changed {
+				if needsApproval(candidateServices[service.ID]) {
+					proposals.Add(service.ID, container, newImage)
+					logger.Log("msg", "added image to proposals", "newimage", newImage)
+					continue
+				}
 				changes.Add(service.ID, container, newImage)
 				logger.Log("msg", "added image to changes", "newimage", newImage)
 			}
 		}
 	}
+}
 
-	if len(changes.Changes) > 0 {
-		d.UpdateManifests(ctx, update.Spec{Type: update.Auto, Spec: changes})
-	}
+// pinDigest says whether images released to a controller with the
+// policies given are pinned by digest.
+func (d *Daemon) pinDigest(policies policy.Set) bool {
+	return d.PinDigests || policies.Contains(policy.PinDigest)
+}
+
+// needsApproval says whether automated releases of a controller with
+// the policies given must be approved.
+func needsApproval(policies policy.Set) bool {
+	mode, _ := policies.Get(policy.AutomationMode)
+	return mode == policy.AutomationApprove
 }
 
-func getTagPattern(services policy.ResourceMap, service flux.ResourceID, container string) string {
+func getTagPattern(services policy.ResourceMap, service flux.ResourceID, container string) policy.Pattern {
 	policies := services[service]
-	if pattern, ok := policies.Get(policy.TagPrefix(container)); ok {
-		return strings.TrimPrefix(pattern, "glob:")
+	pattern := policy.PatternAll
+	if value, ok := policies.Get(policy.TagPrefix(container)); ok {
+		pattern = policy.NewPattern(value)
 	}
-	return "*"
+	// An ordering that can't be parsed is ignored, in favour of the
+	// pattern's own
+	if value, ok := policies.Get(policy.OrderPrefix(container)); ok {
+		if ordering, err := policy.ParseOrdering(value); err == nil {
+			pattern = policy.Order(pattern, ordering)
+		}
+	}
+	return pattern
 }
 
 func (d *Daemon) unlockedAutomatedServices() (policy.ResourceMap, error) {
-	services, err := d.Manifests.ServicesWithPolicies(d.Checkout.ManifestDir())
+	services, err := d.Manifests.ServicesWithPolicies(manifestDirs(d.checkouts())...)
 	if err != nil {
 		return nil, err
 	}
 	automatedServices := services.OnlyWithPolicy(policy.Automated)
-	lockedServices := services.OnlyWithPolicy(policy.Locked)
+	// Promoting images from another controller is automation too
+	for id, policies := range services.OnlyWithPolicy(policy.PromoteFrom) {
+		automatedServices[id] = policies
+	}
+	// A lock that has expired doesn't count, even if it's yet to be
+	// removed
+	lockedServices := services.OnlyLocked(time.Now())
 	return automatedServices.Without(lockedServices), nil
 }
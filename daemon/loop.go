@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,7 +16,9 @@ import (
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/job"
 	fluxmetrics "github.com/weaveworks/flux/metrics"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/resource"
 	fluxsync "github.com/weaveworks/flux/sync"
 	"github.com/weaveworks/flux/update"
@@ -29,9 +32,72 @@ const (
 type LoopVars struct {
 	GitPollInterval      time.Duration
 	RegistryPollInterval time.Duration
-	syncSoon             chan struct{}
-	pollImagesSoon       chan struct{}
-	initOnce             sync.Once
+
+	// Delete resources that fluxd applied, but which have since
+	// been removed from the repo; or just log them, for a dry run
+	SyncGarbageCollection       bool
+	SyncGarbageCollectionDryRun bool
+	// Only report how the cluster differs from the repo, rather
+	// than applying anything
+	SyncReportOnly bool
+	// Only sync each repo as far as the last commit with a valid
+	// signature from a trusted key
+	GitVerifySignatures bool
+	// Where to keep the suspension of syncing and automation, if
+	// any, so it survives a restart; if nil, it's kept only in
+	// memory
+	Suspensions SuspensionStore
+	// Where to keep automated releases waiting for approval; if nil,
+	// they're kept only in memory
+	PendingReleases PendingStore
+	// Where to keep the images soaking in controllers that images
+	// are promoted from, and since when, so that soak times carry on
+	// over a restart; if nil, they're kept only in memory
+	SoakingImages SoakStore
+	// How long an image must have been running healthily in a
+	// controller before it's promoted to controllers with a
+	// promote_from policy naming it, unless they give their own
+	// promote_soak
+	PromotionSoak time.Duration
+
+	syncSoon       chan struct{}
+	pollImagesSoon chan struct{}
+	initOnce       sync.Once
+
+	lastSyncMu    sync.RWMutex
+	lastSync      flux.SyncOutcome
+	resourceSyncs map[string]flux.ResourceStatus
+	// The diff for each resource in report mode, by ID, as of the
+	// last sync
+	lastDrift map[string]string
+	// The first commit without a valid signature in each repo, by
+	// URL, as of the last sync
+	lastUnverified map[string]string
+	// The last automated release, when it's to be merged rather
+	// than pushed to the synced branch; there's no point making
+	// another before it's merged, since it would have the same
+	// changes (used only from the loop goroutine)
+	lastAutoRelease job.ID
+	// The last job to remove expired locks; likewise, there's no
+	// point in another until it's done (used only from the loop
+	// goroutine)
+	lastExpiredUnlock job.ID
+	// The image each container of a controller that images are
+	// promoted from has been running healthily, and since when (used
+	// only from the loop goroutine)
+	soaking map[string]update.SoakingImage
+	// The channel that says the loop is shutting down, and the group
+	// it waits on before exiting, so that anything the loop starts in
+	// the background (e.g., verifying rollouts) can stop cleanly; set
+	// when the loop starts
+	loopStop chan struct{}
+	loopWg   *sync.WaitGroup
+
+	suspensionMu sync.Mutex
+	suspension   flux.Suspension
+
+	pendingMu sync.Mutex
+	pending   []update.PendingRelease
 }
 
 func (loop *LoopVars) ensureInit() {
@@ -43,6 +109,7 @@ func (loop *LoopVars) ensureInit() {
 
 func (d *Daemon) GitPollLoop(stop chan struct{}, wg *sync.WaitGroup, logger log.Logger) {
 	defer wg.Done()
+	d.loopStop, d.loopWg = stop, wg
 	// We want to pull the repo and sync at least every
 	// `GitPollInterval`. Being told to sync, or completing a job, may
 	// intervene (in which case, reschedule the next pull-and-sync)
@@ -54,15 +121,27 @@ func (d *Daemon) GitPollLoop(stop chan struct{}, wg *sync.WaitGroup, logger log.
 		}()
 		ctx, cancel := context.WithTimeout(context.Background(), gitOpTimeout)
 		defer cancel()
-		if err := d.Checkout.Pull(ctx); err != nil {
-			logger.Log("operation", "pull", "err", err)
-			return
+		for _, r := range d.Repos {
+			if err := r.Checkout.Pull(ctx); err != nil {
+				logger.Log("operation", "pull", "repo", r.Repo.URL, "err", err)
+				return
+			}
 		}
 		if err := k(logger); err != nil {
 			logger.Log("operation", "after-pull", "err", err)
 		}
 	}
 
+	// While syncing is suspended, the repos are still pulled, so
+	// that e.g., job statuses are up to date, but nothing is applied
+	syncUnlessSuspended := func(logger log.Logger) error {
+		if d.suspended(logger).Sync {
+			logger.Log("msg", "sync suspended")
+			return nil
+		}
+		return d.doSync(logger)
+	}
+
 	imagePollTimer := time.NewTimer(d.RegistryPollInterval)
 
 	// Ask for a sync, and to poll images, straight away
@@ -74,13 +153,19 @@ func (d *Daemon) GitPollLoop(stop chan struct{}, wg *sync.WaitGroup, logger log.
 			logger.Log("stopping", "true")
 			return
 		case <-d.pollImagesSoon:
-			d.pollForNewImages(logger)
+			// Locks expire whether or not automation is suspended
+			d.unlockExpired(time.Now(), logger)
+			if d.suspended(logger).Automation {
+				logger.Log("msg", "automation suspended")
+			} else {
+				d.pollForNewImages(logger)
+			}
 			imagePollTimer.Stop()
 			imagePollTimer = time.NewTimer(d.RegistryPollInterval)
 		case <-imagePollTimer.C:
 			d.AskForImagePoll()
 		case <-d.syncSoon:
-			pullThen(d.doSync)
+			pullThen(syncUnlessSuspended)
 		case <-gitPollTimer.C:
 			// Time to poll for new commits (unless we're already
 			// about to do that)
@@ -102,7 +187,7 @@ func (d *Daemon) GitPollLoop(stop chan struct{}, wg *sync.WaitGroup, logger log.
 			jobDuration.With(
 				fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
 			).Observe(time.Since(start).Seconds())
-			pullThen(d.doSync)
+			pullThen(syncUnlessSuspended)
 		}
 	}
 }
@@ -139,39 +224,101 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 	// undeadlined context in general.
 	ctx := context.Background()
 
-	// checkout a working clone so we can mess around with tags later
-	var working *git.Checkout
+	// checkout working clones so we can mess around with tags later
+	var working workingClones
 	{
 		var err error
 		ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
 		defer cancel()
-		working, err = d.Checkout.WorkingClone(ctx)
+		working, err = d.workingClones(ctx)
 		if err != nil {
 			return err
 		}
 		defer working.Clean()
 	}
 
+	// If verifying signatures, wind each repo back to the last
+	// commit that can be trusted.
+	if d.GitVerifySignatures {
+		for _, w := range working {
+			if err := d.checkoutLatestValid(ctx, w, started, logger); err != nil {
+				return err
+			}
+		}
+	}
+
 	// TODO logging, metrics?
-	// Get a map of all resources defined in the repo
-	allResources, err := d.Manifests.LoadManifests(working.ManifestDir())
+	// Get a map of all resources defined in the repos
+	allResources, repoResources, err := loadResources(d.Manifests, working)
 	if err != nil {
 		return errors.Wrap(err, "loading resources from repo")
 	}
 
-	// TODO supply deletes argument from somewhere (command-line?)
-	if err := fluxsync.Sync(d.Manifests, allResources, d.Cluster, false, logger); err != nil {
-		logger.Log("err", err)
-		// TODO(michael): we should distinguish between "fully mostly
-		// succeeded" and "failed utterly", since we want to abandon
-		// this and not move the tag (and send a SyncFail event
-		// upstream?), if the latter. For now, it's presumed that any
-		// error returned is at worst a minor, partial failure (e.g.,
-		// a small number of resources failed to sync, for unimportant
-		// reasons)
+	// The revision of each resource is that of the repo it's from;
+	// the revision of the sync as a whole is that of the primary repo
+	revisions := map[string]string{}
+	var revision string
+	for i, w := range working {
+		ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+		rev, err := w.HeadRevision(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			revision = rev
+		}
+		for id := range repoResources[i] {
+			revisions[id] = rev
+		}
 	}
 
-	// update notes and emit events for applied commits
+	syncConfig := d.syncConfig(repoResources)
+	outcome := flux.SyncOutcome{
+		Revision: revision,
+		Started:  started,
+		Result:   flux.SyncSucceeded,
+	}
+	result, err := fluxsync.Sync(d.Manifests, allResources, d.Cluster, syncConfig, logger)
+	d.reportDrift(result, revision, started, logger)
+	if err != nil {
+		failure, ok := err.(*fluxsync.Failure)
+		if ok {
+			outcome.Errors = make(map[string]string, len(failure.Errors))
+			for id, err := range failure.Errors {
+				outcome.Errors[id] = err.Error()
+			}
+		}
+		// If most or all of the resources failed, or it failed
+		// for some other reason, leave the sync tags where they are
+		// so the same commits are tried again next time.
+		if !ok || failure.Total() {
+			outcome.Result = flux.SyncFailed
+			if !ok {
+				outcome.Error = err.Error()
+			}
+			d.recordSync(outcome, allResources, revisions, result.Reported)
+			d.logSyncFailed(outcome, started, logger)
+			return errors.Wrap(err, "syncing cluster")
+		}
+		outcome.Result = flux.SyncPartial
+		logger.Log("warning", "some resources failed to sync", "resources", strings.Join(failedIDs(outcome.Errors), ", "))
+	}
+	d.recordSync(outcome, allResources, revisions, result.Reported)
+
+	for i, w := range working {
+		if err := d.afterSync(ctx, d.Repos[i].Checkout, w, repoResources[i], outcome, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterSync updates notes and emits events for the commits applied
+// from a repo, and moves its sync tag.
+func (d *Daemon) afterSync(ctx context.Context, checkout, working *git.Checkout, allResources map[string]resource.Resource, outcome flux.SyncOutcome, logger log.Logger) error {
+	started := outcome.Started
+	var err error
 
 	var initialSync bool
 	var commits []git.Commit
@@ -230,6 +377,7 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 	// autoreleases, that we're already posting as events, so upstream
 	// can skip the sync event if it wants to.
 	includes := make(map[string]bool)
+	var releases []syncedRelease
 	if len(commits) > 0 {
 		var noteEvents []event.Event
 
@@ -285,6 +433,7 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 					},
 				})
 				includes[event.EventRelease] = true
+				releases = append(releases, syncedRelease{commits[i].Revision, n.Result})
 			case update.Auto:
 				spec := n.Spec.Spec.(update.Automated)
 				noteEvents = append(noteEvents, event.Event{
@@ -303,6 +452,7 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 					},
 				})
 				includes[event.EventAutoRelease] = true
+				releases = append(releases, syncedRelease{commits[i].Revision, n.Result})
 			case update.Policy:
 				// Use this to mean any change to policy
 				includes[event.EventUpdatePolicy] = true
@@ -318,16 +468,21 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 			cs[i].Revision = c.Revision
 			cs[i].Message = c.Message
 		}
+		logLevel := event.LogLevelInfo
+		if outcome.Result == flux.SyncPartial {
+			logLevel = event.LogLevelWarn
+		}
 		if err = d.LogEvent(event.Event{
 			ServiceIDs: serviceIDs.ToSlice(),
 			Type:       event.EventSync,
 			StartedAt:  started,
 			EndedAt:    started,
-			LogLevel:   event.LogLevelInfo,
+			LogLevel:   logLevel,
 			Metadata: &event.SyncEventMetadata{
 				Commits:     cs,
 				InitialSync: initialSync,
 				Includes:    includes,
+				Errors:      outcome.Errors,
 			},
 		}); err != nil {
 			logger.Log("err", err)
@@ -353,17 +508,180 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 	// Pull the tag if it has changed
 	{
 		ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
-		if err := d.pullIfTagMoved(ctx, working, logger); err != nil {
+		if err := pullIfTagMoved(ctx, checkout, working, logger); err != nil {
 			logger.Log("err", errors.Wrap(err, "updating tag"))
 		}
 		cancel()
 	}
 
+	d.verifyRollouts(releases, allResources, logger)
 	return nil
 }
 
-func (d *Daemon) pullIfTagMoved(ctx context.Context, working *git.Checkout, logger log.Logger) error {
-	oldTagRev, err := d.Checkout.TagRevision(ctx, d.Checkout.SyncTag)
+// logSyncFailed sends an event saying the sync failed, naming the
+// resources responsible if there are any.
+func (d *Daemon) logSyncFailed(outcome flux.SyncOutcome, started time.Time, logger log.Logger) {
+	var ids []flux.ResourceID
+	for _, id := range failedIDs(outcome.Errors) {
+		if rid, err := flux.ParseResourceID(id); err == nil {
+			ids = append(ids, rid)
+		}
+	}
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: ids,
+		Type:       event.EventSyncFailed,
+		StartedAt:  started,
+		EndedAt:    time.Now().UTC(),
+		LogLevel:   event.LogLevelError,
+		Metadata: &event.SyncFailedEventMetadata{
+			Revision: outcome.Revision,
+			Errors:   outcome.Errors,
+			Error:    outcome.Error,
+		},
+	}); err != nil {
+		logger.Log("err", err)
+	}
+}
+
+// reportDrift updates the drift metric for each resource in report
+// mode, and sends an event if the drift has changed since the last
+// sync.
+func (d *Daemon) reportDrift(result fluxsync.Result, revision string, started time.Time, logger log.Logger) {
+	drift := make(map[string]string, len(result.Drift))
+	for _, diff := range result.Drift {
+		drift[diff.ID.String()] = diff.Diff
+	}
+	for _, id := range result.Reported {
+		drifted := 0.0
+		if _, ok := drift[id]; ok {
+			drifted = 1
+		}
+		syncDrift.With("resource", id).Set(drifted)
+	}
+
+	d.lastSyncMu.Lock()
+	last := d.lastDrift
+	d.lastDrift = drift
+	d.lastSyncMu.Unlock()
+
+	// Resources that have stopped being reported on (e.g., because
+	// they are no longer in the repo) no longer count as drifted
+	for id := range last {
+		if _, ok := drift[id]; !ok {
+			syncDrift.With("resource", id).Set(0)
+		}
+	}
+	if driftEqual(last, drift) {
+		return
+	}
+
+	var ids []flux.ResourceID
+	for _, diff := range result.Drift {
+		ids = append(ids, diff.ID)
+	}
+	logLevel := event.LogLevelWarn
+	if len(drift) == 0 {
+		logLevel = event.LogLevelInfo
+	}
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: ids,
+		Type:       event.EventDrift,
+		StartedAt:  started,
+		EndedAt:    time.Now().UTC(),
+		LogLevel:   logLevel,
+		Metadata: &event.DriftEventMetadata{
+			Revision: revision,
+			Diffs:    result.Drift,
+		},
+	}); err != nil {
+		logger.Log("err", err)
+	}
+}
+
+func driftEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, diff := range a {
+		if other, ok := b[id]; !ok || other != diff {
+			return false
+		}
+	}
+	return true
+}
+
+func failedIDs(errs map[string]string) []string {
+	var ids []string
+	for id := range errs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// recordSync keeps the outcome of a sync, and updates the sync
+// status of each resource that was in the repo, using the revision
+// given for the resource. Resources no longer in the repo are
+// forgotten; resources that were only reported on keep the status
+// they had.
+func (d *LoopVars) recordSync(outcome flux.SyncOutcome, resources map[string]resource.Resource, revisions map[string]string, reported []string) {
+	d.lastSyncMu.Lock()
+	defer d.lastSyncMu.Unlock()
+	d.lastSync = outcome
+	// If the sync failed outright, we don't know anything about
+	// individual resources.
+	if outcome.Error != "" {
+		return
+	}
+
+	notApplied := make(map[string]bool, len(reported))
+	for _, id := range reported {
+		notApplied[id] = true
+	}
+	statuses := make(map[string]flux.ResourceStatus, len(resources))
+	for id, res := range resources {
+		if res.Policy().Contains(policy.Ignore) {
+			continue
+		}
+		if notApplied[id] {
+			if status, ok := d.resourceSyncs[id]; ok {
+				statuses[id] = status
+			}
+			continue
+		}
+		status := d.resourceSyncs[id]
+		status.ID = res.ResourceID()
+		if err, ok := outcome.Errors[id]; ok {
+			status.Sync.Error = err
+		} else {
+			status.Sync = flux.ResourceSyncStatus{
+				Revision: revisions[id],
+				Applied:  outcome.Started,
+			}
+		}
+		statuses[id] = status
+	}
+	d.resourceSyncs = statuses
+}
+
+// ResourceSyncs gives the sync status of each resource from the
+// repo, by ID.
+func (d *LoopVars) ResourceSyncs() map[string]flux.ResourceStatus {
+	d.lastSyncMu.RLock()
+	defer d.lastSyncMu.RUnlock()
+	return d.resourceSyncs
+}
+
+// LastSync gives the outcome of the most recent sync; if there
+// hasn't been one, the Result will be empty.
+func (d *LoopVars) LastSync() flux.SyncOutcome {
+	d.lastSyncMu.RLock()
+	defer d.lastSyncMu.RUnlock()
+	return d.lastSync
+}
+
+func pullIfTagMoved(ctx context.Context, checkout, working *git.Checkout, logger log.Logger) error {
+	oldTagRev, err := checkout.TagRevision(ctx, checkout.SyncTag)
 	if err != nil && !strings.Contains(err.Error(), "unknown revision or path not in the working tree") {
 		return err
 	}
@@ -373,8 +691,8 @@ func (d *Daemon) pullIfTagMoved(ctx context.Context, working *git.Checkout, logg
 	}
 
 	if oldTagRev != newTagRev {
-		logger.Log("tag", d.Checkout.SyncTag, "old", oldTagRev, "new", newTagRev)
-		if err := d.Checkout.Pull(ctx); err != nil {
+		logger.Log("tag", checkout.SyncTag, "old", oldTagRev, "new", newTagRev)
+		if err := checkout.Pull(ctx); err != nil {
 			return err
 		}
 	}
@@ -1,8 +1,11 @@
 package daemon
 
 import (
+	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -17,12 +20,15 @@ import (
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/cluster/kubernetes"
 	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
 	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/git"
 	"github.com/weaveworks/flux/git/gittest"
 	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
 	registryMock "github.com/weaveworks/flux/registry/mock"
 	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/update"
 )
 
 const (
@@ -57,6 +63,7 @@ func daemon(t *testing.T) (*Daemon, func()) {
 	k8s.ExportFunc = func() ([]byte, error) { return nil, nil }
 	k8s.FindDefinedServicesFunc = (&kubernetes.Manifests{}).FindDefinedServices
 	k8s.ServicesWithPoliciesFunc = (&kubernetes.Manifests{}).ServicesWithPolicies
+	k8s.UpdatePoliciesFunc = (&kubernetes.Manifests{}).UpdatePolicies
 
 	events = &mockEventWriter{}
 
@@ -67,7 +74,7 @@ func daemon(t *testing.T) (*Daemon, func()) {
 		Cluster:        k8s,
 		Manifests:      k8s,
 		Registry:       &registryMock.Registry{},
-		Checkout:       working,
+		Repos:          []Repository{{Repo: repo, Checkout: working}},
 		Jobs:           jobs,
 		JobStatusCache: &job.StatusCache{Size: 100},
 		EventWriter:    events,
@@ -129,9 +136,9 @@ func TestPullAndSync_InitialSync(t *testing.T) {
 		}
 	}
 	// It creates the tag at HEAD
-	if err := d.Checkout.Pull(context.Background()); err != nil {
+	if err := d.Repos[0].Checkout.Pull(context.Background()); err != nil {
 		t.Errorf("pulling sync tag: %v", err)
-	} else if revs, err := d.Checkout.CommitsBefore(context.Background(), gitSyncTag); err != nil {
+	} else if revs, err := d.Repos[0].Checkout.CommitsBefore(context.Background(), gitSyncTag); err != nil {
 		t.Errorf("finding revisions before sync tag: %v", err)
 	} else if len(revs) <= 0 {
 		t.Errorf("Found no revisions before the sync tag")
@@ -142,7 +149,7 @@ func TestDoSync_NoNewCommits(t *testing.T) {
 	// Tag exists
 	d, cleanup := daemon(t)
 	defer cleanup()
-	if err := d.Checkout.MoveTagAndPush(context.Background(), "HEAD", "Sync pointer"); err != nil {
+	if err := d.Repos[0].Checkout.MoveTagAndPush(context.Background(), "HEAD", "Sync pointer"); err != nil {
 		t.Fatal(err)
 	}
 
@@ -179,13 +186,13 @@ func TestDoSync_NoNewCommits(t *testing.T) {
 	}
 
 	// It doesn't move the tag
-	oldRevs, err := d.Checkout.CommitsBefore(context.Background(), gitSyncTag)
+	oldRevs, err := d.Repos[0].Checkout.CommitsBefore(context.Background(), gitSyncTag)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := d.Checkout.Pull(context.Background()); err != nil {
+	if err := d.Repos[0].Checkout.Pull(context.Background()); err != nil {
 		t.Errorf("pulling sync tag: %v", err)
-	} else if revs, err := d.Checkout.CommitsBefore(context.Background(), gitSyncTag); err != nil {
+	} else if revs, err := d.Repos[0].Checkout.CommitsBefore(context.Background(), gitSyncTag); err != nil {
 		t.Errorf("finding revisions before sync tag: %v", err)
 	} else if !reflect.DeepEqual(revs, oldRevs) {
 		t.Errorf("Should have kept the sync tag at HEAD")
@@ -197,15 +204,15 @@ func TestDoSync_WithNewCommit(t *testing.T) {
 	d, cleanup := daemon(t)
 	defer cleanup()
 	// Set the sync tag to head
-	if err := d.Checkout.MoveTagAndPush(context.Background(), "HEAD", "Sync pointer"); err != nil {
+	if err := d.Repos[0].Checkout.MoveTagAndPush(context.Background(), "HEAD", "Sync pointer"); err != nil {
 		t.Fatal(err)
 	}
-	oldRevision, err := d.Checkout.HeadRevision(context.Background())
+	oldRevision, err := d.Repos[0].Checkout.HeadRevision(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 	// Push some new changes
-	if err := cluster.UpdateManifest(k8s, d.Checkout.ManifestDir(), flux.MustParseResourceID("default:deployment/helloworld"), func(def []byte) ([]byte, error) {
+	if err := cluster.UpdateManifest(k8s, d.Repos[0].Checkout.ManifestDirs(), flux.MustParseResourceID("default:deployment/helloworld"), func(def []byte) ([]byte, error) {
 		// A simple modification so we have changes to push
 		return []byte(strings.Replace(string(def), "replicas: 5", "replicas: 4", -1)), nil
 	}); err != nil {
@@ -213,10 +220,10 @@ func TestDoSync_WithNewCommit(t *testing.T) {
 	}
 
 	commitAction := &git.CommitAction{Author: "", Message: "test commit"}
-	if err := d.Checkout.CommitAndPush(context.Background(), commitAction, nil); err != nil {
+	if err := d.Repos[0].Checkout.CommitAndPush(context.Background(), commitAction, nil); err != nil {
 		t.Fatal(err)
 	}
-	newRevision, err := d.Checkout.HeadRevision(context.Background())
+	newRevision, err := d.Repos[0].Checkout.HeadRevision(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -262,9 +269,9 @@ func TestDoSync_WithNewCommit(t *testing.T) {
 		}
 	}
 	// It moves the tag
-	if err := d.Checkout.Pull(context.Background()); err != nil {
+	if err := d.Repos[0].Checkout.Pull(context.Background()); err != nil {
 		t.Errorf("pulling sync tag: %v", err)
-	} else if revs, err := d.Checkout.CommitsBetween(context.Background(), oldRevision, gitSyncTag); err != nil {
+	} else if revs, err := d.Repos[0].Checkout.CommitsBetween(context.Background(), oldRevision, gitSyncTag); err != nil {
 		t.Errorf("finding revisions before sync tag: %v", err)
 	} else if len(revs) <= 0 {
 		t.Errorf("Should have moved sync tag forward")
@@ -272,3 +279,532 @@ func TestDoSync_WithNewCommit(t *testing.T) {
 		t.Errorf("Should have moved sync tag to HEAD (%s), but was moved to: %s")
 	}
 }
+
+func TestDoSync_GarbageCollection(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+	d.SyncGarbageCollection = true
+
+	// Sync once, to find out what gets applied (and marked)
+	var applied [][]byte
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		for _, action := range def.Actions {
+			if action.Apply != nil {
+				applied = append(applied, action.Apply)
+			}
+		}
+		return nil
+	}
+	d.doSync(log.NewLogfmtLogger(ioutil.Discard))
+	if len(applied) != 3 {
+		t.Fatalf("expected all three resources to be applied, got %d", len(applied))
+	}
+
+	// The cluster now has everything we applied, and something
+	// else that we didn't apply
+	unmanaged := []byte(`---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: unmanaged
+  namespace: default
+`)
+	k8s.ExportFunc = func() ([]byte, error) {
+		return bytes.Join(append(applied, unmanaged), []byte("\n---\n")), nil
+	}
+
+	// Remove one of the resources from the repo
+	if err := os.Remove(filepath.Join(d.Repos[0].Checkout.Dir, "helloworld-deploy.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	commitAction := &git.CommitAction{Author: "", Message: "remove helloworld"}
+	if err := d.Repos[0].Checkout.CommitAndPush(context.Background(), commitAction, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var syncDef *cluster.SyncDef
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		syncDef = &def
+		return nil
+	}
+	d.doSync(log.NewLogfmtLogger(ioutil.Discard))
+
+	if syncDef == nil {
+		t.Fatal("Sync was not called")
+	}
+	var deleted []string
+	for _, action := range syncDef.Actions {
+		if action.Delete != nil {
+			deleted = append(deleted, action.ResourceID)
+		}
+	}
+	expected := []string{"default:deployment/helloworld"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("expected only %v to be deleted, got %v", expected, deleted)
+	}
+
+	// In a dry run, nothing is deleted
+	d.SyncGarbageCollection = false
+	d.SyncGarbageCollectionDryRun = true
+	syncDef = nil
+	d.doSync(log.NewLogfmtLogger(ioutil.Discard))
+	if syncDef == nil {
+		t.Fatal("Sync was not called")
+	}
+	for _, action := range syncDef.Actions {
+		if action.Delete != nil {
+			t.Errorf("expected nothing to be deleted in a dry run, but %s was", action.ResourceID)
+		}
+	}
+}
+
+func TestDoSync_TotalFailure(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		errs := cluster.SyncError{}
+		for _, action := range def.Actions {
+			errs[action.ResourceID] = errors.New("apply failed")
+		}
+		return errs
+	}
+
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err == nil {
+		t.Error("expected an error from doSync when all resources fail")
+	}
+
+	// No sync event, just a sync failed event
+	es, err := events.AllEvents(time.Time{}, -1, time.Time{})
+	if err != nil {
+		t.Error(err)
+	} else if len(es) != 1 {
+		t.Errorf("Unexpected events: %#v", es)
+	} else if es[0].Type != event.EventSyncFailed {
+		t.Errorf("Unexpected event type: %#v", es[0])
+	} else if meta := es[0].Metadata.(*event.SyncFailedEventMetadata); len(meta.Errors) != 3 {
+		t.Errorf("Expected an error for each resource, got %#v", meta.Errors)
+	}
+
+	// The tag is not created
+	if err := d.Repos[0].Checkout.Pull(context.Background()); err != nil {
+		t.Errorf("pulling: %v", err)
+	} else if _, err := d.Repos[0].Checkout.CommitsBefore(context.Background(), gitSyncTag); err == nil {
+		t.Errorf("expected the sync tag not to exist")
+	}
+
+	if outcome := d.LastSync(); outcome.Result != flux.SyncFailed {
+		t.Errorf("expected last sync to be recorded as failed, got %#v", outcome)
+	}
+}
+
+func TestDoSync_PartialFailure(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+
+	failing := "default:deployment/helloworld"
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		return cluster.SyncError{failing: errors.New("apply failed")}
+	}
+
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err != nil {
+		t.Fatal(err)
+	}
+
+	es, err := events.AllEvents(time.Time{}, -1, time.Time{})
+	if err != nil {
+		t.Error(err)
+	} else if len(es) != 1 {
+		t.Errorf("Unexpected events: %#v", es)
+	} else if es[0].Type != event.EventSync || es[0].LogLevel != event.LogLevelWarn {
+		t.Errorf("Expected a sync event with level warn, got: %#v", es[0])
+	} else if meta := es[0].Metadata.(*event.SyncEventMetadata); meta.Errors[failing] == "" {
+		t.Errorf("Expected the failing resource in the sync event, got %#v", meta.Errors)
+	}
+
+	// The tag is moved regardless
+	if err := d.Repos[0].Checkout.Pull(context.Background()); err != nil {
+		t.Errorf("pulling sync tag: %v", err)
+	} else if revs, err := d.Repos[0].Checkout.CommitsBefore(context.Background(), gitSyncTag); err != nil {
+		t.Errorf("finding revisions before sync tag: %v", err)
+	} else if len(revs) <= 0 {
+		t.Errorf("Found no revisions before the sync tag")
+	}
+
+	outcome := d.LastSync()
+	if outcome.Result != flux.SyncPartial {
+		t.Errorf("expected last sync to be recorded as partial, got %#v", outcome)
+	}
+	if _, ok := outcome.Errors[failing]; !ok {
+		t.Errorf("expected %s in the errors, got %#v", failing, outcome.Errors)
+	}
+
+	// Each resource has its own status recorded
+	resources, err := d.ListResources(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 3 {
+		t.Fatalf("expected a status for each of 3 resources, got %#v", resources)
+	}
+	for _, r := range resources {
+		switch {
+		case r.ID.String() == failing && r.Sync.Error == "":
+			t.Errorf("expected an error recorded for %s", r.ID)
+		case r.ID.String() != failing && (r.Sync.Error != "" || r.Sync.Revision != outcome.Revision):
+			t.Errorf("expected %s to be recorded as applied at %s, got %#v", r.ID, outcome.Revision, r.Sync)
+		}
+	}
+}
+
+func TestDoSync_ReportOnly(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+	d.SyncReportOnly = true
+
+	var applied int
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		applied += len(def.Actions)
+		return nil
+	}
+
+	driftEvents := func() []event.Event {
+		es, err := events.AllEvents(time.Time{}, -1, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var drift []event.Event
+		for _, e := range es {
+			if e.Type == event.EventDrift {
+				drift = append(drift, e)
+			}
+		}
+		return drift
+	}
+
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Errorf("expected nothing to be applied, got %d actions", applied)
+	}
+	es := driftEvents()
+	if len(es) != 1 {
+		t.Fatalf("expected one drift event, got %#v", es)
+	}
+	if meta := es[0].Metadata.(*event.DriftEventMetadata); len(meta.Diffs) != 3 {
+		t.Errorf("expected a diff for each of 3 resources, got %#v", meta.Diffs)
+	}
+
+	// Nothing has changed, so there's no need to report it again
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err != nil {
+		t.Fatal(err)
+	}
+	if es := driftEvents(); len(es) != 1 {
+		t.Errorf("expected no further drift events, got %#v", es)
+	}
+
+	// Resources that were only reported on haven't been applied
+	resources, err := d.ListResources(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 0 {
+		t.Errorf("expected no resources recorded as synced, got %#v", resources)
+	}
+}
+
+const otherDeploymentID = "default:deployment/other"
+
+const otherDeployment = `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: other
+spec:
+  template:
+    spec:
+      containers:
+      - name: other
+        image: quay.io/weaveworks/other:1
+`
+
+// addRepo adds another repo to the daemon. If replaceFiles is true,
+// the test files in the repo are replaced with a single, different
+// deployment; otherwise, the repo defines the same resources as the
+// first.
+func addRepo(t *testing.T, d *Daemon, replaceFiles bool) func() {
+	repo, repoCleanup := gittest.Repo(t)
+	config := git.Config{
+		SyncTag:   gitSyncTag,
+		NotesRef:  gitNotesRef,
+		UserName:  gitUser,
+		UserEmail: gitEmail,
+	}
+	checkout, err := repo.Clone(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaceFiles {
+		// Overwrite one file and remove the others, so that there
+		// are only changes to files git already knows about
+		var overwritten bool
+		for file := range testfiles.Files {
+			path := filepath.Join(checkout.Dir, file)
+			if !overwritten {
+				err = ioutil.WriteFile(path, []byte(otherDeployment), 0666)
+				overwritten = true
+			} else {
+				err = os.Remove(path)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := checkout.CommitAndPush(context.Background(), &git.CommitAction{Message: "other things"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Repos = append(d.Repos, Repository{Repo: repo, Checkout: checkout})
+	return func() {
+		checkout.Clean()
+		repoCleanup()
+	}
+}
+
+func TestDoSync_MultipleRepos(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+	defer addRepo(t, d, true)()
+
+	var synced []string
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		for _, action := range def.Actions {
+			synced = append(synced, action.ResourceID)
+		}
+		return nil
+	}
+
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err != nil {
+		t.Fatal(err)
+	}
+	if len(synced) != 4 {
+		t.Errorf("expected resources from both repos to be synced, got %v", synced)
+	}
+
+	// Each repo has its own sync tag moved
+	for _, r := range d.Repos {
+		if err := r.Checkout.Pull(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Checkout.CommitsBefore(context.Background(), gitSyncTag); err != nil {
+			t.Errorf("expected sync tag in %s: %v", r.Repo.URL, err)
+		}
+	}
+
+	// Resources record the revision of the repo they came from
+	otherRevision, err := d.Repos[1].Checkout.HeadRevision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := d.ListResources(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range resources {
+		if r.ID.String() == otherDeploymentID && r.Sync.Revision != otherRevision {
+			t.Errorf("expected %s to be synced at %s, got %#v", r.ID, otherRevision, r.Sync)
+		}
+	}
+
+	// Both repos are consulted for the sync status
+	revs, err := d.SyncStatus(context.Background(), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 0 {
+		t.Errorf("expected no commits waiting to be synced, got %v", revs)
+	}
+}
+
+// Each repo is its own sync set, so garbage collection doesn't delete
+// the resources applied from a repo no longer synced from.
+func TestDoSync_GarbageCollectionMultipleRepos(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+	defer addRepo(t, d, true)()
+	d.SyncGarbageCollection = true
+
+	applied := map[string][]byte{}
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		for _, action := range def.Actions {
+			if action.Apply != nil {
+				applied[action.ResourceID] = action.Apply
+			}
+			if action.Delete != nil {
+				delete(applied, action.ResourceID)
+			}
+		}
+		return nil
+	}
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 4 {
+		t.Fatalf("expected resources from both repos to be applied, got %d", len(applied))
+	}
+	k8s.ExportFunc = func() ([]byte, error) {
+		var defs [][]byte
+		for _, def := range applied {
+			defs = append(defs, def)
+		}
+		return bytes.Join(defs, []byte("\n---\n")), nil
+	}
+
+	// Without the second repo, its resources are no longer in the
+	// repo, but they aren't in the first repo's sync set either
+	d.Repos = d.Repos[:1]
+	if err := d.doSync(log.NewLogfmtLogger(ioutil.Discard)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := applied[otherDeploymentID]; !ok {
+		t.Errorf("expected %s to be left in the cluster", otherDeploymentID)
+	}
+}
+
+func TestDoSync_SameResourceInTwoRepos(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+	defer addRepo(t, d, false)()
+
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		t.Error("expected nothing to be synced")
+		return nil
+	}
+	err := d.doSync(log.NewLogfmtLogger(ioutil.Discard))
+	if err == nil || !strings.Contains(err.Error(), "more than one repo") {
+		t.Errorf("expected an error about resources defined in more than one repo, got %v", err)
+	}
+}
+
+func TestUpdatePolicy_MultipleRepos(t *testing.T) {
+	d, cleanup := daemon(t)
+	defer cleanup()
+	defer addRepo(t, d, true)()
+
+	before := make([]string, len(d.Repos))
+	for i, r := range d.Repos {
+		rev, err := r.Checkout.HeadRevision(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[i] = rev
+	}
+
+	id := flux.MustParseResourceID(otherDeploymentID)
+	spec := update.Spec{
+		Type: update.Policy,
+		Spec: policy.Updates{
+			id: {Add: policy.Set{policy.Locked: "true"}},
+		},
+	}
+	metadata, err := d.executeJob("job", d.updatePolicy(spec, spec.Spec.(policy.Updates)), log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Result[id].Status != update.ReleaseStatusSuccess {
+		t.Fatalf("expected policy update to succeed, got %#v", metadata.Result)
+	}
+
+	// Only the repo defining the controller gets a commit
+	for i, r := range d.Repos {
+		if err := r.Checkout.Pull(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		rev, err := r.Checkout.HeadRevision(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case i == 1 && rev != metadata.Revision:
+			t.Errorf("expected the commit to be in the repo defining %s", id)
+		case i == 0 && rev != before[0]:
+			t.Errorf("expected no commit to the repo not defining %s", id)
+		}
+	}
+}
+
+func TestDoSync_VerifySignatures(t *testing.T) {
+	key, cleanupKey := gittest.GPGKey(t)
+	defer cleanupKey()
+	d, cleanup := daemon(t)
+	defer cleanup()
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		return nil
+	}
+	ctx := context.Background()
+	checkout := d.Repos[0].Checkout
+	logger := log.NewLogfmtLogger(ioutil.Discard)
+
+	// The commit in the test repo isn't signed, so sync it before
+	// asking for verification, to get a starting point
+	if err := d.doSync(logger); err != nil {
+		t.Fatal(err)
+	}
+	d.GitVerifySignatures = true
+
+	signed := gittest.PushCommit(t, checkout.Remote(), key, "Signed")
+	unsigned := gittest.PushCommit(t, checkout.Remote(), "", "Unsigned")
+	gittest.PushCommit(t, checkout.Remote(), key, "Signed after unsigned")
+	if err := checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.doSync(logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := checkout.TagRevision(ctx, gitSyncTag); err != nil || rev != signed {
+		t.Errorf("expected sync tag to be at the last signed commit %s, got %s (err %v)", signed, rev, err)
+	}
+
+	unverifiedEvents := func() []event.Event {
+		es, err := events.AllEvents(time.Time{}, -1, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var unverified []event.Event
+		for _, e := range es {
+			if e.Type == event.EventUnverified {
+				unverified = append(unverified, e)
+			}
+		}
+		return unverified
+	}
+	es := unverifiedEvents()
+	if len(es) != 1 {
+		t.Fatalf("expected one unverified commit event, got %#v", es)
+	}
+	if meta := es[0].Metadata.(*event.UnverifiedEventMetadata); meta.Revision != unsigned || meta.SyncedRevision != signed {
+		t.Errorf("expected event about unsigned commit %s, got %#v", unsigned, meta)
+	}
+
+	// The same commit doesn't get reported again
+	if err := d.doSync(logger); err != nil {
+		t.Fatal(err)
+	}
+	if es := unverifiedEvents(); len(es) != 1 {
+		t.Errorf("expected no further unverified commit events, got %#v", es)
+	}
+
+	// Asking after the head revision says why it won't be synced
+	_, err := d.SyncStatus(ctx, "HEAD")
+	if err == nil || !strings.Contains(err.Error(), unsigned) {
+		t.Errorf("expected error naming the unsigned commit from SyncStatus, got %v", err)
+	}
+	revs, err := d.SyncStatus(ctx, signed)
+	if err != nil || len(revs) != 0 {
+		t.Errorf("expected signed commit to be synced, got %v (err %v)", revs, err)
+	}
+}
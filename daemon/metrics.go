@@ -38,6 +38,15 @@ var (
 		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 15, 20, 30, 45, 60, 120},
 	}, []string{})
 
+	// 1 for each resource in report mode that differs from the repo,
+	// 0 for those that don't
+	syncDrift = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "sync_drift",
+		Help:      "Whether a resource that is reported on rather than synced differs from the repo.",
+	}, []string{"resource"})
+
 	queueLength = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 		Namespace: "flux",
 		Subsystem: "daemon",
@@ -21,19 +21,22 @@ type NotReadyDaemon struct {
 	gitRemote flux.GitRemoteConfig
 	gitStatus flux.GitRepoStatus
 	reason    error
+	// any repos beyond the first
+	extraRemotes []flux.GitRemoteConfig
 }
 
 // NotReadyDaemon is a state of the daemon that has not proceeded past
 // getting the git repo set up. Since this typically needs some
 // actions on the part of the user, this state can last indefinitely;
 // so, it has its own code.
-func NewNotReadyDaemon(version string, cluster cluster.Cluster, gitRemote flux.GitRemoteConfig) (nrd *NotReadyDaemon) {
+func NewNotReadyDaemon(version string, cluster cluster.Cluster, gitRemote flux.GitRemoteConfig, extraRemotes ...flux.GitRemoteConfig) (nrd *NotReadyDaemon) {
 	return &NotReadyDaemon{
-		version:   version,
-		cluster:   cluster,
-		gitRemote: gitRemote,
-		gitStatus: flux.RepoNoConfig,
-		reason:    errors.New("git repo is not configured"),
+		version:      version,
+		cluster:      cluster,
+		gitRemote:    gitRemote,
+		extraRemotes: extraRemotes,
+		gitStatus:    flux.RepoNoConfig,
+		reason:       errors.New("git repo is not configured"),
 	}
 }
 
@@ -72,6 +75,10 @@ func (nrd *NotReadyDaemon) ListImages(context.Context, update.ResourceSpec) ([]f
 	return nil, nrd.Reason()
 }
 
+func (nrd *NotReadyDaemon) Diff(context.Context, update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	return nil, nrd.Reason()
+}
+
 func (nrd *NotReadyDaemon) UpdateManifests(context.Context, update.Spec) (job.ID, error) {
 	var id job.ID
 	return id, nrd.Reason()
@@ -98,7 +105,36 @@ func (nrd *NotReadyDaemon) GitRepoConfig(ctx context.Context, regenerate bool) (
 	defer nrd.RUnlock()
 	return flux.GitConfig{
 		Remote:       nrd.gitRemote,
+		Remotes:      append([]flux.GitRemoteConfig{nrd.gitRemote}, nrd.extraRemotes...),
 		PublicSSHKey: publicSSHKey,
 		Status:       nrd.gitStatus,
 	}, nil
 }
+
+func (nrd *NotReadyDaemon) SyncOutcome(context.Context) (flux.SyncOutcome, error) {
+	return flux.SyncOutcome{}, nrd.Reason()
+}
+
+func (nrd *NotReadyDaemon) ListResources(context.Context, string) ([]flux.ResourceStatus, error) {
+	return nil, nrd.Reason()
+}
+
+func (nrd *NotReadyDaemon) Suspend(context.Context, flux.Suspension) (flux.Suspension, error) {
+	return flux.Suspension{}, nrd.Reason()
+}
+
+func (nrd *NotReadyDaemon) Resume(context.Context, flux.Suspension) (flux.Suspension, error) {
+	return flux.Suspension{}, nrd.Reason()
+}
+
+func (nrd *NotReadyDaemon) ListPending(context.Context) ([]update.PendingRelease, error) {
+	return nil, nrd.Reason()
+}
+
+func (nrd *NotReadyDaemon) ApprovePending(context.Context, string, update.Cause) (job.ID, error) {
+	return "", nrd.Reason()
+}
+
+func (nrd *NotReadyDaemon) RejectPending(context.Context, string, update.Cause) error {
+	return nrd.Reason()
+}
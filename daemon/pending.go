@@ -0,0 +1,165 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/guid"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/update"
+)
+
+// PendingStore keeps the automated releases waiting for approval
+// somewhere they will survive a restart.
+type PendingStore interface {
+	LoadPending() ([]update.PendingRelease, error)
+	SavePending([]update.PendingRelease) error
+}
+
+// LoadPending picks up the releases that were waiting for approval
+// when the daemon was last restarted.
+func (d *Daemon) LoadPending() error {
+	if d.PendingReleases == nil {
+		return nil
+	}
+	pending, err := d.PendingReleases.LoadPending()
+	if err != nil {
+		return err
+	}
+	d.pendingMu.Lock()
+	d.pending = pending
+	d.pendingMu.Unlock()
+	return nil
+}
+
+// propose records the automated changes given as releases waiting
+// for approval, one for each controller. If the same changes are
+// already waiting (or were rejected), they're left as they are;
+// otherwise, they replace whatever was waiting for the controller.
+func (d *Daemon) propose(changes *update.Automated, logger log.Logger) error {
+	byService := map[string]*update.PendingRelease{}
+	var order []string
+	for _, change := range changes.Changes {
+		key := change.ServiceID.String()
+		p, ok := byService[key]
+		if !ok {
+			p = &update.PendingRelease{ServiceID: change.ServiceID}
+			byService[key] = p
+			order = append(order, key)
+		}
+		p.Spec.Changes = append(p.Spec.Changes, change)
+	}
+
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+
+	pending := append([]update.PendingRelease{}, d.pending...)
+	var proposed int
+	for _, key := range order {
+		p := byService[key]
+		existing := -1
+		for i := range pending {
+			if pending[i].ServiceID == p.ServiceID {
+				existing = i
+				break
+			}
+		}
+		if existing >= 0 && pending[existing].SameChanges(p.Spec.Changes) {
+			continue
+		}
+		p.ID = guid.New()
+		p.ProposedAt = time.Now().UTC()
+		if existing >= 0 {
+			pending[existing] = *p
+		} else {
+			pending = append(pending, *p)
+		}
+		proposed++
+		logger.Log("msg", "proposed automated release", "service", p.ServiceID, "pending", p.ID)
+	}
+	if proposed == 0 {
+		return nil
+	}
+	return d.savePending(pending)
+}
+
+// ListPending gives the releases waiting for approval, oldest first.
+func (d *Daemon) ListPending(ctx context.Context) ([]update.PendingRelease, error) {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	var result []update.PendingRelease
+	for _, p := range d.pending {
+		if !p.Rejected {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ProposedAt.Before(result[j].ProposedAt)
+	})
+	return result, nil
+}
+
+// ApprovePending makes the release waiting for approval with the ID
+// given, as the user in the cause.
+func (d *Daemon) ApprovePending(ctx context.Context, id string, cause update.Cause) (job.ID, error) {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	i, err := d.findPending(id)
+	if err != nil {
+		return "", err
+	}
+	spec := d.pending[i].Spec
+	jobID, err := d.UpdateManifests(ctx, update.Spec{Type: update.Auto, Cause: cause, Spec: &spec})
+	if err != nil {
+		return jobID, err
+	}
+	pending := append(append([]update.PendingRelease{}, d.pending[:i]...), d.pending[i+1:]...)
+	return jobID, d.savePending(pending)
+}
+
+// RejectPending rejects the release waiting for approval with the ID
+// given, so it is neither made nor proposed again.
+func (d *Daemon) RejectPending(ctx context.Context, id string, cause update.Cause) error {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	i, err := d.findPending(id)
+	if err != nil {
+		return err
+	}
+	pending := append([]update.PendingRelease{}, d.pending...)
+	pending[i].Rejected = true
+	pending[i].RejectedBy = cause.User
+	return d.savePending(pending)
+}
+
+// findPending gives the index of the release waiting for approval
+// with the ID given. It must be called with the pending lock held.
+func (d *Daemon) findPending(id string) (int, error) {
+	for i, p := range d.pending {
+		if p.ID == id && !p.Rejected {
+			return i, nil
+		}
+	}
+	return -1, &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("no pending release %s", id),
+		Help: fmt.Sprintf("There is no release %s waiting for approval; it may have been approved or rejected already, or replaced by a newer release. Use `fluxctl pending list` to see the releases waiting for approval.", id),
+	}
+}
+
+// savePending persists and records the pending releases given. It
+// must be called with the pending lock held.
+func (d *Daemon) savePending(pending []update.PendingRelease) error {
+	if d.PendingReleases != nil {
+		if err := d.PendingReleases.SavePending(pending); err != nil {
+			return err
+		}
+	}
+	d.pending = pending
+	return nil
+}
@@ -0,0 +1,182 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// SoakStore keeps the images soaking in controllers that images are
+// promoted from somewhere they will survive a restart, so that a
+// restart doesn't start the soak time again.
+type SoakStore interface {
+	LoadSoaking() ([]update.SoakingImage, error)
+	SaveSoaking([]update.SoakingImage) error
+}
+
+// LoadSoaking picks up the images that were soaking when the daemon
+// was last restarted.
+func (d *Daemon) LoadSoaking() error {
+	if d.SoakingImages == nil {
+		return nil
+	}
+	soaking, err := d.SoakingImages.LoadSoaking()
+	if err != nil {
+		return err
+	}
+	d.soaking = map[string]update.SoakingImage{}
+	for _, s := range soaking {
+		d.soaking[soakingKey(s.ServiceID, s.Container)] = s
+	}
+	return nil
+}
+
+// addPromotions adds a change for each container of the controllers
+// given for which the controller it promotes from has been running
+// another image healthily for long enough; to the proposals, if the
+// controller's releases must be approved.
+func (d *Daemon) addPromotions(promotedServices policy.ResourceMap, changes, proposals *update.Automated, now time.Time, logger log.Logger) {
+	sources := map[flux.ResourceID]flux.ResourceID{}
+	ids := promotedServices.ToSlice()
+	for id, policies := range promotedServices {
+		value, _ := policies.Get(policy.PromoteFrom)
+		source, err := flux.ParseResourceID(value)
+		if err != nil {
+			logger.Log("service", id, "error", errors.Wrapf(err, "invalid %s policy", policy.PromoteFrom))
+			continue
+		}
+		sources[id] = source
+		if _, ok := promotedServices[source]; !ok {
+			ids = append(ids, source)
+		}
+	}
+
+	controllers, err := d.Cluster.SomeControllers(ids)
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "checking services for promotions"))
+		return
+	}
+	byID := map[flux.ResourceID]cluster.Controller{}
+	for _, c := range controllers {
+		byID[c.ID] = c
+	}
+	var observed bool
+	for _, source := range sources {
+		if c, ok := byID[source]; ok && d.observeSoaking(c, now) {
+			observed = true
+		}
+	}
+	if observed {
+		if err := d.saveSoaking(); err != nil {
+			logger.Log("error", errors.Wrap(err, "saving soaking images"))
+		}
+	}
+
+	for id, source := range sources {
+		target, ok := byID[id]
+		if !ok {
+			continue
+		}
+		soak := d.PromotionSoak
+		if value, ok := promotedServices[id].Get(policy.PromoteSoak); ok {
+			if soak, err = time.ParseDuration(value); err != nil || soak < 0 {
+				logger.Log("service", id, "error", fmt.Errorf("invalid %s policy %q; expected a duration, e.g., 2h", policy.PromoteSoak, value))
+				continue
+			}
+		}
+
+		for _, container := range target.ContainersOrNil() {
+			logger := log.With(logger, "service", id, "container", container.Name, "currentimage", container.Image, "source", source)
+
+			currentImageID, err := image.ParseRef(container.Image)
+			if err != nil {
+				logger.Log("error", err)
+				continue
+			}
+			soaked, ok := d.soaking[soakingKey(source, container.Name)]
+			if !ok || now.Sub(soaked.Since) < soak {
+				continue
+			}
+			// The image can only be pinned if it's pinned in the
+			// source controller, since that's where the digest comes
+			// from
+			newImage, changed := update.TargetImage(currentImageID, image.Info{ID: soaked.Image, Digest: soaked.Image.Digest}, d.pinDigest(promotedServices[id]))
+			if !changed {
+				continue
+			}
+			if soaked.Image.Name.CanonicalName() != currentImageID.Name.CanonicalName() {
+				logger.Log("msg", "not promoting image from a different repository", "sourceimage", soaked.Image)
+				continue
+			}
+
+			if needsApproval(promotedServices[id]) {
+				proposals.AddPromotion(id, container, newImage, source)
+				logger.Log("msg", "added promotion to proposals", "newimage", newImage)
+				continue
+			}
+			changes.AddPromotion(id, container, newImage, source)
+			logger.Log("msg", "added promotion to changes", "newimage", newImage)
+		}
+	}
+}
+
+// observeSoaking records the image each container of the controller
+// is running, if the controller has finished rolling it out; if it
+// hasn't, the image isn't healthy, and starts soaking again once it
+// is. It reports whether anything changed.
+func (d *Daemon) observeSoaking(c cluster.Controller, now time.Time) bool {
+	if d.soaking == nil {
+		d.soaking = map[string]update.SoakingImage{}
+	}
+	var changed bool
+	healthy := c.Rollout.Complete()
+	for _, container := range c.ContainersOrNil() {
+		key := soakingKey(c.ID, container.Name)
+		soaked, ok := d.soaking[key]
+		ref, err := image.ParseRef(container.Image)
+		if err != nil || !healthy {
+			if ok {
+				delete(d.soaking, key)
+				changed = true
+			}
+			continue
+		}
+		if ok && soaked.Image.String() == ref.String() {
+			continue
+		}
+		d.soaking[key] = update.SoakingImage{ServiceID: c.ID, Container: container.Name, Image: ref, Since: now}
+		changed = true
+	}
+	return changed
+}
+
+// saveSoaking persists the images soaking, if there's somewhere to
+// keep them.
+func (d *Daemon) saveSoaking() error {
+	if d.SoakingImages == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(d.soaking))
+	for key := range d.soaking {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	soaking := make([]update.SoakingImage, 0, len(keys))
+	for _, key := range keys {
+		soaking = append(soaking, d.soaking[key])
+	}
+	return d.SoakingImages.SaveSoaking(soaking)
+}
+
+func soakingKey(id flux.ResourceID, container string) string {
+	return id.String() + " " + container
+}
@@ -77,3 +77,35 @@ func (pr *Ref) SyncStatus(ctx context.Context, ref string) ([]string, error) {
 func (pr *Ref) GitRepoConfig(ctx context.Context, regenerate bool) (flux.GitConfig, error) {
 	return pr.Platform().GitRepoConfig(ctx, regenerate)
 }
+
+func (pr *Ref) Diff(ctx context.Context, spec update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	return pr.Platform().Diff(ctx, spec)
+}
+
+func (pr *Ref) SyncOutcome(ctx context.Context) (flux.SyncOutcome, error) {
+	return pr.Platform().SyncOutcome(ctx)
+}
+
+func (pr *Ref) ListResources(ctx context.Context, namespace string) ([]flux.ResourceStatus, error) {
+	return pr.Platform().ListResources(ctx, namespace)
+}
+
+func (pr *Ref) Suspend(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	return pr.Platform().Suspend(ctx, s)
+}
+
+func (pr *Ref) Resume(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	return pr.Platform().Resume(ctx, s)
+}
+
+func (pr *Ref) ListPending(ctx context.Context) ([]update.PendingRelease, error) {
+	return pr.Platform().ListPending(ctx)
+}
+
+func (pr *Ref) ApprovePending(ctx context.Context, id string, cause update.Cause) (job.ID, error) {
+	return pr.Platform().ApprovePending(ctx, id, cause)
+}
+
+func (pr *Ref) RejectPending(ctx context.Context, id string, cause update.Cause) error {
+	return pr.Platform().RejectPending(ctx, id, cause)
+}
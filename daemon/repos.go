@@ -0,0 +1,197 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/resource"
+	fluxsync "github.com/weaveworks/flux/sync"
+)
+
+// Repository is one of the git repos the daemon syncs from: the
+// remote, and the clone the daemon reads from.
+type Repository struct {
+	Repo     git.Repo
+	Checkout *git.Checkout
+}
+
+// primary is the first of the repos. Its git config supplies e.g.,
+// whether to set the author of commits.
+func (d *Daemon) primary() Repository {
+	return d.Repos[0]
+}
+
+func (d *Daemon) checkouts() []*git.Checkout {
+	checkouts := make([]*git.Checkout, len(d.Repos))
+	for i, r := range d.Repos {
+		checkouts[i] = r.Checkout
+	}
+	return checkouts
+}
+
+// readLockRepos takes the read lock on each of the checkouts, and
+// returns a func to release them all.
+func (d *Daemon) readLockRepos() func() {
+	for _, r := range d.Repos {
+		r.Checkout.RLock()
+	}
+	return func() {
+		for _, r := range d.Repos {
+			r.Checkout.RUnlock()
+		}
+	}
+}
+
+// workingClones makes a working clone of each of the repos, in the
+// same order as the repos. If there's a problem with any of them, the
+// clones made so far are cleaned up.
+func (d *Daemon) workingClones(ctx context.Context) (workingClones, error) {
+	var working workingClones
+	for _, r := range d.Repos {
+		clone, err := r.Checkout.WorkingClone(ctx)
+		if err != nil {
+			working.Clean()
+			return nil, err
+		}
+		working = append(working, clone)
+	}
+	return working, nil
+}
+
+// workingClones is a working clone of each of the daemon's repos. A
+// job can change files in any of them, according to which repo
+// defines each controller; only the clones with changes are
+// committed and pushed.
+type workingClones []*git.Checkout
+
+func (w workingClones) Clean() {
+	for _, c := range w {
+		c.Clean()
+	}
+}
+
+func (w workingClones) ManifestDirs() []string {
+	return manifestDirs(w)
+}
+
+// CommitAndPush commits and pushes the changes made in any of the
+// clones, and returns the revision of the first commit made. If there
+// are no changes in any clone, it returns git.ErrNoChanges.
+func (w workingClones) CommitAndPush(ctx context.Context, commitAction *git.CommitAction, note *git.Note) (string, error) {
+	revisions, err := w.commitAndPushBranch(ctx, "", commitAction, note)
+	for _, rev := range revisions {
+		if rev != "" {
+			return rev, err
+		}
+	}
+	return "", err
+}
+
+// commitAndPushBranch commits the changes made in any of the clones
+// and pushes them to the branch given (or the usual branch, if
+// that's empty). It returns the revision committed in each clone, or
+// an empty string for those without changes. If there are no changes
+// in any clone, it returns git.ErrNoChanges.
+func (w workingClones) commitAndPushBranch(ctx context.Context, branch string, commitAction *git.CommitAction, note *git.Note) ([]string, error) {
+	revisions := make([]string, len(w))
+	var committed bool
+	for i, c := range w {
+		err := c.CommitAndPushBranch(ctx, branch, commitAction, note)
+		if err == git.ErrNoChanges {
+			continue
+		}
+		if err != nil {
+			return revisions, err
+		}
+		if revisions[i], err = c.HeadRevision(ctx); err != nil {
+			return revisions, err
+		}
+		committed = true
+	}
+	if !committed {
+		return revisions, git.ErrNoChanges
+	}
+	return revisions, nil
+}
+
+func manifestDirs(checkouts []*git.Checkout) []string {
+	var dirs []string
+	for _, c := range checkouts {
+		dirs = append(dirs, c.ManifestDirs()...)
+	}
+	return dirs
+}
+
+// loadResources loads the resources defined in each of the checkouts
+// and merges them, also returning the resources from each checkout
+// separately. It's an error for more than one repo to define the same
+// resource, since there's no sensible way to decide which should
+// win.
+func loadResources(m cluster.Manifests, checkouts []*git.Checkout) (map[string]resource.Resource, []map[string]resource.Resource, error) {
+	all := map[string]resource.Resource{}
+	byRepo := make([]map[string]resource.Resource, len(checkouts))
+	definedIn := map[string]*git.Checkout{}
+	for i, c := range checkouts {
+		resources, err := m.LoadManifests(c.ManifestDirs()...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for id, res := range resources {
+			if other, ok := definedIn[id]; ok {
+				return nil, nil, fmt.Errorf("resource %s is defined in more than one repo (%s and %s)", id, other.Remote().URL, c.Remote().URL)
+			}
+			definedIn[id] = c
+			all[id] = res
+		}
+		byRepo[i] = resources
+	}
+	return all, byRepo, nil
+}
+
+// syncSetName gives the name of the sync set for the resources from
+// the repo at the index given, with which they are marked when
+// applied. The primary repo's is its sync tag, as it always has been;
+// each other repo's also has its URL and branch, so that it's a set
+// of its own even if it has the same sync tag.
+func (d *Daemon) syncSetName(i int) string {
+	checkout := d.Repos[i].Checkout
+	if i == 0 {
+		return checkout.SyncTag
+	}
+	remote := checkout.Remote()
+	return fmt.Sprintf("%s:%s#%s", checkout.SyncTag, remote.URL, remote.Branch)
+}
+
+// syncConfig gives the config for syncing the resources loaded from
+// each repo (as given by loadResources). Each repo is a sync set of
+// its own, so garbage collection only deletes what's applied from a
+// repo this fluxd still syncs from.
+func (d *Daemon) syncConfig(byRepo []map[string]resource.Resource) fluxsync.Config {
+	conf := fluxsync.Config{
+		SyncSetName: d.syncSetName(0),
+		GC:          d.SyncGarbageCollection,
+		GCDryRun:    d.SyncGarbageCollectionDryRun,
+		ReportOnly:  d.SyncReportOnly,
+	}
+	for i := 1; i < len(byRepo); i++ {
+		if conf.SyncSets == nil {
+			conf.SyncSets = map[string][]string{}
+		}
+		ids := []string{}
+		for id := range byRepo[i] {
+			ids = append(ids, id)
+		}
+		conf.SyncSets[d.syncSetName(i)] = ids
+	}
+	return conf
+}
+
+// LoadResources loads and merges the resources defined in all the
+// repos, failing if more than one repo defines the same resource.
+func (d *Daemon) LoadResources() (map[string]resource.Resource, error) {
+	defer d.readLockRepos()()
+	all, _, err := loadResources(d.Manifests, d.checkouts())
+	return all, err
+}
@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/cluster"
+	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+func (d *Daemon) rollback(spec update.Spec, s update.RollbackSpec) DaemonJobFunc {
+	return func(ctx context.Context, jobID job.ID, working workingClones, logger log.Logger) (*event.CommitEventMetadata, error) {
+		revision, images, err := findRollback(ctx, working, s)
+		if err != nil {
+			return nil, err
+		}
+		if s.Lock {
+			lock := policy.Set{policy.Locked: "true"}
+			if spec.Cause.User != "" {
+				lock = lock.
+					Set(policy.LockedUser, spec.Cause.User).
+					Set(policy.LockedMsg, spec.Cause.Message)
+			}
+			// This lock stays until someone has looked into it
+			noExpiry := policy.Set{}.Add(policy.LockedUntil)
+			if _, err := cluster.UpdatePolicies(d.Manifests, working.ManifestDirs(), s.ServiceID, policy.Update{Add: lock, Remove: noExpiry}); err != nil {
+				return nil, err
+			}
+		}
+		changes := &update.RollbackRelease{
+			Spec:     s,
+			Revision: revision,
+			Images:   images,
+		}
+		return d.release(spec, changes)(ctx, jobID, working, logger)
+	}
+}
+
+// findRollback looks through the notes on commits in each repo for
+// the release to roll back, and returns its revision, and the images
+// the controller's containers had before it. Without `To`, releases
+// already undone by an earlier rollback are passed over, so that
+// rolling back again goes further back rather than undoing the
+// rollback.
+func findRollback(ctx context.Context, working workingClones, s update.RollbackSpec) (string, map[string]image.Ref, error) {
+	for _, checkout := range working {
+		// Rollbacks seen so far (newest first) that haven't yet been
+		// matched with the releases they undid: those without `To`
+		// each undid the next release back; one with `To` undid
+		// everything back to and including that release.
+		var undone int
+		var undoneTo string
+
+		notes, err := checkout.NoteRevList(ctx)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "enumerating commit notes")
+		}
+		commits, err := checkout.CommitsBefore(ctx, "HEAD")
+		if err != nil {
+			return "", nil, errors.Wrap(err, "looking for release to roll back")
+		}
+
+		for _, commit := range commits {
+			if _, ok := notes[commit.Revision]; !ok {
+				continue
+			}
+			note, err := checkout.GetNote(ctx, commit.Revision)
+			if err != nil {
+				return "", nil, errors.Wrap(err, "loading notes from repo")
+			}
+			if note == nil {
+				continue
+			}
+			result := note.Result[s.ServiceID]
+			if s.To != "" {
+				if !isRelease(s.To, commit.Revision, note) {
+					continue
+				}
+				if !changedImages(result) {
+					return "", nil, noRollbackError(s, fmt.Errorf("release %s did not change the images of %s", s.To, s.ServiceID))
+				}
+			} else {
+				if !changedImages(result) {
+					continue
+				}
+				if note.Spec.Type == update.Rollback {
+					// Anything a rollback within the range being
+					// skipped undid is skipped along with it
+					if rb, ok := note.Spec.Spec.(update.RollbackSpec); ok && undoneTo == "" {
+						if rb.To != "" {
+							undoneTo = rb.To
+						} else {
+							undone++
+						}
+					}
+					continue
+				}
+				if undoneTo != "" {
+					if isRelease(undoneTo, commit.Revision, note) {
+						undoneTo = ""
+					}
+					continue
+				}
+				if undone > 0 {
+					undone--
+					continue
+				}
+			}
+
+			images := map[string]image.Ref{}
+			for _, c := range result.PerContainer {
+				images[c.Container] = c.Current
+			}
+			return commit.Revision, images, nil
+		}
+	}
+	if s.To != "" {
+		return "", nil, noRollbackError(s, fmt.Errorf("release %s not found", s.To))
+	}
+	return "", nil, noRollbackError(s, fmt.Errorf("no release of %s found", s.ServiceID))
+}
+
+// isRelease says whether the job ID or (a prefix of) the revision
+// given identifies the commit with the note.
+func isRelease(to, revision string, note *git.Note) bool {
+	return string(note.JobID) == to || strings.HasPrefix(revision, to)
+}
+
+func changedImages(result update.ControllerResult) bool {
+	return result.Status == update.ReleaseStatusSuccess && len(result.PerContainer) > 0
+}
+
+func noRollbackError(s update.RollbackSpec, err error) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  err,
+		Help: `Release to roll back not found
+
+Rolling back relies on the notes fluxd attaches to the commits it makes
+for releases, which record the images each container had before. Check
+that
+
+    ` + s.ServiceID.String() + `
+
+has been released by fluxd (and not, e.g., by editing the manifest by
+hand), and that the job ID or revision given (if any) is that of a
+release of it.
+`,
+	}
+}
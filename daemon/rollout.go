@@ -0,0 +1,190 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/update"
+)
+
+// How often to look at a rollout that's being verified
+var rolloutPollInterval = 10 * time.Second
+
+// syncedRelease is a release found in the notes on the commits just
+// synced.
+type syncedRelease struct {
+	revision string
+	result   update.Result
+}
+
+// verifyRollouts watches the rollout of each controller changed by
+// the releases given that has a verify_rollout policy, and rolls the
+// release back if the rollout doesn't complete in time. The watching
+// stops when the loop shuts down, and isn't taken up again when
+// fluxd restarts.
+func (d *Daemon) verifyRollouts(releases []syncedRelease, resources map[string]resource.Resource, logger log.Logger) {
+	stop, wg := d.loopStop, d.loopWg
+	if wg == nil {
+		// Not run from the loop (e.g., in tests), so there's nothing
+		// to shut down with
+		wg = &sync.WaitGroup{}
+	}
+	for _, r := range releases {
+		for id, result := range r.result {
+			if !changedImages(result) {
+				continue
+			}
+			res, ok := resources[id.String()]
+			if !ok {
+				continue
+			}
+			value, ok := res.Policy().Get(policy.VerifyRollout)
+			if !ok {
+				continue
+			}
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				logger.Log("resource", id, "err", fmt.Errorf("invalid %s policy %q; expected a duration, e.g., 5m", policy.VerifyRollout, value))
+				continue
+			}
+			wg.Add(1)
+			go d.verifyRollout(stop, wg, id, r.revision, result.PerContainer, timeout, log.With(logger, "resource", id, "revision", r.revision))
+		}
+	}
+}
+
+type rolloutState int
+
+const (
+	rolloutInProgress rolloutState = iota
+	rolloutComplete
+	rolloutSuperseded
+)
+
+// verifyRollout waits for the controller to finish rolling out the
+// container updates given, and if it doesn't within the timeout,
+// rolls back the release. It returns the ID of the job doing the
+// rolling back, if there is one. If told to stop before then, it
+// gives up without rolling back.
+func (d *Daemon) verifyRollout(stop chan struct{}, wg *sync.WaitGroup, id flux.ResourceID, revision string, updates []update.ContainerUpdate, timeout time.Duration, logger log.Logger) job.ID {
+	defer wg.Done()
+	deadline := time.Now().Add(timeout)
+	var status string
+	for {
+		controllers, err := d.Cluster.SomeControllers([]flux.ResourceID{id})
+		switch {
+		case err != nil:
+			logger.Log("err", errors.Wrap(err, "checking rollout"))
+		case len(controllers) == 0:
+			logger.Log("msg", "controller not found in cluster; not verifying rollout")
+			return ""
+		default:
+			status = controllers[0].Status
+			switch rolloutProgress(controllers[0], updates) {
+			case rolloutComplete:
+				logger.Log("msg", "rollout complete")
+				return ""
+			case rolloutSuperseded:
+				logger.Log("msg", "release superseded; not verifying rollout")
+				return ""
+			}
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			break
+		}
+		if remaining > rolloutPollInterval {
+			remaining = rolloutPollInterval
+		}
+		select {
+		case <-stop:
+			logger.Log("msg", "shutting down; not verifying rollout")
+			return ""
+		case <-time.After(remaining):
+		}
+	}
+	return d.revertRelease(id, revision, timeout, status, logger)
+}
+
+// rolloutProgress says how far the controller has got with rolling
+// out the container updates given: whether it's running the updated
+// images, and if so, whether all its replicas are updated and
+// available. If it's running some other images, the release has been
+// superseded.
+func rolloutProgress(c cluster.Controller, updates []update.ContainerUpdate) rolloutState {
+	images := map[string]image.Ref{}
+	for _, container := range c.ContainersOrNil() {
+		if ref, err := image.ParseRef(container.Image); err == nil {
+			images[container.Name] = ref
+		}
+	}
+	for _, u := range updates {
+		switch images[u.Container].String() {
+		case u.Target.String():
+		case u.Current.String():
+			// not applied yet
+			return rolloutInProgress
+		default:
+			return rolloutSuperseded
+		}
+	}
+	if c.Rollout.Complete() {
+		return rolloutComplete
+	}
+	return rolloutInProgress
+}
+
+// revertRelease rolls back the release of the controller, and locks
+// it so it isn't released again until someone has had a look.
+func (d *Daemon) revertRelease(id flux.ResourceID, revision string, timeout time.Duration, status string, logger log.Logger) job.ID {
+	started := time.Now().UTC()
+	spec := update.Spec{
+		Type: update.Rollback,
+		Cause: update.Cause{
+			User:    update.UserAutomated,
+			Message: fmt.Sprintf("Roll back %s: release %.7s did not roll out within %s", id, revision, timeout),
+		},
+		Spec: update.RollbackSpec{
+			ServiceID: id,
+			To:        revision,
+			Lock:      true,
+		},
+	}
+	metadata := &event.RevertEventMetadata{
+		Revision: revision,
+		Timeout:  timeout.String(),
+		Status:   status,
+	}
+	jobID, err := d.UpdateManifests(context.Background(), spec)
+	if err != nil {
+		logger.Log("err", errors.Wrap(err, "rolling back release"))
+		metadata.Error = err.Error()
+	} else {
+		logger.Log("msg", "rollout not complete; rolling back", "job", jobID)
+		metadata.JobID = string(jobID)
+	}
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: []flux.ResourceID{id},
+		Type:       event.EventRevert,
+		StartedAt:  started,
+		EndedAt:    time.Now().UTC(),
+		LogLevel:   event.LogLevelError,
+		Metadata:   metadata,
+	}); err != nil {
+		logger.Log("err", err)
+	}
+	return jobID
+}
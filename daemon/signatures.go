@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+)
+
+// latestValidRevision finds the newest revision that can be synced
+// from the checkout when only commits with valid signatures are to be
+// trusted; that is, the last commit before the first commit (since
+// the sync tag) without a valid signature. The first such commit is
+// also returned, or nil if there isn't one. If even the oldest commit
+// is invalid, the revision is that of the sync tag, or empty if
+// there's no sync tag.
+func latestValidRevision(ctx context.Context, working *git.Checkout) (string, *git.Commit, error) {
+	var tagRevision string
+	var commits []git.Commit
+	{
+		ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+		defer cancel()
+		var err error
+		tagRevision, err = working.TagRevision(ctx, working.SyncTag)
+		switch {
+		case isUnknownRevision(err):
+			tagRevision = ""
+			commits, err = working.CommitsBefore(ctx, "HEAD")
+		case err == nil:
+			commits, err = working.CommitsBetween(ctx, working.SyncTag, "HEAD")
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	// The commits are in order newest first
+	for i := len(commits) - 1; i >= 0; i-- {
+		if commits[i].Signature.Valid() {
+			continue
+		}
+		invalid := commits[i]
+		if i == len(commits)-1 {
+			return tagRevision, &invalid, nil
+		}
+		return commits[i+1].Revision, &invalid, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	head, err := working.HeadRevision(ctx)
+	return head, nil, err
+}
+
+// checkoutLatestValid checks out the latest revision of the working
+// clone that has a valid signature, and reports the commit that
+// stopped it going further, if there is one.
+func (d *Daemon) checkoutLatestValid(ctx context.Context, working *git.Checkout, started time.Time, logger log.Logger) error {
+	revision, invalid, err := latestValidRevision(ctx, working)
+	if err != nil {
+		return errors.Wrap(err, "verifying commit signatures")
+	}
+	d.reportUnverified(working.Remote().URL, revision, invalid, started, logger)
+	if invalid == nil {
+		return nil
+	}
+	if revision == "" {
+		return fmt.Errorf("no commits with valid signatures to sync in %s", working.Remote().URL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	return working.CheckoutRevision(ctx, revision)
+}
+
+// reportUnverified logs the first commit without a valid signature,
+// and sends an event if it's not the same one as at the last sync.
+func (d *Daemon) reportUnverified(url, revision string, invalid *git.Commit, started time.Time, logger log.Logger) {
+	d.lastSyncMu.Lock()
+	if d.lastUnverified == nil {
+		d.lastUnverified = map[string]string{}
+	}
+	last := d.lastUnverified[url]
+	if invalid == nil {
+		delete(d.lastUnverified, url)
+	} else {
+		d.lastUnverified[url] = invalid.Revision
+	}
+	d.lastSyncMu.Unlock()
+
+	if invalid == nil {
+		return
+	}
+	logger.Log("warning", "commit does not have a valid signature; not syncing it or anything after it", "repo", url, "revision", invalid.Revision, "signature", invalid.Signature.Status)
+	if last == invalid.Revision {
+		return
+	}
+	if err := d.LogEvent(event.Event{
+		Type:      event.EventUnverified,
+		StartedAt: started,
+		EndedAt:   time.Now().UTC(),
+		LogLevel:  event.LogLevelWarn,
+		Metadata: &event.UnverifiedEventMetadata{
+			Revision:       invalid.Revision,
+			Message:        invalid.Message,
+			Key:            invalid.Signature.Key,
+			Status:         invalid.Signature.Status,
+			SyncedRevision: revision,
+		},
+	}); err != nil {
+		logger.Log("err", err)
+	}
+}
+
+// unverifiedCommitError is returned from SyncStatus when a commit
+// that hasn't been synced doesn't have a valid signature, since it
+// won't be synced until something is done about it.
+func unverifiedCommitError(url string, commit git.Commit) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("commit %s in %s does not have a valid signature", commit.Revision, url),
+		Help: `Commit without a valid signature
+
+Commits in the git repo are only synced if they are signed by one of
+the keys fluxd has been told to trust. Commit
+
+    ` + commit.Revision + ` (` + commit.Message + `)
+
+in ` + url + ` does not have a valid signature, so fluxd will
+not sync it or anything after it.
+
+To fix this, either sign the commit (and those after it) with a
+trusted key and force-push, or, once you have checked it, move the
+sync tag past it.
+`,
+	}
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/event"
+)
+
+// SuspensionStore keeps the daemon's suspension somewhere it will
+// survive a restart.
+type SuspensionStore interface {
+	LoadSuspension() (flux.Suspension, error)
+	SaveSuspension(flux.Suspension) error
+}
+
+// LoadSuspension picks up the suspension (if any) from before the
+// daemon was last restarted.
+func (d *Daemon) LoadSuspension() error {
+	if d.Suspensions == nil {
+		return nil
+	}
+	s, err := d.Suspensions.LoadSuspension()
+	if err != nil {
+		return err
+	}
+	d.suspensionMu.Lock()
+	d.suspension = s
+	d.suspensionMu.Unlock()
+	return nil
+}
+
+// Suspend stops syncing and/or automation, as asked, until resumed
+// or until the suspension expires. Anything already suspended stays
+// suspended; the reason and expiry given replace those from before.
+func (d *Daemon) Suspend(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	if !s.Suspended() {
+		return flux.Suspension{}, &fluxerr.Error{
+			Type: fluxerr.User,
+			Err:  errors.New("nothing to suspend"),
+			Help: "Nothing to suspend: ask for syncing, automation, or both to be suspended.",
+		}
+	}
+	d.suspensionMu.Lock()
+	current := d.suspension
+	if current.Expired(time.Now()) {
+		current = flux.Suspension{}
+	}
+	s.Sync = s.Sync || current.Sync
+	s.Automation = s.Automation || current.Automation
+	err := d.saveSuspension(s)
+	// Logging the event may mean a call to the upstream service, so
+	// don't hold up the loop checking for a suspension meanwhile
+	d.suspensionMu.Unlock()
+	if err != nil {
+		return current, err
+	}
+	d.LogEvent(event.Event{
+		Type:      event.EventSuspend,
+		StartedAt: time.Now().UTC(),
+		EndedAt:   time.Now().UTC(),
+		LogLevel:  event.LogLevelWarn,
+		Metadata:  &event.SuspendEventMetadata{Suspension: s},
+	})
+	return s, nil
+}
+
+// Resume starts syncing and/or automation again, as asked, and
+// returns what's still suspended.
+func (d *Daemon) Resume(ctx context.Context, r flux.Suspension) (flux.Suspension, error) {
+	d.suspensionMu.Lock()
+	current := d.suspension
+	resumed := &event.ResumeEventMetadata{
+		Sync:       r.Sync && current.Sync,
+		Automation: r.Automation && current.Automation,
+		User:       r.User,
+	}
+	if current.Expired(time.Now()) {
+		resumed.Sync, resumed.Automation = current.Sync, current.Automation
+		resumed.Expired = true
+	}
+	if !resumed.Sync && !resumed.Automation {
+		d.suspensionMu.Unlock()
+		return current, nil
+	}
+	s := current
+	s.Sync = current.Sync && !resumed.Sync
+	s.Automation = current.Automation && !resumed.Automation
+	err := d.resume(s)
+	left := d.suspension
+	d.suspensionMu.Unlock()
+	if err != nil {
+		return current, err
+	}
+	d.logResumed(resumed)
+	d.AskForSync()
+	d.AskForImagePoll()
+	return left, nil
+}
+
+// suspended gives what's currently suspended. If the suspension has
+// expired, it's cleared first.
+func (d *Daemon) suspended(logger log.Logger) flux.Suspension {
+	d.suspensionMu.Lock()
+	var resumed *event.ResumeEventMetadata
+	if d.suspension.Suspended() && d.suspension.Expired(time.Now()) {
+		expired := &event.ResumeEventMetadata{
+			Sync:       d.suspension.Sync,
+			Automation: d.suspension.Automation,
+			Expired:    true,
+		}
+		if err := d.resume(flux.Suspension{}); err != nil {
+			logger.Log("operation", "resume", "err", err)
+		} else {
+			resumed = expired
+		}
+	}
+	s := d.suspension
+	d.suspensionMu.Unlock()
+	if resumed != nil {
+		d.logResumed(resumed)
+	}
+	return s
+}
+
+// resume records the suspension that's left after resuming. It must
+// be called with the suspension lock held; the event for resuming is
+// logged (with logResumed) after letting go of the lock, since that
+// may mean a call to the upstream service.
+func (d *Daemon) resume(s flux.Suspension) error {
+	if !s.Suspended() {
+		s = flux.Suspension{}
+	}
+	return d.saveSuspension(s)
+}
+
+func (d *Daemon) logResumed(resumed *event.ResumeEventMetadata) {
+	d.LogEvent(event.Event{
+		Type:      event.EventResume,
+		StartedAt: time.Now().UTC(),
+		EndedAt:   time.Now().UTC(),
+		LogLevel:  event.LogLevelInfo,
+		Metadata:  resumed,
+	})
+}
+
+// saveSuspension persists and records the suspension given. It must
+// be called with the suspension lock held.
+func (d *Daemon) saveSuspension(s flux.Suspension) error {
+	if d.Suspensions != nil {
+		if err := d.Suspensions.SaveSuspension(s); err != nil {
+			return err
+		}
+	}
+	d.suspension = s
+	return nil
+}
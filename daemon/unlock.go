@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// unlockExpired queues a job to remove the locks that have expired,
+// as of the time given. Until it's done, those locks are ignored
+// anyway (see policy.ResourceMap.OnlyLocked).
+func (d *Daemon) unlockExpired(now time.Time, logger log.Logger) {
+	// Don't unlock again while the last unlock is yet to land
+	if d.lastExpiredUnlock != "" {
+		status, err := d.JobStatus(context.Background(), d.lastExpiredUnlock)
+		if err == nil && status.StatusString != job.StatusSucceeded && status.StatusString != job.StatusFailed {
+			return
+		}
+		d.lastExpiredUnlock = ""
+	}
+
+	unlock := d.readLockRepos()
+	services, err := d.Manifests.ServicesWithPolicies(manifestDirs(d.checkouts())...)
+	if err != nil {
+		unlock()
+		logger.Log("error", errors.Wrap(err, "checking for expired locks"))
+		return
+	}
+	namespaces, err := d.Manifests.NamespacesWithPolicies(manifestDirs(d.checkouts())...)
+	unlock()
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "checking for expired locks"))
+		return
+	}
+
+	updates := policy.Updates{}
+	var ids []flux.ResourceID
+	removeLock := policy.Update{
+		Remove: policy.Set{}.Add(policy.Locked, policy.LockedUser, policy.LockedMsg, policy.LockedUntil),
+	}
+	for ns, policies := range namespaces {
+		if policies.LockExpired(now) {
+			id := flux.MakeResourceID(ns, "namespace", ns)
+			updates[id] = removeLock
+			ids = append(ids, id)
+		}
+	}
+	for id, policies := range services.OnlyWithPolicy(policy.Locked) {
+		if !policies.LockExpired(now) {
+			continue
+		}
+		// A lock inherited from the namespace is removed from the
+		// namespace, above
+		ns, _, _ := id.Components()
+		if until, ok := namespaces[ns].Get(policy.LockedUntil); ok && until == policies[policy.LockedUntil] {
+			continue
+		}
+		updates[id] = removeLock
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var names []string
+	for _, id := range ids {
+		names = append(names, id.String())
+	}
+	sort.Strings(names)
+	spec := update.Spec{
+		Type: update.Policy,
+		Cause: update.Cause{
+			User:    update.UserAutomated,
+			Message: fmt.Sprintf("Unlock %s, since the lock expired", strings.Join(names, ", ")),
+		},
+		Spec: updates,
+	}
+	id, err := d.UpdateManifests(context.Background(), spec)
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "unlocking controllers with expired locks"))
+		return
+	}
+	logger.Log("msg", "unlocking controllers with expired locks", "controllers", strings.Join(names, ","), "job", id)
+	d.lastExpiredUnlock = id
+	d.LogEvent(event.Event{
+		ServiceIDs: ids,
+		Type:       event.EventUnlock,
+		StartedAt:  now.UTC(),
+		EndedAt:    now.UTC(),
+		LogLevel:   event.LogLevelInfo,
+		Message:    fmt.Sprintf("Lock expired: %s", strings.Join(names, ", ")),
+	})
+}
@@ -17,6 +17,12 @@ import (
 const (
 	EventCommit       = "commit"
 	EventSync         = "sync"
+	EventSyncFailed   = "sync_failed"
+	EventDrift        = "drift"
+	EventUnverified   = "unverified"
+	EventSuspend      = "suspend"
+	EventResume       = "resume"
+	EventRevert       = "revert"
 	EventRelease      = "release"
 	EventAutoRelease  = "autorelease"
 	EventAutomate     = "automate"
@@ -125,9 +131,18 @@ func (e Event) String() string {
 		if len(strImageIDs) == 0 {
 			strImageIDs = []string{"no image changes"}
 		}
+		var promoted string
+		if promotions := metadata.Spec.Promotions(); len(promotions) > 0 {
+			var strPromotions []string
+			for _, p := range promotions {
+				strPromotions = append(strPromotions, fmt.Sprintf("%s from %s", p.ImageID, p.Source))
+			}
+			promoted = fmt.Sprintf(", promoting %s", strings.Join(strPromotions, ", "))
+		}
 		return fmt.Sprintf(
-			"Automated release of %s",
+			"Automated release of %s%s",
 			strings.Join(strImageIDs, ", "),
+			promoted,
 		)
 	case EventCommit:
 		metadata := e.Metadata.(*CommitEventMetadata)
@@ -135,6 +150,9 @@ func (e Event) String() string {
 		if len(strServiceIDs) > 0 {
 			svcStr = strings.Join(strServiceIDs, ", ")
 		}
+		if metadata.Branch != "" {
+			return fmt.Sprintf("Commit: %s (to branch %s), %s", shortRevision(metadata.Revision), metadata.Branch, svcStr)
+		}
 		return fmt.Sprintf("Commit: %s, %s", shortRevision(metadata.Revision), svcStr)
 	case EventSync:
 		metadata := e.Metadata.(*SyncEventMetadata)
@@ -153,6 +171,46 @@ func (e Event) String() string {
 			svcStr = strings.Join(strServiceIDs, ", ")
 		}
 		return fmt.Sprintf("Sync: %s, %s", revStr, svcStr)
+	case EventSyncFailed:
+		metadata := e.Metadata.(*SyncFailedEventMetadata)
+		reason := metadata.Error
+		if len(metadata.Errors) > 0 {
+			reason = fmt.Sprintf("%d resources failed", len(metadata.Errors))
+		}
+		return fmt.Sprintf("Sync failed: %s, %s", shortRevision(metadata.Revision), reason)
+	case EventDrift:
+		metadata := e.Metadata.(*DriftEventMetadata)
+		if len(metadata.Diffs) == 0 {
+			return fmt.Sprintf("Drift: %s, no resources differ", shortRevision(metadata.Revision))
+		}
+		ids := make([]string, len(metadata.Diffs))
+		for i, d := range metadata.Diffs {
+			ids[i] = d.ID.String()
+		}
+		return fmt.Sprintf("Drift: %s, %s", shortRevision(metadata.Revision), strings.Join(ids, ", "))
+	case EventUnverified:
+		metadata := e.Metadata.(*UnverifiedEventMetadata)
+		if metadata.SyncedRevision == "" {
+			return fmt.Sprintf("Unverified commit: %s, nothing synced", shortRevision(metadata.Revision))
+		}
+		return fmt.Sprintf("Unverified commit: %s, not syncing beyond %s", shortRevision(metadata.Revision), shortRevision(metadata.SyncedRevision))
+	case EventSuspend:
+		metadata := e.Metadata.(*SuspendEventMetadata)
+		what := suspendedThings(metadata.Sync, metadata.Automation)
+		if metadata.Reason != "" {
+			return fmt.Sprintf("Suspended: %s (%s)", what, metadata.Reason)
+		}
+		return fmt.Sprintf("Suspended: %s", what)
+	case EventResume:
+		metadata := e.Metadata.(*ResumeEventMetadata)
+		what := suspendedThings(metadata.Sync, metadata.Automation)
+		if metadata.Expired {
+			return fmt.Sprintf("Resumed: %s (suspension expired)", what)
+		}
+		return fmt.Sprintf("Resumed: %s", what)
+	case EventRevert:
+		metadata := e.Metadata.(*RevertEventMetadata)
+		return fmt.Sprintf("Revert: %s, release %s did not roll out within %s", strings.Join(strServiceIDs, ", "), shortRevision(metadata.Revision), metadata.Timeout)
 	case EventAutomate:
 		return fmt.Sprintf("Automated: %s", strings.Join(strServiceIDs, ", "))
 	case EventDeautomate:
@@ -168,6 +226,17 @@ func (e Event) String() string {
 	}
 }
 
+func suspendedThings(sync, automation bool) string {
+	var things []string
+	if sync {
+		things = append(things, "sync")
+	}
+	if automation {
+		things = append(things, "automation")
+	}
+	return strings.Join(things, ", ")
+}
+
 func shortRevision(rev string) string {
 	if len(rev) <= 7 {
 		return rev
@@ -180,6 +249,11 @@ type CommitEventMetadata struct {
 	Revision string        `json:"revision,omitempty"`
 	Spec     *update.Spec  `json:"spec"`
 	Result   update.Result `json:"result,omitempty"`
+	// If the commit was pushed to a branch of its own, to be merged
+	// later, rather than to the branch that's synced; and the
+	// reference to the request to merge it, if there is one
+	Branch        string `json:"branch,omitempty"`
+	ChangeRequest string `json:"changeRequest,omitempty"`
 }
 
 func (c CommitEventMetadata) ShortRevision() string {
@@ -206,6 +280,9 @@ type SyncEventMetadata struct {
 	Includes map[string]bool `json:"includes,omitempty"`
 	// `true` if we have no record of having synced before
 	InitialSync bool `json:"initialSync,omitempty"`
+	// The errors for any resources that failed to sync, by resource
+	// ID, when the sync was nonetheless treated as done
+	Errors map[string]string `json:"errors,omitempty"`
 }
 
 // Account for old events, which used the revisions field rather than commits
@@ -224,6 +301,68 @@ func (ev *SyncEventMetadata) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// SyncFailedEventMetadata is the metadata for when a sync failed,
+// and the sync tag was left where it was
+type SyncFailedEventMetadata struct {
+	Revision string `json:"revision"`
+	// The error for each resource that failed, by resource ID
+	Errors map[string]string `json:"errors,omitempty"`
+	// Set if the sync failed other than because of particular
+	// resources
+	Error string `json:"error,omitempty"`
+}
+
+// DriftEventMetadata is the metadata for when resources that are
+// only reported on, rather than synced, differ from the repo (or
+// stop differing, in which case there are no diffs).
+type DriftEventMetadata struct {
+	Revision string              `json:"revision"`
+	Diffs    []flux.ResourceDiff `json:"diffs,omitempty"`
+}
+
+// UnverifiedEventMetadata is the metadata for when a commit without
+// a valid signature stops the sync from going any further.
+type UnverifiedEventMetadata struct {
+	Revision string `json:"revision"`
+	Message  string `json:"message,omitempty"`
+	// The ID of the key used to sign the commit, if it was signed,
+	// and the status of the signature as reported by git
+	Key    string `json:"key,omitempty"`
+	Status string `json:"status"`
+	// The revision that was synced instead; empty if there was none
+	SyncedRevision string `json:"syncedRevision,omitempty"`
+}
+
+// SuspendEventMetadata is the metadata for when syncing and/or
+// automation are suspended; it gives what was suspended, and why.
+type SuspendEventMetadata struct {
+	flux.Suspension
+}
+
+// ResumeEventMetadata is the metadata for when syncing and/or
+// automation are resumed, either when asked or because the
+// suspension expired.
+type ResumeEventMetadata struct {
+	Sync       bool   `json:"sync,omitempty"`
+	Automation bool   `json:"automation,omitempty"`
+	User       string `json:"user,omitempty"`
+	Expired    bool   `json:"expired,omitempty"`
+}
+
+// RevertEventMetadata is the metadata for when a controller has not
+// finished rolling out a release in the time given by its
+// verify_rollout policy, and the release is being rolled back.
+type RevertEventMetadata struct {
+	Revision string `json:"revision"` // of the release
+	Timeout  string `json:"timeout"`
+	// The controller's status, as of giving up on it
+	Status string `json:"status,omitempty"`
+	// The job rolling back the release, or the error if it could
+	// not be started
+	JobID string `json:"jobID,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
 type ReleaseEventCommon struct {
 	Revision string        // the revision which has the changes for the release
 	Result   update.Result `json:"result"`
@@ -294,6 +433,48 @@ func (e *Event) UnmarshalJSON(in []byte) error {
 		}
 		e.Metadata = &metadata
 		break
+	case EventSyncFailed:
+		var metadata SyncFailedEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventDrift:
+		var metadata DriftEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventUnverified:
+		var metadata UnverifiedEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventSuspend:
+		var metadata SuspendEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventResume:
+		var metadata ResumeEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventRevert:
+		var metadata RevertEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
 	default:
 		if len(wireEvent.MetadataBytes) > 0 {
 			var metadata UnknownEventMetadata
@@ -325,6 +506,30 @@ func (cem *SyncEventMetadata) Type() string {
 	return EventSync
 }
 
+func (sfm *SyncFailedEventMetadata) Type() string {
+	return EventSyncFailed
+}
+
+func (dm *DriftEventMetadata) Type() string {
+	return EventDrift
+}
+
+func (um *UnverifiedEventMetadata) Type() string {
+	return EventUnverified
+}
+
+func (sm *SuspendEventMetadata) Type() string {
+	return EventSuspend
+}
+
+func (rm *ResumeEventMetadata) Type() string {
+	return EventResume
+}
+
+func (rm *RevertEventMetadata) Type() string {
+	return EventRevert
+}
+
 func (rem *ReleaseEventMetadata) Type() string {
 	return EventRelease
 }
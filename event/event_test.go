@@ -3,7 +3,11 @@ package event
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/update"
 )
 
@@ -60,3 +64,219 @@ func TestEvent_ParseNoMetadata(t *testing.T) {
 		t.Fatal("Hasn't been unmarshalled properly")
 	}
 }
+
+func TestEvent_ParseSyncFailedMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventSyncFailed,
+		Metadata: &SyncFailedEventMetadata{
+			Revision: "abcdef0123456789",
+			Errors:   map[string]string{"default:deployment/foo": "invalid"},
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	err := e.UnmarshalJSON(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch r := e.Metadata.(type) {
+	case *SyncFailedEventMetadata:
+		if r.Revision != "abcdef0123456789" ||
+			r.Errors["default:deployment/foo"] != "invalid" {
+			t.Fatal("Sync failed event wasn't marshalled/unmarshalled")
+		}
+	default:
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if e.String() != "Sync failed: abcdef0, 1 resources failed" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+}
+
+func TestEvent_ParseDriftMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventDrift,
+		Metadata: &DriftEventMetadata{
+			Revision: "abcdef0123456789",
+			Diffs: []flux.ResourceDiff{
+				{ID: flux.MustParseResourceID("default:deployment/foo"), Type: flux.ResourceChanged, Diff: "-a\n+b\n"},
+			},
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	err := e.UnmarshalJSON(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch r := e.Metadata.(type) {
+	case *DriftEventMetadata:
+		if r.Revision != "abcdef0123456789" ||
+			len(r.Diffs) != 1 ||
+			r.Diffs[0].ID.String() != "default:deployment/foo" {
+			t.Fatal("Drift event wasn't marshalled/unmarshalled")
+		}
+	default:
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if e.String() != "Drift: abcdef0, default:deployment/foo" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+}
+
+func TestEvent_ParseUnverifiedMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventUnverified,
+		Metadata: &UnverifiedEventMetadata{
+			Revision:       "abcdef0123456789",
+			Status:         "N",
+			SyncedRevision: "0123456789abcdef",
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	err := e.UnmarshalJSON(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch r := e.Metadata.(type) {
+	case *UnverifiedEventMetadata:
+		if r.Revision != "abcdef0123456789" || r.Status != "N" || r.SyncedRevision != "0123456789abcdef" {
+			t.Fatal("Unverified event wasn't marshalled/unmarshalled")
+		}
+	default:
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if e.String() != "Unverified commit: abcdef0, not syncing beyond 0123456" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+}
+
+func TestEvent_ParseSuspendMetadata(t *testing.T) {
+	expires := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	origEvent := Event{
+		Type: EventSuspend,
+		Metadata: &SuspendEventMetadata{flux.Suspension{
+			Sync:    true,
+			Reason:  "incident",
+			Expires: expires,
+		}},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	err := e.UnmarshalJSON(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch r := e.Metadata.(type) {
+	case *SuspendEventMetadata:
+		if !r.Sync || r.Automation || r.Reason != "incident" || !r.Expires.Equal(expires) {
+			t.Fatal("Suspend event wasn't marshalled/unmarshalled")
+		}
+	default:
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if e.String() != "Suspended: sync (incident)" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+
+	origEvent = Event{
+		Type: EventResume,
+		Metadata: &ResumeEventMetadata{
+			Sync:       true,
+			Automation: true,
+			Expired:    true,
+		},
+	}
+	bytes, _ = json.Marshal(origEvent)
+	e = Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.Metadata.(*ResumeEventMetadata); !ok {
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if e.String() != "Resumed: sync, automation (suspension expired)" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+}
+
+func TestEvent_ParseRevertMetadata(t *testing.T) {
+	origEvent := Event{
+		Type:       EventRevert,
+		ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("default:deployment/helloworld")},
+		Metadata: &RevertEventMetadata{
+			Revision: "0123456789abcdef",
+			Timeout:  "5m0s",
+			Status:   "1 out of 2 updated",
+			JobID:    "job-1",
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	r, ok := e.Metadata.(*RevertEventMetadata)
+	if !ok {
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if r.Revision != "0123456789abcdef" || r.JobID != "job-1" {
+		t.Fatal("Revert event wasn't marshalled/unmarshalled")
+	}
+	if e.String() != "Revert: default:deployment/helloworld, release 0123456 did not roll out within 5m0s" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+}
+
+func TestEvent_ParsePromotionMetadata(t *testing.T) {
+	prod := flux.MustParseResourceID("prod:deployment/helloworld")
+	staging := flux.MustParseResourceID("staging:deployment/helloworld")
+	current, _ := image.ParseRef("quay.io/weaveworks/helloworld:1")
+	ref, _ := image.ParseRef("quay.io/weaveworks/helloworld:2")
+	var spec update.Automated
+	spec.AddPromotion(prod, cluster.Container{Name: "greeter"}, ref, staging)
+	origEvent := Event{
+		Type:       EventAutoRelease,
+		ServiceIDs: []flux.ResourceID{prod},
+		Metadata: &AutoReleaseEventMetadata{
+			ReleaseEventCommon: ReleaseEventCommon{
+				Revision: "0123456789abcdef",
+				Result: update.Result{
+					prod: update.ControllerResult{
+						Status:       update.ReleaseStatusSuccess,
+						PerContainer: []update.ContainerUpdate{{Container: "greeter", Current: current, Target: ref}},
+					},
+				},
+			},
+			Spec: spec,
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	r, ok := e.Metadata.(*AutoReleaseEventMetadata)
+	if !ok {
+		t.Fatal("Wrong event type unmarshalled")
+	}
+	if promotions := r.Spec.Promotions(); len(promotions) != 1 || promotions[0].Source != staging {
+		t.Fatalf("expected promotion from %s, got %#v", staging, r.Spec)
+	}
+	if e.String() != "Automated release of quay.io/weaveworks/helloworld:2, promoting quay.io/weaveworks/helloworld:2 from staging:deployment/helloworld" {
+		t.Errorf("unexpected event string %q", e.String())
+	}
+}
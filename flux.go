@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/weaveworks/flux/image"
@@ -260,32 +261,149 @@ type ControllerStatus struct {
 	Locked     bool
 	Ignore     bool
 	Policies   map[string]string
+	// Inherited names those of the Policies the controller has from
+	// its namespace, rather than its own manifest
+	Inherited []string
+	Sync      ResourceSyncStatus
+}
+
+// ResourceSyncStatus records what happened when a resource was last
+// synced. Revision and Applied are for the last time the resource
+// was applied successfully; Error is set if the most recent attempt
+// failed.
+type ResourceSyncStatus struct {
+	Revision string    `json:"revision,omitempty"`
+	Applied  time.Time `json:"applied,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ResourceStatus gives the sync status of any resource defined in
+// the repo, whether or not it's a controller.
+type ResourceStatus struct {
+	ID   ResourceID
+	Sync ResourceSyncStatus
 }
 
 type Container struct {
 	Name           string
 	Current        image.Info
+	Candidate      *image.Info `json:",omitempty"` // the image automation would release, if any
+	Ordering       string      `json:",omitempty"` // how the candidate is picked; see policy.Ordering
 	Available      []image.Info
 	AvailableError string `json:",omitempty"`
 }
 
+// ResourceDiffType says how a resource differs between the cluster
+// and the git repo.
+type ResourceDiffType string
+
+const (
+	ResourceAdded   ResourceDiffType = "added"   // in the repo, but not in the cluster
+	ResourceRemoved ResourceDiffType = "removed" // in the cluster, but not in the repo
+	ResourceChanged ResourceDiffType = "changed" // in both, but different
+)
+
+// ResourceDiff is the difference between a resource as it is in the
+// cluster, and as it is defined in the git repo. Diff is a unified
+// diff of the YAML, from the cluster to the repo.
+type ResourceDiff struct {
+	ID   ResourceID
+	Type ResourceDiffType
+	Diff string
+}
+
+// SyncResult summarises how a sync went.
+type SyncResult string
+
+const (
+	SyncSucceeded SyncResult = "success" // everything was applied
+	SyncPartial   SyncResult = "partial" // some resources failed, but the sync went ahead
+	SyncFailed    SyncResult = "failed"  // the sync failed, and the sync tag was not moved
+)
+
+// SyncOutcome records the last attempt to sync the cluster with the
+// git repo. Errors gives the error for each resource that failed, by
+// resource ID; Error is set if the sync failed for some other reason.
+type SyncOutcome struct {
+	Revision string            `json:"revision"`
+	Started  time.Time         `json:"started"`
+	Result   SyncResult        `json:"result"`
+	Errors   map[string]string `json:"errors,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Suspension says what, if anything, fluxd has been asked to stop
+// doing for the time being; e.g., during an incident. If Expires is
+// not zero, the suspension lapses at that time.
+type Suspension struct {
+	Sync       bool      `json:"sync,omitempty"`
+	Automation bool      `json:"automation,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	User       string    `json:"user,omitempty"`
+	Expires    time.Time `json:"expires,omitempty"`
+}
+
+// Suspended says whether anything is suspended.
+func (s Suspension) Suspended() bool {
+	return s.Sync || s.Automation
+}
+
+// Expired says whether the suspension has lapsed, as of the time
+// given.
+func (s Suspension) Expired(now time.Time) bool {
+	return !s.Expires.IsZero() && !now.Before(s.Expires)
+}
+
 // --- config types
 
-func NewGitRemoteConfig(url, branch, path string) (GitRemoteConfig, error) {
-	if len(path) > 0 && path[0] == '/' {
-		return GitRemoteConfig{}, errors.New("git subdirectory (--git-path) should not have leading forward slash")
+func NewGitRemoteConfig(url, branch string, paths []string) (GitRemoteConfig, error) {
+	for _, path := range paths {
+		if len(path) > 0 && path[0] == '/' {
+			return GitRemoteConfig{}, errors.New("git subdirectory (--git-path) should not have leading forward slash")
+		}
 	}
 	return GitRemoteConfig{
 		URL:    url,
 		Branch: branch,
-		Path:   path,
+		Paths:  paths,
 	}, nil
 }
 
+// GitRemoteConfig says which repo and branch to use, and the
+// subdirectories of the repo in which to find manifests; if there
+// are no Paths, the whole repo is used.
 type GitRemoteConfig struct {
 	URL    string `json:"url"`
 	Branch string `json:"branch"`
-	Path   string `json:"path"`
+	// Path is the first of Paths, for readers that predate there
+	// being more than one; it's filled in when encoding, and used
+	// as the only path when decoding something without Paths
+	Path  string   `json:"path"`
+	Paths []string `json:"paths"`
+}
+
+// MarshalJSON encodes a GitRemoteConfig with both the path (the
+// first, if there's more than one) and the paths, so that previous
+// flux versions still get the path.
+func (c GitRemoteConfig) MarshalJSON() ([]byte, error) {
+	type plain GitRemoteConfig
+	if c.Path == "" && len(c.Paths) > 0 {
+		c.Path = c.Paths[0]
+	}
+	return json.Marshal(plain(c))
+}
+
+// UnmarshalJSON decodes a GitRemoteConfig, taking the path as the
+// only path if there are no paths, as from previous flux versions.
+func (c *GitRemoteConfig) UnmarshalJSON(data []byte) error {
+	type plain GitRemoteConfig
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	if len(c.Paths) == 0 && c.Path != "" {
+		c.Paths = []string{c.Path}
+	}
+	return nil
 }
 
 // GitRepoStatus represents the progress made synchronising with a git
@@ -301,7 +419,17 @@ const (
 )
 
 type GitConfig struct {
-	Remote       GitRemoteConfig `json:"remote"`
-	PublicSSHKey ssh.PublicKey   `json:"publicSSHKey"`
-	Status       GitRepoStatus   `json:"status"`
+	Remote GitRemoteConfig `json:"remote"`
+	// All the repos the daemon syncs from, starting with Remote
+	Remotes      []GitRemoteConfig `json:"remotes,omitempty"`
+	PublicSSHKey ssh.PublicKey     `json:"publicSSHKey"`
+	// The key used to sign commits and tags, if there is one
+	SigningKey *GPGKey       `json:"signingKey,omitempty"`
+	Status     GitRepoStatus `json:"status"`
+}
+
+// GPGKey is the public part of a GPG key.
+type GPGKey struct {
+	ID  string `json:"id"`  // as given to fluxd; e.g., a fingerprint
+	Key string `json:"key"` // ASCII-armored
 }
@@ -1,9 +1,12 @@
 package gittest
 
 import (
+	"bytes"
 	"io/ioutil"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"context"
@@ -53,7 +56,7 @@ func Repo(t *testing.T) (git.Repo, func()) {
 		t.Fatal(err)
 	}
 
-	conf, _ := flux.NewGitRemoteConfig(gitDir, "master", "")
+	conf, _ := flux.NewGitRemoteConfig(gitDir, "master", nil)
 	return git.Repo{
 		GitRemoteConfig: conf,
 	}, cleanup
@@ -78,6 +81,97 @@ func Checkout(t *testing.T) (*git.Checkout, func()) {
 	}
 }
 
+// GPGKey makes a throwaway GPG keyring with a new signing key in it,
+// and points GNUPGHOME at it (so git will use it to check
+// signatures). It returns the fingerprint of the key, and a func to
+// clean up afterwards.
+func GPGKey(t *testing.T) (string, func()) {
+	gpgHome, err := ioutil.TempDir("", "flux-gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldHome, hadHome := os.LookupEnv("GNUPGHOME")
+	os.Setenv("GNUPGHOME", gpgHome)
+	cleanup := func() {
+		execCommand("gpgconf", "--kill", "gpg-agent")
+		if hadHome {
+			os.Setenv("GNUPGHOME", oldHome)
+		} else {
+			os.Unsetenv("GNUPGHOME")
+		}
+		os.RemoveAll(gpgHome)
+	}
+
+	if err := execCommand("gpg", "--batch", "--pinentry-mode", "loopback", "--passphrase", "",
+		"--quick-generate-key", "Flux Test <flux-test@example.com>", "default", "sign", "never"); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	c := exec.Command("gpg", "--list-secret-keys", "--with-colons")
+	c.Stdout = out
+	if err := c.Run(); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Split(line, ":")
+		if fields[0] == "fpr" && len(fields) > 9 {
+			return fields[9], cleanup
+		}
+	}
+	cleanup()
+	t.Fatal("no fingerprint found for generated GPG key")
+	return "", nil
+}
+
+// PushCommit pushes a new commit, which changes one of the files, to
+// the repo given. The commit is signed with the key given, unless
+// that is empty. It returns the revision of the commit.
+func PushCommit(t *testing.T, repo flux.GitRemoteConfig, signingKey, message string) string {
+	dir, err := ioutil.TempDir("", "flux-push")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := execCommand("git", "clone", "--branch", repo.Branch, repo.URL, dir); err != nil {
+		t.Fatal(err)
+	}
+	for file := range testfiles.Files {
+		f, err := os.OpenFile(filepath.Join(dir, file), os.O_APPEND|os.O_WRONLY, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.WriteString("# " + message + "\n")
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	args := []string{"-C", dir, "-c", "user.name=example", "-c", "user.email=example@example.com"}
+	if signingKey != "" {
+		args = append(args, "-c", "user.signingkey="+signingKey, "commit", "-S")
+	} else {
+		args = append(args, "commit")
+	}
+	if err := execCommand("git", append(args, "-am", message)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", dir, "push", "origin", repo.Branch); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	c := exec.Command("git", "-C", dir, "rev-parse", "HEAD")
+	c.Stdout = out
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out.String())
+}
+
 func execCommand(cmd string, args ...string) error {
 	c := exec.Command(cmd, args...)
 	c.Stderr = ioutil.Discard
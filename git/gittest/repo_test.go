@@ -2,8 +2,10 @@ package gittest
 
 import (
 	"io/ioutil"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"context"
@@ -57,7 +59,7 @@ func TestCheckout(t *testing.T) {
 
 	changedFile := ""
 	for file, _ := range testfiles.Files {
-		path := filepath.Join(working.ManifestDir(), file)
+		path := filepath.Join(working.Dir, file)
 		if err := ioutil.WriteFile(path, []byte("FIRST CHANGE"), 0666); err != nil {
 			t.Fatal(err)
 		}
@@ -69,7 +71,7 @@ func TestCheckout(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	path := filepath.Join(working.ManifestDir(), changedFile)
+	path := filepath.Join(working.Dir, changedFile)
 	if err := ioutil.WriteFile(path, []byte("SECOND CHANGE"), 0666); err != nil {
 		t.Fatal(err)
 	}
@@ -94,7 +96,7 @@ func TestCheckout(t *testing.T) {
 	}
 
 	check := func(c *git.Checkout) {
-		contents, err := ioutil.ReadFile(filepath.Join(c.ManifestDir(), changedFile))
+		contents, err := ioutil.ReadFile(filepath.Join(c.Dir, changedFile))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -128,3 +130,149 @@ func TestCheckout(t *testing.T) {
 	defer anotherCheckout.Clean()
 	check(checkout)
 }
+
+func TestCommitSignatures(t *testing.T) {
+	key, cleanupKey := GPGKey(t)
+	defer cleanupKey()
+	checkout, cleanup := Checkout(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	head, err := checkout.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := PushCommit(t, checkout.Remote(), key, "Signed")
+	unsigned := PushCommit(t, checkout.Remote(), "", "Unsigned")
+	if err := checkout.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := checkout.CommitsBetween(ctx, head, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected two commits, got %#v", commits)
+	}
+	if commits[0].Revision != unsigned || commits[0].Signature.Valid() {
+		t.Errorf("expected unsigned commit first and not valid, got %#v", commits[0])
+	}
+	if commits[1].Revision != signed || !commits[1].Signature.Valid() || commits[1].Message != "Signed" {
+		t.Errorf("expected signed commit second and valid, got %#v", commits[1])
+	}
+
+	if err := checkout.CheckoutRevision(ctx, signed); err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := checkout.HeadRevision(ctx); err != nil || rev != signed {
+		t.Errorf("expected HEAD to be %s after checking out, got %s (err %v)", signed, rev, err)
+	}
+}
+
+func TestSignedCommitAndTag(t *testing.T) {
+	key, cleanupKey := GPGKey(t)
+	defer cleanupKey()
+	repo, cleanup := Repo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	checkout, err := repo.Clone(ctx, git.Config{
+		UserName:   "example",
+		UserEmail:  "example@example.com",
+		SyncTag:    "flux-test",
+		NotesRef:   "fluxtest",
+		SigningKey: key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkout.Clean()
+
+	head, err := checkout.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file := range testfiles.Files {
+		if err := ioutil.WriteFile(filepath.Join(checkout.Dir, file), []byte("CHANGED"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	if err := checkout.CommitAndPush(ctx, &git.CommitAction{Message: "Signed change"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	commits, err := checkout.CommitsBetween(ctx, head, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || !commits[0].Signature.Valid() {
+		t.Errorf("expected one commit with a valid signature, got %#v", commits)
+	}
+
+	if err := checkout.MoveTagAndPush(ctx, "HEAD", "Sync pointer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", checkout.Dir, "verify-tag", "flux-test"); err != nil {
+		t.Errorf("expected sync tag to have a valid signature, got %v", err)
+	}
+}
+
+func TestCommitAndPushBranch(t *testing.T) {
+	repo, cleanup := Repo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	config := git.Config{
+		UserName:  "example",
+		UserEmail: "example@example.com",
+		SyncTag:   "flux-test",
+		NotesRef:  "fluxtest",
+	}
+	checkout, err := repo.Clone(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkout.Clean()
+
+	head, err := checkout.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file := range testfiles.Files {
+		if err := ioutil.WriteFile(filepath.Join(checkout.Dir, file), []byte("CHANGED"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	if err := checkout.CommitAndPushBranch(ctx, "flux/test", &git.CommitAction{Message: "Change on a branch"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := checkout.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := checkout.IsAncestor(ctx, head, rev); err != nil || !ok {
+		t.Errorf("expected %s to be an ancestor of %s (err: %v)", head, rev, err)
+	}
+	if ok, err := checkout.IsAncestor(ctx, rev, head); err != nil || ok {
+		t.Errorf("expected %s not to be an ancestor of %s (err: %v)", rev, head, err)
+	}
+
+	// The commit went to the branch given, and not to the one cloned
+	fresh, err := repo.Clone(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Clean()
+	if ok, err := fresh.IsAncestor(ctx, rev, "HEAD"); err != nil || ok {
+		t.Errorf("expected commit not to be on the cloned branch (err: %v)", err)
+	}
+	out, err := exec.Command("git", "-C", repo.URL, "rev-parse", "refs/heads/flux/test").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(out)) != rev {
+		t.Errorf("expected branch to be at %s, got %s", rev, out)
+	}
+}
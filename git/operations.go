@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -55,23 +56,16 @@ func checkPush(ctx context.Context, workingDir, upstream string) error {
 	return execGitCmd(ctx, workingDir, nil, "push", "-d", upstream, "tag", CheckPushTag)
 }
 
-func commit(ctx context.Context, workingDir string, commitAction *CommitAction) error {
-	commitAuthor := commitAction.Author
-	if commitAuthor != "" {
-		if err := execGitCmd(ctx,
-			workingDir, nil,
-			"commit",
-			"--no-verify", "-a", "--author", commitAuthor, "-m", commitAction.Message,
-		); err != nil {
-			return errors.Wrap(err, "git commit")
-		}
-		return nil
+func commit(ctx context.Context, workingDir, signingKey string, commitAction *CommitAction) error {
+	args := []string{"commit", "--no-verify", "-a"}
+	if commitAction.Author != "" {
+		args = append(args, "--author", commitAction.Author)
+	}
+	if signingKey != "" {
+		args = append(args, "--gpg-sign="+signingKey)
 	}
-	if err := execGitCmd(ctx,
-		workingDir, nil,
-		"commit",
-		"--no-verify", "-a", "-m", commitAction.Message,
-	); err != nil {
+	args = append(args, "-m", commitAction.Message)
+	if err := execGitCmd(ctx, workingDir, nil, args...); err != nil {
 		return errors.Wrap(err, "git commit")
 	}
 	return nil
@@ -173,6 +167,24 @@ func refRevision(ctx context.Context, path, ref string) (string, error) {
 	return strings.TrimSpace(out.String()), nil
 }
 
+func isAncestor(ctx context.Context, path, rev, ref string) (bool, error) {
+	err := execGitCmd(ctx, path, nil, "merge-base", "--is-ancestor", rev, ref)
+	switch {
+	case err == nil:
+		return true, nil
+	case isExitError(err): // exits with 1, and says nothing, if not
+		return false, nil
+	case strings.Contains(err.Error(), "Not a valid commit name"):
+		return false, nil
+	}
+	return false, err
+}
+
+func isExitError(err error) bool {
+	_, ok := err.(*exec.ExitError)
+	return ok
+}
+
 func revlist(ctx context.Context, path, ref string) ([]string, error) {
 	out := &bytes.Buffer{}
 	if err := execGitCmd(ctx, path, out, "rev-list", ref); err != nil {
@@ -181,25 +193,21 @@ func revlist(ctx context.Context, path, ref string) ([]string, error) {
 	return splitList(out.String()), nil
 }
 
-// Return the revisions and one-line log commit messages
-// subdir argument ... corresponds to the git-path flag supplied to weave-flux-agent
-func onelinelog(ctx context.Context, path, refspec, subdir string) ([]Commit, error) {
+// Return the revisions and one-line log commit messages, along with
+// the signature status of each commit
+// subdirs argument ... corresponds to the git-path flags supplied to weave-flux-agent
+func onelinelog(ctx context.Context, path, refspec string, subdirs []string) ([]Commit, error) {
 	out := &bytes.Buffer{}
-
-	// we need to distinguish whether subdir is populated or not,
+	args := []string{"log", "--pretty=format:%GK|%G?|%H|%s", refspec}
+	// we need to distinguish whether there are subdirs or not,
 	// because supplying an empty string to execGitCmd results in git complaining about
 	// >> ambiguous argument '' <<
-	if subdir != "" {
-		if err := execGitCmd(ctx, path, out, "log", "--oneline", "--no-abbrev-commit", refspec, subdir); err != nil {
-			return nil, err
-		}
-		return splitLog(out.String())
+	if len(subdirs) > 0 {
+		args = append(append(args, "--"), subdirs...)
 	}
-
-	if err := execGitCmd(ctx, path, out, "log", "--oneline", "--no-abbrev-commit", refspec); err != nil {
+	if err := execGitCmd(ctx, path, out, args...); err != nil {
 		return nil, err
 	}
-
 	return splitLog(out.String())
 }
 
@@ -207,9 +215,16 @@ func splitLog(s string) ([]Commit, error) {
 	lines := splitList(s)
 	commits := make([]Commit, len(lines))
 	for i, m := range lines {
-		revAndMessage := strings.SplitN(m, " ", 2)
-		commits[i].Revision = revAndMessage[0]
-		commits[i].Message = revAndMessage[1]
+		parts := strings.SplitN(m, "|", 4)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("unexpected line in git log: %q", m)
+		}
+		commits[i].Signature = Signature{
+			Key:    parts[0],
+			Status: parts[1],
+		}
+		commits[i].Revision = parts[2]
+		commits[i].Message = parts[3]
 	}
 	return commits, nil
 }
@@ -222,9 +237,21 @@ func splitList(s string) []string {
 	return strings.Split(outStr, "\n")
 }
 
+func checkoutRevision(ctx context.Context, workingDir, rev string) error {
+	if err := execGitCmd(ctx, workingDir, nil, "checkout", "--quiet", "--detach", rev); err != nil {
+		return errors.Wrap(err, "checking out "+rev)
+	}
+	return nil
+}
+
 // Move the tag to the ref given and push that tag upstream
-func moveTagAndPush(ctx context.Context, path string, tag, ref, msg, upstream string) error {
-	if err := execGitCmd(ctx, path, nil, "tag", "--force", "-a", "-m", msg, tag, ref); err != nil {
+func moveTagAndPush(ctx context.Context, path string, tag, signingKey, ref, msg, upstream string) error {
+	args := []string{"tag", "--force", "-a"}
+	if signingKey != "" {
+		args = append(args, "--local-user="+signingKey)
+	}
+	args = append(args, "-m", msg, tag, ref)
+	if err := execGitCmd(ctx, path, nil, args...); err != nil {
 		return errors.Wrap(err, "moving tag "+tag)
 	}
 	if err := execGitCmd(ctx, path, nil, "push", "--force", upstream, "tag", tag); err != nil {
@@ -233,16 +260,19 @@ func moveTagAndPush(ctx context.Context, path string, tag, ref, msg, upstream st
 	return nil
 }
 
-func changedFiles(ctx context.Context, path, subPath, ref string) ([]string, error) {
+func changedFiles(ctx context.Context, path string, subPaths []string, ref string) ([]string, error) {
 	// Remove leading slash if present. diff doesn't work when using github style root paths.
-	if len(subPath) > 0 && subPath[0] == '/' {
-		return []string{}, errors.New("git subdirectory should not have leading forward slash")
+	for _, subPath := range subPaths {
+		if len(subPath) > 0 && subPath[0] == '/' {
+			return []string{}, errors.New("git subdirectory should not have leading forward slash")
+		}
 	}
 	out := &bytes.Buffer{}
 	// This uses --diff-filter to only look at changes for file _in
 	// the working dir_; i.e, we do not report on things that no
 	// longer appear.
-	if err := execGitCmd(ctx, path, out, "diff", "--name-only", "--diff-filter=ACMRT", ref, "--", subPath); err != nil {
+	args := append([]string{"diff", "--name-only", "--diff-filter=ACMRT", ref, "--"}, subPaths...)
+	if err := execGitCmd(ctx, path, out, args...); err != nil {
 		return nil, err
 	}
 	return splitList(out.String()), nil
@@ -278,13 +308,20 @@ func execGitCmd(ctx context.Context, dir string, out io.Writer, args ...string)
 }
 
 func env() []string {
-	return []string{"GIT_TERMINAL_PROMPT=0"}
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	// So that signatures are checked against the keyring that keys
+	// were imported into
+	if gnupgHome, ok := os.LookupEnv("GNUPGHOME"); ok {
+		env = append(env, "GNUPGHOME="+gnupgHome)
+	}
+	return env
 }
 
 // check returns true if there are changes locally.
-func check(ctx context.Context, workingDir, subdir string) bool {
+func check(ctx context.Context, workingDir string, subdirs []string) bool {
 	// `--quiet` means "exit with 1 if there are changes"
-	return execGitCmd(ctx, workingDir, nil, "diff", "--quiet", "--", subdir) != nil
+	args := append([]string{"diff", "--quiet", "--"}, subdirs...)
+	return execGitCmd(ctx, workingDir, nil, args...) != nil
 }
 
 func findErrorMessage(output io.Reader) string {
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
@@ -111,7 +112,7 @@ func TestChangedFiles_SlashPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = changedFiles(context.Background(), newDir, nestedDir, "HEAD")
+	_, err = changedFiles(context.Background(), newDir, []string{nestedDir}, "HEAD")
 	if err == nil {
 		t.Fatal("Should have errored")
 	}
@@ -128,7 +129,7 @@ func TestChangedFiles_UnslashPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = changedFiles(context.Background(), newDir, nestedDir, "HEAD")
+	_, err = changedFiles(context.Background(), newDir, []string{nestedDir}, "HEAD")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -145,7 +146,7 @@ func TestChangedFiles_NoPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = changedFiles(context.Background(), newDir, nestedDir, "HEAD")
+	_, err = changedFiles(context.Background(), newDir, nil, "HEAD")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -168,7 +169,7 @@ func TestOnelinelog_NoGitpath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	commits, err := onelinelog(context.Background(), newDir, "HEAD~2..HEAD", "")
+	commits, err := onelinelog(context.Background(), newDir, "HEAD~2..HEAD", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -196,7 +197,7 @@ func TestOnelinelog_WithGitpath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	commits, err := onelinelog(context.Background(), newDir, "HEAD~2..HEAD", "dev")
+	commits, err := onelinelog(context.Background(), newDir, "HEAD~2..HEAD", []string{"dev"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -206,6 +207,45 @@ func TestOnelinelog_WithGitpath(t *testing.T) {
 	}
 }
 
+func TestOnelinelog_WithMultipleGitpaths(t *testing.T) {
+	newDir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+
+	subdirs := []string{"base", "dev", "prod"}
+
+	err := createRepo(newDir, subdirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range subdirs {
+		if err = updateDirAndCommit(newDir, dir, testfiles.FilesUpdated); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commits, err := onelinelog(context.Background(), newDir, "HEAD~3..HEAD", []string{"base", "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected commits to base and prod, got %+v", commits)
+	}
+
+	files, err := changedFiles(context.Background(), newDir, []string{"base", "prod"}, "HEAD~3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file, "dev/") {
+			t.Errorf("expected only changes in base and prod, got %s", file)
+		}
+	}
+	if len(files) == 0 {
+		t.Error("expected changes in base and prod")
+	}
+}
+
 func createRepo(dir string, subdirs []string) error {
 	var (
 		err      error
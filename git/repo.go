@@ -44,11 +44,14 @@ type Config struct {
 	UserName  string
 	UserEmail string
 	SetAuthor bool
+	// The GPG key to sign commits and the sync tag with, if any
+	SigningKey string
 }
 
 type Commit struct {
-	Revision string
-	Message  string
+	Signature Signature
+	Revision  string
+	Message   string
 }
 
 // CommitAction - struct holding commit information
@@ -135,9 +138,21 @@ func (c *Checkout) Clean() {
 	}
 }
 
-// ManifestDir returns a path to where the files are
-func (c *Checkout) ManifestDir() string {
-	return filepath.Join(c.Dir, c.repo.Path)
+// Remote gives the configuration of the repo this is a clone of
+func (c *Checkout) Remote() flux.GitRemoteConfig {
+	return c.repo.GitRemoteConfig
+}
+
+// ManifestDirs returns the paths to where the files are
+func (c *Checkout) ManifestDirs() []string {
+	if len(c.repo.Paths) == 0 {
+		return []string{c.Dir}
+	}
+	paths := make([]string, len(c.repo.Paths))
+	for i, p := range c.repo.Paths {
+		paths[i] = filepath.Join(c.Dir, p)
+	}
+	return paths
 }
 
 // CheckOriginWritable tests that we can write to the origin
@@ -155,12 +170,19 @@ func (c *Checkout) CheckOriginWritable(ctx context.Context) error {
 // CommitAndPush commits changes made in this checkout, along with any
 // extra data as a note, and pushes the commit and note to the remote repo.
 func (c *Checkout) CommitAndPush(ctx context.Context, commitAction *CommitAction, note *Note) error {
+	return c.CommitAndPushBranch(ctx, "", commitAction, note)
+}
+
+// CommitAndPushBranch is like CommitAndPush, but pushes the commit
+// to the branch given, rather than to the branch that was cloned
+// (unless the branch given is empty).
+func (c *Checkout) CommitAndPushBranch(ctx context.Context, branch string, commitAction *CommitAction, note *Note) error {
 	c.Lock()
 	defer c.Unlock()
-	if !check(ctx, c.Dir, c.repo.Path) {
+	if !check(ctx, c.Dir, c.repo.Paths) {
 		return ErrNoChanges
 	}
-	if err := commit(ctx, c.Dir, commitAction); err != nil {
+	if err := commit(ctx, c.Dir, c.SigningKey, commitAction); err != nil {
 		return err
 	}
 
@@ -175,6 +197,9 @@ func (c *Checkout) CommitAndPush(ctx context.Context, commitAction *CommitAction
 	}
 
 	refs := []string{c.repo.Branch}
+	if branch != "" {
+		refs = []string{"HEAD:refs/heads/" + branch}
+	}
 	ok, err := refExists(ctx, c.Dir, c.realNotesRef)
 	if ok {
 		refs = append(refs, c.realNotesRef)
@@ -231,26 +256,44 @@ func (c *Checkout) TagRevision(ctx context.Context, tag string) (string, error)
 func (c *Checkout) CommitsBetween(ctx context.Context, ref1, ref2 string) ([]Commit, error) {
 	c.RLock()
 	defer c.RUnlock()
-	return onelinelog(ctx, c.Dir, ref1+".."+ref2, c.repo.GitRemoteConfig.Path)
+	return onelinelog(ctx, c.Dir, ref1+".."+ref2, c.repo.Paths)
+}
+
+// IsAncestor says whether the revision given is in the history of
+// the ref given. A revision the checkout doesn't know about (e.g.,
+// because it's only been pushed to another branch) is not.
+func (c *Checkout) IsAncestor(ctx context.Context, rev, ref string) (bool, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return isAncestor(ctx, c.Dir, rev, ref)
 }
 
 func (c *Checkout) CommitsBefore(ctx context.Context, ref string) ([]Commit, error) {
 	c.RLock()
 	defer c.RUnlock()
-	return onelinelog(ctx, c.Dir, ref, c.repo.GitRemoteConfig.Path)
+	return onelinelog(ctx, c.Dir, ref, c.repo.Paths)
+}
+
+// CheckoutRevision checks out the revision given (detaching HEAD),
+// e.g., so as to sync from a revision other than the head of the
+// branch.
+func (c *Checkout) CheckoutRevision(ctx context.Context, rev string) error {
+	c.Lock()
+	defer c.Unlock()
+	return checkoutRevision(ctx, c.Dir, rev)
 }
 
 func (c *Checkout) MoveTagAndPush(ctx context.Context, ref, msg string) error {
 	c.Lock()
 	defer c.Unlock()
-	return moveTagAndPush(ctx, c.Dir, c.SyncTag, ref, msg, c.repo.URL)
+	return moveTagAndPush(ctx, c.Dir, c.SyncTag, c.SigningKey, ref, msg, c.repo.URL)
 }
 
 // ChangedFiles does a git diff listing changed files
 func (c *Checkout) ChangedFiles(ctx context.Context, ref string) ([]string, error) {
 	c.Lock()
 	defer c.Unlock()
-	list, err := changedFiles(ctx, c.Dir, c.repo.Path, ref)
+	list, err := changedFiles(ctx, c.Dir, c.repo.Paths, ref)
 	if err == nil {
 		for i, file := range list {
 			list[i] = filepath.Join(c.Dir, file)
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Signature is the GPG signature on a commit, as reported by git.
+type Signature struct {
+	Key    string // the ID of the key used to sign, if signed
+	Status string // as given by `git log --pretty=%G?`
+}
+
+// Valid says whether the commit has a good signature from a key in
+// the keyring. Since the keyring holds only the keys we were given to
+// trust, a good signature of unknown validity (i.e., from a key that
+// isn't itself signed by a trusted key) is also fine.
+func (s Signature) Valid() bool {
+	return s.Status == "G" || s.Status == "U"
+}
+
+// ImportKeys imports the public keys in the file given, or in each
+// file in the directory given, into the GPG keyring used to verify
+// signatures. It returns the names of the files imported.
+func ImportKeys(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if fi.IsDir() {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, info := range infos {
+			// Secrets mounted as volumes have hidden files and
+			// directories (e.g., `..data`) alongside the keys
+			if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+				continue
+			}
+			files = append(files, filepath.Join(path, info.Name()))
+		}
+	}
+
+	var imported []string
+	for _, file := range files {
+		cmd := exec.Command("gpg", "--batch", "--import", file)
+		errOut := &bytes.Buffer{}
+		cmd.Stderr = errOut
+		if err := cmd.Run(); err != nil {
+			return imported, errors.Wrapf(err, "importing keys from %s: %s", file, strings.TrimSpace(errOut.String()))
+		}
+		imported = append(imported, filepath.Base(file))
+	}
+	return imported, nil
+}
+
+// ExportKey gives the ASCII-armored public part of the key given
+// (by ID or fingerprint), e.g., to add to a git host so it shows
+// signed commits as verified.
+func ExportKey(key string) (string, error) {
+	cmd := exec.Command("gpg", "--batch", "--armor", "--export", key)
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "exporting key %s: %s", key, strings.TrimSpace(errOut.String()))
+	}
+	if out.Len() == 0 {
+		return "", fmt.Errorf("no key %s found in keyring", key)
+	}
+	return out.String(), nil
+}
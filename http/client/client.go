@@ -61,6 +61,9 @@ func (c *Client) UpdateImages(ctx context.Context, s update.ReleaseSpec, cause u
 	for _, ex := range s.Excludes {
 		args = append(args, "exclude", ex.String())
 	}
+	if s.Force {
+		args = append(args, "force", "true")
+	}
 	if cause.Message != "" {
 		args = append(args, "message", cause.Message)
 	}
@@ -91,6 +94,15 @@ func (c *Client) UpdatePolicies(ctx context.Context, updates policy.Updates, cau
 	return res, c.methodWithResp(ctx, "PATCH", &res, "UpdatePolicies", updates, args...)
 }
 
+func (c *Client) Rollback(ctx context.Context, spec update.RollbackSpec, cause update.Cause) (job.ID, error) {
+	args := []string{"user", cause.User}
+	if cause.Message != "" {
+		args = append(args, "message", cause.Message)
+	}
+	var res job.ID
+	return res, c.methodWithResp(ctx, "POST", &res, "Rollback", spec, args...)
+}
+
 func (c *Client) LogEvent(ctx context.Context, event event.Event) error {
 	return c.PostWithBody(ctx, "LogEvent", event)
 }
@@ -114,6 +126,63 @@ func (c *Client) PublicSSHKey(ctx context.Context, regenerate bool) (ssh.PublicK
 	return res, err
 }
 
+func (c *Client) Diff(ctx context.Context, s update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	var res []flux.ResourceDiff
+	err := c.Get(ctx, &res, "Diff", "service", string(s))
+	return res, err
+}
+
+func (c *Client) SyncOutcome(ctx context.Context) (flux.SyncOutcome, error) {
+	var res flux.SyncOutcome
+	err := c.Get(ctx, &res, "SyncOutcome")
+	return res, err
+}
+
+func (c *Client) ListResources(ctx context.Context, namespace string) ([]flux.ResourceStatus, error) {
+	var res []flux.ResourceStatus
+	err := c.Get(ctx, &res, "ListResources", "namespace", namespace)
+	return res, err
+}
+
+func (c *Client) Suspend(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	var res flux.Suspension
+	return res, c.methodWithResp(ctx, "POST", &res, "Suspend", s)
+}
+
+func (c *Client) Resume(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	var res flux.Suspension
+	return res, c.methodWithResp(ctx, "POST", &res, "Resume", s)
+}
+
+func (c *Client) ListPending(ctx context.Context) ([]update.PendingRelease, error) {
+	var res []update.PendingRelease
+	err := c.Get(ctx, &res, "ListPending")
+	return res, err
+}
+
+func (c *Client) ApprovePending(ctx context.Context, id string, cause update.Cause) (job.ID, error) {
+	args := []string{"id", id, "user", cause.User}
+	if cause.Message != "" {
+		args = append(args, "message", cause.Message)
+	}
+	var res job.ID
+	return res, c.methodWithResp(ctx, "POST", &res, "ApprovePending", nil, args...)
+}
+
+func (c *Client) RejectPending(ctx context.Context, id string, cause update.Cause) error {
+	args := []string{"id", id, "user", cause.User}
+	if cause.Message != "" {
+		args = append(args, "message", cause.Message)
+	}
+	return c.Post(ctx, "RejectPending", args...)
+}
+
+func (c *Client) GitRepoConfig(ctx context.Context) (flux.GitConfig, error) {
+	var res flux.GitConfig
+	err := c.Get(ctx, &res, "GitRepoConfig")
+	return res, err
+}
+
 // --- Request helpers
 
 // post is a simple query-param only post request
@@ -58,6 +58,16 @@ func NewHandler(d remote.Platform, r *mux.Router) http.Handler {
 	r.Get("Export").HandlerFunc(handle.Export)
 	r.Get("GetPublicSSHKey").HandlerFunc(handle.GetPublicSSHKey)
 	r.Get("RegeneratePublicSSHKey").HandlerFunc(handle.RegeneratePublicSSHKey)
+	r.Get("Diff").HandlerFunc(handle.Diff)
+	r.Get("SyncOutcome").HandlerFunc(handle.SyncOutcome)
+	r.Get("ListResources").HandlerFunc(handle.ListResources)
+	r.Get("GitRepoConfig").HandlerFunc(handle.GitRepoConfig)
+	r.Get("Rollback").HandlerFunc(handle.Rollback)
+	r.Get("Suspend").HandlerFunc(handle.Suspend)
+	r.Get("Resume").HandlerFunc(handle.Resume)
+	r.Get("ListPending").HandlerFunc(handle.ListPending)
+	r.Get("ApprovePending").HandlerFunc(handle.ApprovePending)
+	r.Get("RejectPending").HandlerFunc(handle.RejectPending)
 
 	r.Get("GitPushHook").HandlerFunc(handle.GitPushHook)
 	r.Get("ImagePushHook").HandlerFunc(handle.ImagePushHook)
@@ -184,6 +194,7 @@ func (s HTTPServer) UpdateImages(w http.ResponseWriter, r *http.Request) {
 		ImageSpec:    imageSpec,
 		Kind:         releaseKind,
 		Excludes:     excludes,
+		Force:        r.FormValue("force") == "true",
 	}
 	cause := update.Cause{
 		User:    r.FormValue("user"),
@@ -218,6 +229,27 @@ func (s HTTPServer) UpdatePolicies(w http.ResponseWriter, r *http.Request) {
 	transport.JSONResponse(w, r, jobID)
 }
 
+func (s HTTPServer) Rollback(w http.ResponseWriter, r *http.Request) {
+	var spec update.RollbackSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	cause := update.Cause{
+		User:    r.FormValue("user"),
+		Message: r.FormValue("message"),
+	}
+
+	jobID, err := s.daemon.UpdateManifests(r.Context(), update.Spec{Type: update.Rollback, Cause: cause, Spec: spec})
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+
+	transport.JSONResponse(w, r, jobID)
+}
+
 func (s HTTPServer) ListServices(w http.ResponseWriter, r *http.Request) {
 	namespace := mux.Vars(r)["namespace"]
 	res, err := s.daemon.ListServices(r.Context(), namespace)
@@ -256,3 +288,109 @@ func (s HTTPServer) RegeneratePublicSSHKey(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
+
+func (s HTTPServer) Diff(w http.ResponseWriter, r *http.Request) {
+	service := mux.Vars(r)["service"]
+	spec, err := update.ParseResourceSpec(service)
+	if err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, errors.Wrapf(err, "parsing service spec %q", service))
+		return
+	}
+
+	diffs, err := s.daemon.Diff(r.Context(), spec)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, diffs)
+}
+
+func (s HTTPServer) SyncOutcome(w http.ResponseWriter, r *http.Request) {
+	outcome, err := s.daemon.SyncOutcome(r.Context())
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, outcome)
+}
+
+func (s HTTPServer) Suspend(w http.ResponseWriter, r *http.Request) {
+	var suspension flux.Suspension
+	if err := json.NewDecoder(r.Body).Decode(&suspension); err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.daemon.Suspend(r.Context(), suspension)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) Resume(w http.ResponseWriter, r *http.Request) {
+	var resume flux.Suspension
+	if err := json.NewDecoder(r.Body).Decode(&resume); err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.daemon.Resume(r.Context(), resume)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) ListPending(w http.ResponseWriter, r *http.Request) {
+	res, err := s.daemon.ListPending(r.Context())
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) ApprovePending(w http.ResponseWriter, r *http.Request) {
+	cause := update.Cause{
+		User:    r.FormValue("user"),
+		Message: r.FormValue("message"),
+	}
+	jobID, err := s.daemon.ApprovePending(r.Context(), mux.Vars(r)["id"], cause)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, jobID)
+}
+
+func (s HTTPServer) RejectPending(w http.ResponseWriter, r *http.Request) {
+	cause := update.Cause{
+		User:    r.FormValue("user"),
+		Message: r.FormValue("message"),
+	}
+	if err := s.daemon.RejectPending(r.Context(), mux.Vars(r)["id"], cause); err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s HTTPServer) ListResources(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	res, err := s.daemon.ListResources(r.Context(), namespace)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) GitRepoConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := s.daemon.GitRepoConfig(r.Context(), false)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, config)
+}
@@ -24,11 +24,14 @@ import (
 
 // Upstream handles communication from the daemon to a service
 type Upstream struct {
-	client    *http.Client
-	ua        string
-	token     flux.Token
-	url       *url.URL
-	endpoint  string
+	client   *http.Client
+	ua       string
+	token    flux.Token
+	url      *url.URL
+	endpoint string
+	// Registering with older versions of the API, newest first, to
+	// fall back to if the service doesn't know about the latest
+	fallbacks []*url.URL
 	apiClient *fluxclient.Client
 	platform  remote.Platform
 	logger    log.Logger
@@ -53,7 +56,13 @@ func NewUpstream(client *http.Client, ua string, t flux.Token, router *mux.Route
 		return nil, errors.Wrap(err, "inferring WS/HTTP endpoints")
 	}
 
-	u, err := transport.MakeURL(wsEndpoint, router, "RegisterDaemonV9")
+	u, err := transport.MakeURL(wsEndpoint, router, "RegisterDaemonV10")
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing URL")
+	}
+	// A service that predates v10 only calls the methods it knows
+	// about, all of which are still served.
+	v9, err := transport.MakeURL(wsEndpoint, router, "RegisterDaemonV9")
 	if err != nil {
 		return nil, errors.Wrap(err, "constructing URL")
 	}
@@ -64,6 +73,7 @@ func NewUpstream(client *http.Client, ua string, t flux.Token, router *mux.Route
 		token:     t,
 		url:       u,
 		endpoint:  wsEndpoint,
+		fallbacks: []*url.URL{v9},
 		apiClient: fluxclient.New(client, router, httpEndpoint, t),
 		platform:  p,
 		logger:    logger,
@@ -129,8 +139,17 @@ func (a *Upstream) connect() error {
 	a.logger.Log("connecting", true)
 	ws, err := websocket.Dial(a.client, a.ua, a.token, a.url)
 	if err != nil {
-		if err, ok := err.(*websocket.DialErr); ok && err.HTTPResponse != nil && err.HTTPResponse.StatusCode == http.StatusGone {
-			return ErrEndpointDeprecated
+		if err, ok := err.(*websocket.DialErr); ok && err.HTTPResponse != nil {
+			switch {
+			case err.HTTPResponse.StatusCode == http.StatusGone:
+				return ErrEndpointDeprecated
+			case err.HTTPResponse.StatusCode == http.StatusNotFound && len(a.fallbacks) > 0:
+				// The service is older than this daemon; register
+				// with the newest version it might know about
+				// instead, from now on
+				a.url, a.fallbacks = a.fallbacks[0], a.fallbacks[1:]
+				return errors.Wrapf(err, "falling back to %s", a.url)
+			}
 		}
 		return errors.Wrapf(err, "executing websocket %s", a.url)
 	}
@@ -1,7 +1,14 @@
 package daemon
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	transport "github.com/weaveworks/flux/http"
 )
 
 func TestEndpointInference(t *testing.T) {
@@ -37,3 +44,39 @@ func TestUnsupportedEndpoint(t *testing.T) {
 		t.Error("Expected err, got nil")
 	}
 }
+
+func TestFallbackToOlderAPI(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	router := transport.NewUpstreamRouter()
+	_, wsEndpoint, err := inferEndpoints(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v10, err := transport.MakeURL(wsEndpoint, router, "RegisterDaemonV10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v9, err := transport.MakeURL(wsEndpoint, router, "RegisterDaemonV9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Upstream{
+		client:    http.DefaultClient,
+		url:       v10,
+		endpoint:  wsEndpoint,
+		fallbacks: []*url.URL{v9},
+		logger:    log.NewNopLogger(),
+	}
+
+	if err := a.connect(); err == nil {
+		t.Fatal("expected error connecting to a service without the API")
+	}
+	assertEquals(t, v9.String(), a.url.String())
+	// Nothing older to fall back to
+	if err := a.connect(); err == nil {
+		t.Fatal("expected error connecting to a service without the API")
+	}
+	assertEquals(t, v9.String(), a.url.String())
+}
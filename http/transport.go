@@ -39,6 +39,16 @@ func NewAPIRouter() *mux.Router {
 	r.NewRoute().Name("Export").Methods("HEAD", "GET").Path("/v6/export")
 	r.NewRoute().Name("GetPublicSSHKey").Methods("GET").Path("/v6/identity.pub")
 	r.NewRoute().Name("RegeneratePublicSSHKey").Methods("POST").Path("/v6/identity.pub")
+	r.NewRoute().Name("Diff").Methods("GET").Path("/v10/diff").Queries("service", "{service}")
+	r.NewRoute().Name("SyncOutcome").Methods("GET").Path("/v10/sync-outcome")
+	r.NewRoute().Name("ListResources").Methods("GET").Path("/v10/resources").Queries("namespace", "{namespace}") // optional namespace!
+	r.NewRoute().Name("GitRepoConfig").Methods("GET").Path("/v10/git-config")
+	r.NewRoute().Name("Rollback").Methods("POST").Path("/v10/rollback")
+	r.NewRoute().Name("Suspend").Methods("POST").Path("/v10/suspend")
+	r.NewRoute().Name("Resume").Methods("POST").Path("/v10/resume")
+	r.NewRoute().Name("ListPending").Methods("GET").Path("/v10/pending")
+	r.NewRoute().Name("ApprovePending").Methods("POST").Path("/v10/pending/approve").Queries("id", "{id}")
+	r.NewRoute().Name("RejectPending").Methods("POST").Path("/v10/pending/reject").Queries("id", "{id}")
 
 	return r // TODO 404 though?
 }
@@ -48,6 +58,7 @@ func UpstreamRoutes(r *mux.Router) {
 	r.NewRoute().Name("RegisterDaemonV7").Methods("GET").Path("/v7/daemon")
 	r.NewRoute().Name("RegisterDaemonV8").Methods("GET").Path("/v8/daemon")
 	r.NewRoute().Name("RegisterDaemonV9").Methods("GET").Path("/v9/daemon")
+	r.NewRoute().Name("RegisterDaemonV10").Methods("GET").Path("/v10/daemon")
 	r.NewRoute().Name("LogEvent").Methods("POST").Path("/v6/events")
 }
 
@@ -20,6 +20,7 @@ var (
 	ErrInvalidImageID   = errors.New("invalid image ID")
 	ErrBlankImageID     = errors.Wrap(ErrInvalidImageID, "blank image name")
 	ErrMalformedImageID = errors.Wrap(ErrInvalidImageID, `expected image name as either <image>:<tag> or just <image>`)
+	ErrMalformedDigest  = errors.Wrap(ErrInvalidImageID, `expected digest as <algorithm>:<hex>, e.g., sha256:...`)
 )
 
 // Name represents an unversioned (i.e., untagged) image a.k.a.,
@@ -100,16 +101,20 @@ func (i Name) ToRef(tag string) Ref {
 
 // Ref represents a versioned (i.e., tagged) image. The tag is
 // allowed to be empty, though it is in general undefined what that
-// means. As such, `Ref` also includes all `Name` values.
+// means. As such, `Ref` also includes all `Name` values. A Ref may
+// also be pinned to the digest of the image's manifest, in which case
+// the tag is for information only.
 //
 // Examples (stringified):
 //  * alpine:3.5
 //  * library/alpine:3.5
 //  * quay.io/weaveworks/flux:1.1.0
 //  * localhost:5000/arbitrary/path/to/repo:revision-sha1
+//  * quay.io/weaveworks/flux:1.1.0@sha256:5ae1...
 type Ref struct {
 	Name
-	Tag string
+	Tag    string
+	Digest string
 }
 
 // CanonicalRef is an image ref with none of the fields left to be
@@ -120,11 +125,14 @@ type CanonicalRef struct {
 
 // String returns the Ref as a string (i.e., unparsed) without canonicalising it.
 func (i Ref) String() string {
-	var tag string
+	var tag, digest string
 	if i.Tag != "" {
 		tag = ":" + i.Tag
 	}
-	return fmt.Sprintf("%s%s", i.Name.String(), tag)
+	if i.Digest != "" {
+		digest = "@" + i.Digest
+	}
+	return fmt.Sprintf("%s%s%s", i.Name.String(), tag, digest)
 }
 
 // ParseRef parses a string representation of an image id into an
@@ -140,6 +148,15 @@ func ParseRef(s string) (Ref, error) {
 		return id, ErrMalformedImageID
 	}
 
+	// Figure out if there's a digest
+	if i := strings.Index(s, "@"); i >= 0 {
+		if !digestRegexp.MatchString(s[i+1:]) {
+			return id, ErrMalformedDigest
+		}
+		id.Digest = s[i+1:]
+		s = s[:i]
+	}
+
 	elements := strings.Split(s, "/")
 	switch len(elements) {
 	case 0: // NB strings.Split will never return []
@@ -180,6 +197,7 @@ var (
 	domainComponent = `([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
 	domain          = fmt.Sprintf(`localhost|(%s([.]%s)+)(:[0-9]+)?`, domainComponent, domainComponent)
 	domainRegexp    = regexp.MustCompile(domain)
+	digestRegexp    = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
 )
 
 // ImageID is serialized/deserialized as a string
@@ -203,8 +221,9 @@ func (i Ref) CanonicalRef() CanonicalRef {
 	name := i.CanonicalName()
 	return CanonicalRef{
 		Ref: Ref{
-			Name: name.Name,
-			Tag:  i.Tag,
+			Name:   name.Name,
+			Tag:    i.Tag,
+			Digest: i.Digest,
 		},
 	}
 }
@@ -213,11 +232,21 @@ func (i Ref) Components() (domain, repo, tag string) {
 	return i.Domain, i.Image, i.Tag
 }
 
-// WithNewTag makes a new copy of an ImageID with a new tag
+// WithNewTag makes a new copy of an ImageID with a new tag (and no
+// digest, since that belonged to the old tag)
 func (i Ref) WithNewTag(t string) Ref {
 	var img Ref
 	img = i
 	img.Tag = t
+	img.Digest = ""
+	return img
+}
+
+// WithDigest makes a new copy of an ImageID pinned to the digest
+// given, or not pinned if it's empty
+func (i Ref) WithDigest(d string) Ref {
+	img := i
+	img.Digest = d
 	return img
 }
 
@@ -52,6 +52,9 @@ func TestParseRef(t *testing.T) {
 		{"quay.io/library/alpine:latest", "quay.io", "library/alpine", "quay.io/library/alpine:latest"},
 		{"quay.io/library/alpine:mytag", "quay.io", "library/alpine", "quay.io/library/alpine:mytag"},
 		{"localhost:5000/path/to/repo/alpine:mytag", "localhost:5000", "path/to/repo/alpine", "localhost:5000/path/to/repo/alpine:mytag"},
+		// An image can be pinned to a digest, with or without a tag
+		{"alpine:mytag@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", dockerHubHost, "library/alpine", "index.docker.io/library/alpine:mytag@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		{"localhost:5000/hello@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "localhost:5000", "hello", "localhost:5000/hello@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
 	} {
 		i, err := ParseRef(x.test)
 		if err != nil {
@@ -80,6 +83,9 @@ func TestParseRefErrorCases(t *testing.T) {
 		{":tag"},
 		{"/leading/slash"},
 		{"trailing/slash/"},
+		{"alpine:mytag@"},
+		{"alpine:mytag@sha256:abc"},
+		{"alpine@mytag@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
 	} {
 		_, err := ParseRef(x.test)
 		if err == nil {
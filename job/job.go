@@ -24,12 +24,16 @@ const (
 	StatusRunning   StatusString = "running"
 	StatusFailed    StatusString = "failed"
 	StatusSucceeded StatusString = "succeeded"
+	// The job pushed a commit to a branch of its own, which is yet
+	// to be merged into the branch that's synced
+	StatusAwaitingMerge StatusString = "awaiting_merge"
 )
 
 // Status holds the possible states of a job; either,
 //  1. queued or otherwise pending
 //  2. succeeded with a job-specific result
 //  3. failed, resulting in an error and possibly a job-specific result
+//  4. awaiting merge, with a job-specific result
 type Status struct {
 	Result       event.CommitEventMetadata
 	Err          string
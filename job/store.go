@@ -0,0 +1,182 @@
+package job
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/flux/update"
+)
+
+// Store keeps jobs somewhere they survive a restart: those yet to
+// finish, with the spec needed to run them again, and the status of
+// those that have finished.
+type Store interface {
+	// Queued records a job that is yet to run.
+	Queued(ID, update.Spec) error
+	// SetStatus records the status of a job. Once it has finished,
+	// there's no need to keep its spec.
+	SetStatus(ID, Status) error
+	// Status gives the status recorded for a job, and whether
+	// there is one.
+	Status(ID) (Status, bool, error)
+	// Unfinished gives the jobs queued or running, in the order they
+	// were queued.
+	Unfinished() ([]Record, error)
+}
+
+// Record is a job as kept in a Store.
+type Record struct {
+	ID       ID           `json:"id"`
+	Spec     *update.Spec `json:"spec,omitempty"` // only until the job has finished
+	Status   Status       `json:"status"`
+	QueuedAt time.Time    `json:"queuedAt"`
+}
+
+// Finished says whether the job has run, successfully or not.
+func (r Record) Finished() bool {
+	return r.Status.StatusString != StatusQueued && r.Status.StatusString != StatusRunning
+}
+
+// FileStore keeps jobs in a file, e.g., on a persistent volume. All
+// unfinished jobs are kept, along with the most recently queued of
+// those finished.
+type FileStore struct {
+	Path string
+	// Size is the number of finished jobs to keep
+	Size int
+
+	mu sync.Mutex
+}
+
+func (s *FileStore) Queued(id ID, spec update.Spec) error {
+	return s.update(func(records []Record) []Record {
+		return append(records, Record{
+			ID:       id,
+			Spec:     &spec,
+			Status:   Status{StatusString: StatusQueued},
+			QueuedAt: time.Now().UTC(),
+		})
+	})
+}
+
+func (s *FileStore) SetStatus(id ID, status Status) error {
+	return s.update(func(records []Record) []Record {
+		i := recordIndex(records, id)
+		if i < 0 {
+			records = append(records, Record{ID: id, QueuedAt: time.Now().UTC()})
+			i = len(records) - 1
+		}
+		records[i].Status = status
+		if records[i].Finished() {
+			records[i].Spec = nil
+		}
+		return records
+	})
+}
+
+func (s *FileStore) Status(id ID) (Status, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return Status{}, false, err
+	}
+	if i := recordIndex(records, id); i >= 0 {
+		return records[i].Status, true, nil
+	}
+	return Status{}, false, nil
+}
+
+func (s *FileStore) Unfinished() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var unfinished []Record
+	for _, r := range records {
+		if !r.Finished() {
+			unfinished = append(unfinished, r)
+		}
+	}
+	return unfinished, nil
+}
+
+// update loads the records, changes them with the func given, evicts
+// the oldest finished jobs to make room, and saves them again.
+func (s *FileStore) update(fn func([]Record) []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	records = fn(records)
+
+	var finished int
+	for _, r := range records {
+		if r.Finished() {
+			finished++
+		}
+	}
+	kept := records[:0]
+	for _, r := range records {
+		if r.Finished() && finished > s.Size {
+			finished--
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return s.save(kept)
+}
+
+func (s *FileStore) load() ([]Record, error) {
+	bytes, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	if err := json.Unmarshal(bytes, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// save writes the records to a temporary file, then renames it over
+// the store's file, so it's never left half-written.
+func (s *FileStore) save(records []Record) error {
+	bytes, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+func recordIndex(records []Record, id ID) int {
+	for i := range records {
+		if records[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
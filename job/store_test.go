@@ -0,0 +1,66 @@
+package job
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flux-jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.json")
+
+	spec := update.Spec{
+		Type:  update.Rollback,
+		Cause: update.Cause{User: "test user"},
+		Spec:  update.RollbackSpec{ServiceID: flux.MustParseResourceID("default:deployment/helloworld")},
+	}
+	s := &FileStore{Path: path, Size: 1}
+	for _, id := range []ID{"job 1", "job 2", "job 3"} {
+		if err := s.Queued(id, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SetStatus("job 1", Status{StatusString: StatusSucceeded}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStatus("job 2", Status{StatusString: StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+
+	// As though restarted
+	s = &FileStore{Path: path, Size: 1}
+	status, ok, err := s.Status("job 1")
+	if err != nil || !ok || status.StatusString != StatusSucceeded {
+		t.Errorf("expected job 1 to have succeeded, got %#v, %v, %v", status, ok, err)
+	}
+	unfinished, err := s.Unfinished()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfinished) != 2 || unfinished[0].ID != "job 2" || unfinished[1].ID != "job 3" {
+		t.Fatalf("expected jobs 2 and 3 to be unfinished, got %#v", unfinished)
+	}
+	if got := unfinished[1].Spec; got == nil || got.Cause != spec.Cause || got.Spec.(update.RollbackSpec).ServiceID != spec.Spec.(update.RollbackSpec).ServiceID {
+		t.Errorf("expected spec %#v to be kept, got %#v", spec, got)
+	}
+
+	// Only the most recent finished job is kept
+	if err := s.SetStatus("job 2", Status{StatusString: StatusFailed, Err: "oops"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Status("job 1"); ok {
+		t.Error("expected job 1 to have been evicted")
+	}
+	if status, ok, _ := s.Status("job 2"); !ok || status.Err != "oops" {
+		t.Errorf("expected job 2 to have failed, got %#v", status)
+	}
+}
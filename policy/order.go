@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/weaveworks/flux/image"
+)
+
+// Ordering says which of the images matching a tag pattern is the
+// latest. Creation times are unreliable for images built
+// reproducibly, so the others go by the tag instead (or, for a regex
+// pattern with a capture group, the part of the tag it captures).
+type Ordering string
+
+const (
+	// OrderCreated picks the image created most recently; the
+	// default for glob and regex patterns.
+	OrderCreated = Ordering("created")
+	// OrderAlphabetical picks the tag that sorts last.
+	OrderAlphabetical = Ordering("alphabetical")
+	// OrderNumeric picks the tag with the highest numbers, compared
+	// one after another; e.g., 20180312.10 is after 20180312.9.
+	OrderNumeric = Ordering("numeric")
+	// OrderSemver picks the highest semantic version; the default
+	// for semver patterns.
+	OrderSemver = Ordering("semver")
+)
+
+// ParseOrdering gives the ordering named, or an error if there's no
+// such ordering.
+func ParseOrdering(s string) (Ordering, error) {
+	switch o := Ordering(s); o {
+	case OrderCreated, OrderAlphabetical, OrderNumeric, OrderSemver:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown ordering %q; expected one of %s, %s, %s or %s", s, OrderCreated, OrderAlphabetical, OrderNumeric, OrderSemver)
+}
+
+// OrderingOf gives the ordering a pattern uses to decide which image
+// is latest.
+func OrderingOf(p Pattern) Ordering {
+	switch p := p.(type) {
+	case orderedPattern:
+		return p.ordering
+	case SemverPattern:
+		return OrderSemver
+	}
+	return OrderCreated
+}
+
+// Order gives a pattern matching the same tags as that given, but
+// deciding which is latest with the ordering given.
+func Order(p Pattern, o Ordering) Pattern {
+	if op, ok := p.(orderedPattern); ok {
+		p = op.Pattern
+	}
+	return orderedPattern{Pattern: p, ordering: o}
+}
+
+type orderedPattern struct {
+	Pattern
+	ordering Ordering
+}
+
+func (o orderedPattern) Newer(a, b *image.Info) bool {
+	ka, kb := o.key(a.ID.Tag), o.key(b.ID.Tag)
+	var c int
+	switch o.ordering {
+	case OrderAlphabetical:
+		c = strings.Compare(ka, kb)
+	case OrderNumeric:
+		c = compareNumbers(ka, kb)
+	case OrderSemver:
+		va, errA := parseVersion(ka)
+		vb, errB := parseVersion(kb)
+		switch {
+		case errA != nil && errB != nil:
+		case errA != nil:
+			c = -1
+		case errB != nil:
+			c = 1
+		default:
+			c = va.compare(vb)
+		}
+	}
+	if c == 0 {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return c > 0
+}
+
+func (o orderedPattern) key(tag string) string {
+	if r, ok := o.Pattern.(RegexpPattern); ok {
+		return r.key(tag)
+	}
+	return tag
+}
+
+// compareNumbers compares the runs of digits in each string, one
+// after another, as numbers. A string with fewer numbers, having
+// matched so far, is lower.
+func compareNumbers(a, b string) int {
+	na, nb := numbers(a), numbers(b)
+	for i := 0; i < len(na) && i < len(nb); i++ {
+		switch {
+		case na[i] < nb[i]:
+			return -1
+		case na[i] > nb[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(na) < len(nb):
+		return -1
+	case len(na) > len(nb):
+		return 1
+	}
+	return 0
+}
+
+func numbers(s string) []uint64 {
+	var ns []uint64
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) {
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue // too long to be a number we care about
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
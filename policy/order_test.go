@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux/image"
+)
+
+func TestOrdering(t *testing.T) {
+	if _, err := ParseOrdering("newest"); err == nil {
+		t.Error("expected error parsing unknown ordering")
+	}
+
+	now := time.Now()
+	info := func(tag string, age time.Duration) *image.Info {
+		return &image.Info{ID: image.Ref{Tag: tag}, CreatedAt: now.Add(-age)}
+	}
+
+	for _, c := range []struct {
+		pattern  Pattern
+		ordering Ordering
+		newer    *image.Info
+		older    *image.Info
+	}{
+		{PatternAll, OrderCreated, info("b", 0), info("c", time.Hour)},
+		{PatternAll, OrderAlphabetical, info("c", time.Hour), info("b", 0)},
+		{PatternAll, OrderNumeric, info("main-20180312.10", time.Hour), info("main-20180312.9", 0)},
+		{PatternAll, OrderNumeric, info("main-20180312.1", time.Hour), info("main-20180312", 0)},
+		{PatternAll, OrderNumeric, info("1", time.Hour), info("latest", 0)},
+		{PatternAll, OrderSemver, info("v1.10.0", time.Hour), info("v1.9.0", 0)},
+		{PatternAll, OrderSemver, info("1.0.0", time.Hour), info("master", 0)},
+		// the same tag, e.g., rebuilt, goes by creation
+		{PatternAll, OrderNumeric, info("1.2", 0), info("1.2", time.Hour)},
+		// ordered by the first capture group, not the commit after
+		{NewPattern(`regex:^main-(\d+\.\d+)-`), OrderNumeric, info("main-20180312.2-aaa", time.Hour), info("main-20180312.1-fff", 0)},
+		{NewPattern(`regex:^main-(\d+\.\d+)-`), OrderAlphabetical, info("main-20180312.2-aaa", time.Hour), info("main-20180312.1-fff", 0)},
+		// a semver pattern can be ordered differently
+		{NewPattern("semver:^1"), OrderCreated, info("1.2.0", 0), info("1.3.0", time.Hour)},
+	} {
+		p := Order(c.pattern, c.ordering)
+		if OrderingOf(p) != c.ordering {
+			t.Errorf("expected ordering %s, got %s", c.ordering, OrderingOf(p))
+		}
+		if !p.Newer(c.newer, c.older) || p.Newer(c.older, c.newer) {
+			t.Errorf("%s by %s: expected %s to be later than %s", c.pattern, c.ordering, c.newer.ID.Tag, c.older.ID.Tag)
+		}
+	}
+
+	if OrderingOf(PatternAll) != OrderCreated || OrderingOf(NewPattern("semver:*")) != OrderSemver {
+		t.Error("expected patterns to have their own orderings by default")
+	}
+}
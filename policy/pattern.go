@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"regexp"
+	"strings"
+
+	glob "github.com/ryanuber/go-glob"
+
+	"github.com/weaveworks/flux/image"
+)
+
+const (
+	globPrefix   = "glob:"
+	semverPrefix = "semver:"
+	regexpPrefix = "regex:"
+)
+
+// PatternAll matches any tag (other than "latest").
+var PatternAll = NewPattern(globPrefix + "*")
+
+// Pattern is the value of a tag policy, which says which of the tags
+// of a container's image it may be updated to, and which of those is
+// the latest.
+type Pattern interface {
+	// Matches says whether the tag given is one the container may
+	// be updated to
+	Matches(tag string) bool
+	// Newer says whether the first image is later than the second
+	Newer(a, b *image.Info) bool
+	// String gives the pattern as given in a policy, with its prefix
+	String() string
+}
+
+// GlobPattern matches tags with a glob, e.g., "master-*"; the latest
+// image is that created most recently.
+type GlobPattern string
+
+// SemverPattern matches tags that are semantic versions meeting a
+// constraint, e.g., "~1.4" or ">=2.0.0 <3"; the latest image is that
+// with the highest version, whenever it was created.
+type SemverPattern struct {
+	pattern    string // without the prefix
+	constraint constraint
+}
+
+// RegexpPattern matches tags with a regular expression, e.g.,
+// "^main-(\d+)\.\d+-"; the latest image is that created most
+// recently, unless an ordering says otherwise. If the expression has
+// a capture group, orderings use the first group's match in place of
+// the whole tag.
+type RegexpPattern struct {
+	pattern string // without the prefix
+	re      *regexp.Regexp
+}
+
+// NewPattern gives the pattern for the value of a tag policy. A value
+// without a prefix is taken to be a glob. A semver constraint or
+// regular expression that can't be parsed matches nothing, so that
+// nothing is released using it; use ParsePattern to check a value
+// first.
+func NewPattern(pattern string) Pattern {
+	p, err := ParsePattern(pattern)
+	if err != nil {
+		if strings.HasPrefix(pattern, regexpPrefix) {
+			return RegexpPattern{pattern: strings.TrimPrefix(pattern, regexpPrefix)}
+		}
+		return SemverPattern{pattern: strings.TrimPrefix(pattern, semverPrefix)}
+	}
+	return p
+}
+
+// ParsePattern gives the pattern for the value of a tag policy, or an
+// error if it isn't valid.
+func ParsePattern(pattern string) (Pattern, error) {
+	switch {
+	case strings.HasPrefix(pattern, semverPrefix):
+		s := strings.TrimPrefix(pattern, semverPrefix)
+		c, err := parseConstraint(s)
+		if err != nil {
+			return nil, err
+		}
+		return SemverPattern{pattern: s, constraint: c}, nil
+	case strings.HasPrefix(pattern, regexpPrefix):
+		s := strings.TrimPrefix(pattern, regexpPrefix)
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		return RegexpPattern{pattern: s, re: re}, nil
+	default:
+		return GlobPattern(strings.TrimPrefix(pattern, globPrefix)), nil
+	}
+}
+
+func (g GlobPattern) Matches(tag string) bool {
+	// Ignore latest if and only if it's not what the user wants.
+	if !strings.EqualFold(string(g), "latest") && strings.EqualFold(tag, "latest") {
+		return false
+	}
+	return glob.Glob(string(g), tag)
+}
+
+func (g GlobPattern) Newer(a, b *image.Info) bool {
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+func (g GlobPattern) String() string {
+	return globPrefix + string(g)
+}
+
+func (s SemverPattern) Matches(tag string) bool {
+	if s.constraint.ranges == nil {
+		return false
+	}
+	v, err := parseVersion(tag)
+	if err != nil {
+		return false
+	}
+	return s.constraint.matches(v)
+}
+
+func (s SemverPattern) Newer(a, b *image.Info) bool {
+	va, errA := parseVersion(a.ID.Tag)
+	vb, errB := parseVersion(b.ID.Tag)
+	switch {
+	case errA != nil || errB != nil:
+		return errB != nil && errA == nil
+	case va.compare(vb) == 0:
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return va.compare(vb) > 0
+}
+
+func (s SemverPattern) String() string {
+	return semverPrefix + s.pattern
+}
+
+func (r RegexpPattern) Matches(tag string) bool {
+	if r.re == nil {
+		return false
+	}
+	// Ignore latest if and only if it's not what the user wants, as
+	// for globs; here, if it's not spelled out in the expression.
+	if !strings.Contains(strings.ToLower(r.pattern), "latest") && strings.EqualFold(tag, "latest") {
+		return false
+	}
+	return r.re.MatchString(tag)
+}
+
+func (r RegexpPattern) Newer(a, b *image.Info) bool {
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+func (r RegexpPattern) String() string {
+	return regexpPrefix + r.pattern
+}
+
+// key gives the part of the tag to order by: the first capture
+// group's match, if there is one, or else the whole tag.
+func (r RegexpPattern) key(tag string) string {
+	if r.re == nil || r.re.NumSubexp() == 0 {
+		return tag
+	}
+	if m := r.re.FindStringSubmatch(tag); m != nil {
+		return m[1]
+	}
+	return tag
+}
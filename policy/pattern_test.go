@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	for _, bad := range []string{
+		"semver:",
+		"semver:>=",
+		"semver:~1.x.y",
+		"semver:1.2.3.4",
+		"semver:1.2-rc.1",
+		"semver:>=1.0 ||",
+		"regex:main-(",
+	} {
+		if _, err := ParsePattern(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+
+	for value, expected := range map[string]string{
+		"master-*":     "glob:master-*",
+		"glob:1.*":     "glob:1.*",
+		"semver:~1.4":  "semver:~1.4",
+		"semver:>= 2":  "semver:>= 2",
+		"semver:^0.4":  "semver:^0.4",
+		"semver:1.2.x": "semver:1.2.x",
+		"regex:^v\\d+": "regex:^v\\d+",
+	} {
+		p, err := ParsePattern(value)
+		if err != nil {
+			t.Errorf("parsing %q: %s", value, err)
+		} else if p.String() != expected {
+			t.Errorf("parsing %q: expected %q, got %q", value, expected, p.String())
+		}
+	}
+}
+
+func TestPatternMatches(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		yes, no []string
+	}{
+		{"glob:*", []string{"1.0", "master-abc"}, []string{"latest"}},
+		{"glob:latest", []string{"latest"}, []string{"1.0"}},
+		{"regex:.*", []string{"1.0", "master-abc"}, []string{"latest"}},
+		{"regex:^[a-z]+$", []string{"stable"}, []string{"latest", "1.0"}},
+		{"regex:^(latest|stable)$", []string{"latest", "stable"}, []string{"1.0"}},
+		{"semver:~1.4", []string{"1.4.0", "v1.4.7"}, []string{"1.3.9", "1.5.0", "1.4.1-rc.1", "master-abc"}},
+		{"semver:>=2.0.0 <3", []string{"2.0.0", "2.9.9"}, []string{"1.9.9", "3.0.0"}},
+		{"semver:>=1.2, <=1.4", []string{"1.2.0", "1.4.7"}, []string{"1.1.9", "1.5.0"}},
+		{"semver:^1.2", []string{"1.2.0", "1.9.0"}, []string{"1.1.0", "2.0.0"}},
+		{"semver:^0.4", []string{"0.4.0", "0.4.9"}, []string{"0.5.0"}},
+		{"semver:1.x || 3", []string{"1.0.0", "3.2.1"}, []string{"2.0.0"}},
+		{"semver:!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"semver:>1.4", []string{"1.5.0"}, []string{"1.4.9"}},
+		{"semver:>=2.0.0-rc.1", []string{"2.0.0-rc.2", "2.0.0", "2.0.0+build.5"}, []string{"2.0.0-rc.0", "2.0.0-beta"}},
+		{"semver:>>1", nil, []string{"1.0.0", "2.0.0"}}, // invalid, so matches nothing
+		{`regex:^main-\d{8}\.\d+-`, []string{"main-20180312.1-abc123"}, []string{"main-2018.1-abc123", "feature-20180312.1-abc123"}},
+		{"regex:main-(", nil, []string{"main-("}}, // invalid, so matches nothing
+	} {
+		p := NewPattern(c.pattern)
+		for _, tag := range c.yes {
+			if !p.Matches(tag) {
+				t.Errorf("expected %s to match %q", c.pattern, tag)
+			}
+		}
+		for _, tag := range c.no {
+			if p.Matches(tag) {
+				t.Errorf("expected %s not to match %q", c.pattern, tag)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package policy
 import (
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/weaveworks/flux"
 )
@@ -14,15 +15,50 @@ const (
 	LockedMsg  = Policy("locked_msg")
 	Automated  = Policy("automated")
 	TagAll     = Policy("tag_all")
+	// LockedUntil, if given along with Locked, is when the lock
+	// expires (e.g., "2018-03-12T17:00:00Z"); fluxd unlocks the
+	// resource then.
+	LockedUntil = Policy("locked_until")
+	// SyncMark is not set by users, but by fluxd when it applies a
+	// resource, to record that the resource is managed by fluxd.
+	SyncMark = Policy("sync_mark")
+	// SyncMode can be set to SyncReport, to have fluxd report how the
+	// resource differs from the repo, rather than applying it.
+	SyncMode = Policy("sync")
+	// DeployWindow and Freeze restrict when changes to a resource are
+	// released; see Schedule for how their values are given.
+	DeployWindow = Policy("deploy_window")
+	Freeze       = Policy("freeze")
+	// VerifyRollout is how long to give a controller to roll out a
+	// release (e.g., "5m"), before rolling it back and locking it.
+	VerifyRollout = Policy("verify_rollout")
+	// AutomationMode can be set to AutomationApprove, to have
+	// automation propose releases of the (automated) resource, to be
+	// approved, rather than making them.
+	AutomationMode = Policy("automation")
+	// PromoteFrom names another controller (e.g.,
+	// "staging:deployment/api"), the images of which are released to
+	// the resource once they have been running healthily for a while;
+	// PromoteSoak, if given, says how long (e.g., "2h").
+	PromoteFrom = Policy("promote_from")
+	PromoteSoak = Policy("promote_soak")
+	// PinDigest has releases to the resource write images with the
+	// digest of their manifest (e.g., "repo:1.2@sha256:..."), so
+	// the image can't change under the tag.
+	PinDigest = Policy("pin_digest")
 )
 
+const SyncReport = "report"
+
+const AutomationApprove = "approve"
+
 // Policy is an string, denoting the current deployment policy of a service,
 // e.g. automated, or locked.
 type Policy string
 
 func Boolean(policy Policy) bool {
 	switch policy {
-	case Locked, Automated, Ignore:
+	case Locked, Automated, Ignore, PinDigest:
 		return true
 	}
 	return false
@@ -32,6 +68,12 @@ func TagPrefix(container string) Policy {
 	return Policy("tag." + container)
 }
 
+// OrderPrefix gives the policy naming the ordering (see Ordering)
+// used to pick the latest of a container's images.
+func OrderPrefix(container string) Policy {
+	return Policy("order." + container)
+}
+
 func Tag(policy Policy) bool {
 	return strings.HasPrefix(string(policy), "tag.")
 }
@@ -105,6 +147,32 @@ func (s Set) Get(p Policy) (string, bool) {
 	return v, ok
 }
 
+// LockExpiry gives when the resource's lock expires, if it's locked
+// and the lock has an expiry. An expiry that can't be parsed is
+// ignored, so that the lock stays until it's removed.
+func (s Set) LockExpiry() (time.Time, bool) {
+	if !s.Contains(Locked) {
+		return time.Time{}, false
+	}
+	value, ok := s.Get(LockedUntil)
+	if !ok {
+		return time.Time{}, false
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// LockExpired says whether the resource's lock has expired as of the
+// time given; if so, it's treated as unlocked, whether or not the
+// lock has been removed yet.
+func (s Set) LockExpired(now time.Time) bool {
+	until, ok := s.LockExpiry()
+	return ok && !now.Before(until)
+}
+
 func (s Set) ToStringMap() map[string]string {
 	m := map[string]string{}
 	for p, v := range s {
@@ -138,6 +206,18 @@ func (s ResourceMap) Without(other ResourceMap) ResourceMap {
 	return newMap
 }
 
+// OnlyLocked gives the resources that are locked as of the time
+// given; that is, those with a lock that hasn't expired.
+func (s ResourceMap) OnlyLocked(now time.Time) ResourceMap {
+	newMap := ResourceMap{}
+	for k, v := range s.OnlyWithPolicy(Locked) {
+		if !v.LockExpired(now) {
+			newMap[k] = v
+		}
+	}
+	return newMap
+}
+
 func (s ResourceMap) OnlyWithPolicy(p Policy) ResourceMap {
 	newMap := ResourceMap{}
 	for k, v := range s {
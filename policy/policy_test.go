@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
 )
 
 func TestJSON(t *testing.T) {
@@ -45,3 +48,39 @@ func TestJSON(t *testing.T) {
 		t.Errorf("Parsing equivalent list did not preserve policy. Expected:\n%#v\nGot:\n%#v\n", policy, policy2)
 	}
 }
+
+func TestLockExpiry(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) string {
+		return now.Add(d).UTC().Format(time.RFC3339)
+	}
+	locked := Set{}.Add(Locked)
+	expired := locked.Set(LockedUntil, at(-time.Minute))
+	unexpired := locked.Set(LockedUntil, at(time.Hour))
+	// an expiry that can't be parsed is ignored
+	garbled := locked.Set(LockedUntil, "tomorrow")
+	// and so is an expiry without a lock
+	unlocked := Set{}.Set(LockedUntil, at(-time.Minute))
+
+	for _, p := range []Set{locked, unexpired, garbled, unlocked} {
+		if p.LockExpired(now) {
+			t.Errorf("did not expect lock to have expired: %v", p)
+		}
+	}
+	if !expired.LockExpired(now) {
+		t.Errorf("expected lock to have expired: %v", expired)
+	}
+
+	resources := ResourceMap{
+		flux.MustParseResourceID("default:deployment/locked"):    locked,
+		flux.MustParseResourceID("default:deployment/expired"):   expired,
+		flux.MustParseResourceID("default:deployment/unexpired"): unexpired,
+		flux.MustParseResourceID("default:deployment/unlocked"):  unlocked,
+	}
+	onlyLocked := resources.OnlyLocked(now)
+	if len(onlyLocked) != 2 ||
+		!onlyLocked.Contains(flux.MustParseResourceID("default:deployment/locked")) ||
+		!onlyLocked.Contains(flux.MustParseResourceID("default:deployment/unexpired")) {
+		t.Errorf("expected only locked and unexpired, got %v", onlyLocked)
+	}
+}
@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a semantic version, as given in an image tag; e.g.,
+// "1.4.2", "v2.0.0-rc.1". Tags with fewer than three numbers, e.g.,
+// "1.4", are taken to have zeros for the missing ones.
+type version struct {
+	major, minor, patch int
+	pre                 []string
+}
+
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i] // build metadata doesn't count
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.pre = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("%q is not a semantic version", s)
+	}
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%q is not a semantic version", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// compare gives -1, 0 or 1 as the version is lower than, equal to or
+// higher than the other, following semver's rules for precedence.
+func (v version) compare(other version) int {
+	for _, d := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	// A release is higher than its pre-releases
+	switch {
+	case len(v.pre) == 0 && len(other.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(other.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.pre) && i < len(other.pre); i++ {
+		if c := comparePre(v.pre[i], other.pre[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.pre) < len(other.pre):
+		return -1
+	case len(v.pre) > len(other.pre):
+		return 1
+	}
+	return 0
+}
+
+// comparePre compares pre-release identifiers: numbers numerically,
+// and lower than anything else, which is compared as text.
+func comparePre(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// constraint is a set of ranges of versions, any of which may
+// match; e.g., "~1.4", ">=2.0.0 <3", "^1.2 || ^2". Each range is
+// one or more comparisons, all of which must match.
+type constraint struct {
+	ranges [][]comparison
+	// Only let pre-releases match if the constraint mentions one
+	allowPre bool
+}
+
+type comparison func(version) bool
+
+func parseConstraint(s string) (constraint, error) {
+	var c constraint
+	for _, r := range strings.Split(s, "||") {
+		var comparisons []comparison
+		var op string // an operator given apart from its version, e.g., ">= 1.2"
+		for _, term := range strings.FieldsFunc(r, func(c rune) bool { return c == ' ' || c == ',' }) {
+			if isOperator(term) {
+				op = term
+				continue
+			}
+			cmp, pre, err := parseComparison(op + term)
+			op = ""
+			if err != nil {
+				return c, fmt.Errorf("semver constraint %q: %s", s, err)
+			}
+			comparisons = append(comparisons, cmp)
+			c.allowPre = c.allowPre || pre
+		}
+		if op != "" {
+			return c, fmt.Errorf("semver constraint %q: %q without a version", s, op)
+		}
+		if len(comparisons) == 0 {
+			return c, fmt.Errorf("semver constraint %q: empty range", s)
+		}
+		c.ranges = append(c.ranges, comparisons)
+	}
+	return c, nil
+}
+
+func (c constraint) matches(v version) bool {
+	if len(v.pre) > 0 && !c.allowPre {
+		return false
+	}
+	for _, r := range c.ranges {
+		matched := true
+		for _, cmp := range r {
+			if !cmp(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+var operators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+func isOperator(s string) bool {
+	for _, o := range operators {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparison parses a single comparison, e.g., ">=1.2", "~1.4",
+// "1.x". A version missing numbers, or with "x" or "*" in their
+// place, stands for all versions with the numbers given; so "<=1.4"
+// includes 1.4.7, and ">1.4" starts at 1.5.0.
+func parseComparison(term string) (comparison, bool, error) {
+	var op string
+	for _, o := range operators {
+		if strings.HasPrefix(term, o) {
+			op = o
+			break
+		}
+	}
+	s := strings.TrimSpace(strings.TrimPrefix(term, op))
+	s = strings.TrimPrefix(s, "v")
+
+	var pre []string
+	if i := strings.Index(s, "-"); i >= 0 {
+		pre = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return nil, false, fmt.Errorf("%q is not a version", term)
+	}
+	var nums []int
+	for i, part := range parts {
+		if isWildcard(part) {
+			if i < len(parts)-1 && !isWildcard(parts[i+1]) {
+				return nil, false, fmt.Errorf("%q is not a version", term)
+			}
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false, fmt.Errorf("%q is not a version", term)
+		}
+		nums = append(nums, n)
+	}
+	if len(pre) > 0 && len(nums) < 3 {
+		return nil, false, fmt.Errorf("%q has a pre-release but not a full version", term)
+	}
+
+	// The versions given are those from lo (inclusive) up to hi
+	// (exclusive); or, with every number given, just lo
+	lo := version{pre: pre}
+	switch len(nums) {
+	case 3:
+		lo.patch = nums[2]
+		fallthrough
+	case 2:
+		lo.minor = nums[1]
+		fallthrough
+	case 1:
+		lo.major = nums[0]
+	}
+	exact := len(nums) == 3
+	hi := bump(lo, len(nums))
+	in := func(v version) bool {
+		if exact {
+			return v.compare(lo) == 0
+		}
+		return v.compare(lo) >= 0 && (len(nums) == 0 || v.compare(hi) < 0)
+	}
+	above := func(v version) bool {
+		if exact {
+			return v.compare(lo) > 0
+		}
+		return len(nums) > 0 && v.compare(hi) >= 0
+	}
+	below := func(v version) bool { return v.compare(lo) < 0 }
+
+	switch op {
+	case "", "=":
+		return in, len(pre) > 0, nil
+	case "!=":
+		return func(v version) bool { return !in(v) }, len(pre) > 0, nil
+	case ">":
+		return above, len(pre) > 0, nil
+	case ">=":
+		return func(v version) bool { return !below(v) }, len(pre) > 0, nil
+	case "<":
+		return below, len(pre) > 0, nil
+	case "<=":
+		return func(v version) bool { return !above(v) }, len(pre) > 0, nil
+	case "~":
+		// ~1.4.2 and ~1.4 mean 1.4.x from the version given; ~1
+		// means 1.x
+		n := len(nums)
+		if n > 2 {
+			n = 2
+		}
+		upper := bump(lo, n)
+		return func(v version) bool {
+			return v.compare(lo) >= 0 && (n == 0 || v.compare(upper) < 0)
+		}, len(pre) > 0, nil
+	case "^":
+		// ^1.4 means 1.x from 1.4; ^0.4 means 0.4.x, and ^0.0.3
+		// just 0.0.3
+		n := 1
+		switch {
+		case lo.major == 0 && lo.minor == 0 && len(nums) == 3:
+			n = 3
+		case lo.major == 0 && len(nums) >= 2:
+			n = 2
+		}
+		if len(nums) == 0 {
+			n = 0
+		}
+		upper := bump(lo, n)
+		return func(v version) bool {
+			return v.compare(lo) >= 0 && (n == 0 || v.compare(upper) < 0)
+		}, len(pre) > 0, nil
+	}
+	return nil, false, fmt.Errorf("%q is not a comparison", term)
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
+
+// bump gives the lowest version above all those starting with the
+// first n numbers of the version given; e.g., bump(1.4.2, 2) is
+// 1.5.0.
+func bump(v version, n int) version {
+	switch n {
+	case 1:
+		return version{major: v.major + 1}
+	case 2:
+		return version{major: v.major, minor: v.minor + 1}
+	case 3:
+		return version{major: v.major, minor: v.minor, patch: v.patch + 1}
+	}
+	return version{}
+}
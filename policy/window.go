@@ -0,0 +1,299 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Schedule says when changes may be released: during any of its
+// windows (or at any time, if it has none), unless during one of its
+// freezes.
+//
+// Windows are given as a comma-separated list, each of days and/or a
+// time range and an optional timezone (UTC if not given); e.g.,
+//
+//	Mon-Fri 09:00-17:00 Europe/London, Sat 10:00-12:00
+//
+// A time range that ends before it starts runs over midnight, and
+// counts as being on the day it starts. Freezes are given as a
+// comma-separated list of inclusive date ranges, each with an
+// optional timezone; e.g.,
+//
+//	2017-12-22/2018-01-02 America/New_York, 2018-03-30
+type Schedule struct {
+	Windows []Window
+	Freezes []FreezePeriod
+}
+
+// Allows says whether a change may be released at the time given.
+func (s Schedule) Allows(t time.Time) bool {
+	return s.InWindow(t) && !s.InFreeze(t)
+}
+
+// InWindow says whether the time given is during a window (or there
+// are no windows, so it's always during one).
+func (s Schedule) InWindow(t time.Time) bool {
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// InFreeze says whether the time given is during a freeze.
+func (s Schedule) InFreeze(t time.Time) bool {
+	for _, f := range s.Freezes {
+		if f.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPolicies gives the schedule for a controller with the policies
+// given: its own windows, if it has any, replace those in the
+// schedule, and its own freezes are in addition to those in the
+// schedule.
+func (s Schedule) WithPolicies(policies Set) (Schedule, error) {
+	result := Schedule{Windows: s.Windows, Freezes: s.Freezes}
+	if value, ok := policies.Get(DeployWindow); ok {
+		windows, err := ParseWindows(value)
+		if err != nil {
+			return result, err
+		}
+		result.Windows = windows
+	}
+	if value, ok := policies.Get(Freeze); ok {
+		freezes, err := ParseFreezes(value)
+		if err != nil {
+			return result, err
+		}
+		result.Freezes = append(append([]FreezePeriod{}, s.Freezes...), freezes...)
+	}
+	return result, nil
+}
+
+// Window is a recurring time, on some days of the week and/or
+// between some times of the day, during which changes may be
+// released.
+type Window struct {
+	Days     []time.Weekday // every day, if empty
+	Start    time.Duration  // since midnight
+	End      time.Duration  // since midnight; if zero, the end of the day
+	Location *time.Location
+}
+
+// Contains says whether the time given is during the window.
+func (w Window) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	end := w.End
+	if end == 0 {
+		end = 24 * time.Hour
+	}
+	switch {
+	case w.Start <= end:
+		return sinceMidnight >= w.Start && sinceMidnight < end && w.onDay(t.Weekday())
+	case sinceMidnight >= w.Start: // before midnight, in a window that runs over it
+		return w.onDay(t.Weekday())
+	case sinceMidnight < end: // after midnight; the window started the day before
+		return w.onDay((t.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+func (w Window) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// FreezePeriod is a range of dates, inclusive, during which changes must
+// not be released.
+type FreezePeriod struct {
+	From, To time.Time // the start of the first day and of the day after the last
+}
+
+// Contains says whether the time given is during the freeze.
+func (f FreezePeriod) Contains(t time.Time) bool {
+	return !t.Before(f.From) && t.Before(f.To)
+}
+
+// ParseWindows parses a comma-separated list of windows, as
+// described for Schedule.
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	for _, spec := range splitList(s) {
+		w, err := parseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseWindow(spec string) (Window, error) {
+	w := Window{Location: time.UTC}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 3 {
+		return w, fmt.Errorf("deployment window %q: expected days and/or times, and optionally a timezone", spec)
+	}
+	var gotDays, gotTimes bool
+	for i, field := range fields {
+		switch {
+		case !gotDays && !gotTimes && isDays(field):
+			days, err := parseDays(field)
+			if err != nil {
+				return w, fmt.Errorf("deployment window %q: %s", spec, err)
+			}
+			w.Days, gotDays = days, true
+		case !gotTimes && strings.Contains(field, ":"):
+			start, end, err := parseTimes(field)
+			if err != nil {
+				return w, fmt.Errorf("deployment window %q: %s", spec, err)
+			}
+			w.Start, w.End, gotTimes = start, end, true
+		case i == len(fields)-1 && (gotDays || gotTimes):
+			loc, err := time.LoadLocation(field)
+			if err != nil {
+				return w, fmt.Errorf("deployment window %q: unknown timezone %q", spec, field)
+			}
+			w.Location = loc
+		default:
+			return w, fmt.Errorf("deployment window %q: did not understand %q", spec, field)
+		}
+	}
+	return w, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func isDays(field string) bool {
+	_, ok := weekdays[strings.ToLower(strings.SplitN(field, "-", 2)[0])]
+	return ok
+}
+
+// parseDays parses a day (e.g., "Sat") or a range of days (e.g.,
+// "Mon-Fri", or "Fri-Mon" to run over the weekend).
+func parseDays(field string) ([]time.Weekday, error) {
+	parts := strings.SplitN(field, "-", 2)
+	from, ok := weekdays[strings.ToLower(parts[0])]
+	if !ok {
+		return nil, fmt.Errorf("unknown day %q", parts[0])
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, ok = weekdays[strings.ToLower(parts[1])]; !ok {
+			return nil, fmt.Errorf("unknown day %q", parts[1])
+		}
+	}
+	days := []time.Weekday{from}
+	for d := from; d != to; {
+		d = (d + 1) % 7
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// parseTimes parses a range of times of day, e.g., "09:00-17:30".
+func parseTimes(field string) (time.Duration, time.Duration, error) {
+	parts := strings.SplitN(field, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected a range of times, e.g., 09:00-17:00, but got %q", field)
+	}
+	start, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("time range %q is empty", field)
+	}
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("expected a time of day, e.g., 17:00, but got %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+const dateFormat = "2006-01-02"
+
+// ParseFreezes parses a comma-separated list of freezes, as described
+// for Schedule.
+func ParseFreezes(s string) ([]FreezePeriod, error) {
+	var freezes []FreezePeriod
+	for _, spec := range splitList(s) {
+		f, err := parseFreeze(spec)
+		if err != nil {
+			return nil, err
+		}
+		freezes = append(freezes, f)
+	}
+	return freezes, nil
+}
+
+func parseFreeze(spec string) (FreezePeriod, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return FreezePeriod{}, fmt.Errorf("freeze %q: expected dates, e.g., 2017-12-22/2018-01-02, and optionally a timezone", spec)
+	}
+	loc := time.UTC
+	if len(fields) == 2 {
+		var err error
+		if loc, err = time.LoadLocation(fields[1]); err != nil {
+			return FreezePeriod{}, fmt.Errorf("freeze %q: unknown timezone %q", spec, fields[1])
+		}
+	}
+	dates := strings.SplitN(fields[0], "/", 2)
+	from, err := time.ParseInLocation(dateFormat, dates[0], loc)
+	if err != nil {
+		return FreezePeriod{}, fmt.Errorf("freeze %q: expected a date, e.g., 2017-12-22, but got %q", spec, dates[0])
+	}
+	to := from
+	if len(dates) == 2 {
+		if to, err = time.ParseInLocation(dateFormat, dates[1], loc); err != nil {
+			return FreezePeriod{}, fmt.Errorf("freeze %q: expected a date, e.g., 2018-01-02, but got %q", spec, dates[1])
+		}
+	}
+	if to.Before(from) {
+		return FreezePeriod{}, fmt.Errorf("freeze %q: ends before it starts", spec)
+	}
+	return FreezePeriod{From: from, To: to.AddDate(0, 0, 1)}, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindows(t *testing.T) {
+	for _, bad := range []string{
+		"Someday",
+		"Mon-Fri 9-5",
+		"Mon-Funday 09:00-17:00",
+		"09:00-09:00",
+		"Mon-Fri 09:00-17:00 Atlantis/Lost",
+		"Mon-Fri 09:00-17:00 UTC extra",
+	} {
+		if _, err := ParseWindows(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+
+	windows, err := ParseWindows("Mon-Fri 09:00-17:00, Sat 22:00-02:00 UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 || len(windows[0].Days) != 5 || len(windows[1].Days) != 1 {
+		t.Fatalf("did not parse windows as expected: %#v", windows)
+	}
+
+	s := Schedule{Windows: windows}
+	for _, c := range []struct {
+		at      string
+		allowed bool
+	}{
+		{"2017-11-06T09:00:00Z", true},  // Monday morning
+		{"2017-11-06T17:00:00Z", false}, // Monday, end of the day
+		{"2017-11-04T12:00:00Z", false}, // Saturday lunchtime
+		{"2017-11-04T23:00:00Z", true},  // Saturday night
+		{"2017-11-05T01:59:00Z", true},  // still Saturday night
+		{"2017-11-05T23:00:00Z", false}, // Sunday night
+	} {
+		at, _ := time.Parse(time.RFC3339, c.at)
+		if s.Allows(at) != c.allowed {
+			t.Errorf("expected %s to be allowed: %v", c.at, c.allowed)
+		}
+	}
+}
+
+func TestParseFreezes(t *testing.T) {
+	for _, bad := range []string{
+		"tomorrow",
+		"2017-12-22/2017-12-01",
+		"2017-12-22 Atlantis/Lost",
+	} {
+		if _, err := ParseFreezes(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+
+	freezes, err := ParseFreezes("2017-12-22/2018-01-02 America/New_York, 2018-03-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Schedule{Freezes: freezes}
+	for _, c := range []struct {
+		at      string
+		allowed bool
+	}{
+		{"2017-12-22T04:59:00Z", true},  // still the 21st in New York
+		{"2017-12-22T05:00:00Z", false}, // the 22nd in New York
+		{"2018-01-03T04:59:00Z", false}, // still the 2nd in New York
+		{"2018-01-03T05:00:00Z", true},
+		{"2018-03-30T23:59:00Z", false},
+		{"2018-03-31T00:00:00Z", true},
+	} {
+		at, _ := time.Parse(time.RFC3339, c.at)
+		if s.Allows(at) != c.allowed {
+			t.Errorf("expected %s to be allowed: %v", c.at, c.allowed)
+		}
+	}
+}
+
+func TestScheduleWithPolicies(t *testing.T) {
+	defaults := Schedule{}
+	defaults.Windows, _ = ParseWindows("Mon-Fri")
+	defaults.Freezes, _ = ParseFreezes("2017-12-25")
+
+	s, err := defaults.WithPolicies(Set{}.Set(DeployWindow, "Sat-Sun").Set(Freeze, "2017-12-30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Windows) != 1 || len(s.Windows[0].Days) != 2 {
+		t.Errorf("expected the controller's window to replace the default, got %#v", s.Windows)
+	}
+	if len(s.Freezes) != 2 || len(defaults.Freezes) != 1 {
+		t.Errorf("expected the controller's freeze to be added to the default, got %#v", s.Freezes)
+	}
+
+	if _, err := defaults.WithPolicies(Set{}.Set(DeployWindow, "whenever")); err == nil {
+		t.Errorf("expected error from an invalid window")
+	}
+}
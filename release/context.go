@@ -3,29 +3,38 @@ package release
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
 	"strings"
 
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/registry"
+	"github.com/weaveworks/flux/resource"
 	"github.com/weaveworks/flux/update"
 )
 
 type ReleaseContext struct {
 	cluster   cluster.Cluster
 	manifests cluster.Manifests
-	repo      *git.Checkout
+	repos     []*git.Checkout
 	registry  registry.Registry
+	// Schedule is the default deployment schedule, for controllers
+	// without windows or freezes of their own.
+	Schedule policy.Schedule
+	// PinDigests has all images released pinned by digest.
+	PinDigests bool
 }
 
-func NewReleaseContext(c cluster.Cluster, m cluster.Manifests, reg registry.Registry, repo *git.Checkout) *ReleaseContext {
+// NewReleaseContext makes a ReleaseContext for releasing to
+// controllers defined in any of the repos given; each update is
+// written to whichever repo defines the controller.
+func NewReleaseContext(c cluster.Cluster, m cluster.Manifests, reg registry.Registry, repos ...*git.Checkout) *ReleaseContext {
 	return &ReleaseContext{
 		cluster:   c,
 		manifests: m,
-		repo:      repo,
+		repos:     repos,
 		registry:  reg,
 	}
 }
@@ -38,16 +47,26 @@ func (rc *ReleaseContext) Manifests() cluster.Manifests {
 	return rc.manifests
 }
 
+func (rc *ReleaseContext) DeploySchedule() policy.Schedule {
+	return rc.Schedule
+}
+
+func (rc *ReleaseContext) DigestPinning() bool {
+	return rc.PinDigests
+}
+
 func (rc *ReleaseContext) WriteUpdates(updates []*update.ControllerUpdate) error {
-	rc.repo.Lock()
-	defer rc.repo.Unlock()
+	for _, repo := range rc.repos {
+		repo.Lock()
+		defer repo.Unlock()
+	}
 	err := func() error {
 		for _, update := range updates {
-			fi, err := os.Stat(update.ManifestPath)
-			if err != nil {
-				return err
+			images := map[string]image.Ref{}
+			for _, c := range update.Updates {
+				images[c.Container] = c.Target
 			}
-			if err = ioutil.WriteFile(update.ManifestPath, update.ManifestBytes, fi.Mode()); err != nil {
+			if err := cluster.WriteDefinition(rc.manifests, update.ManifestPath, update.ResourceID, update.ManifestBytes, images); err != nil {
 				return err
 			}
 		}
@@ -121,19 +140,33 @@ func (rc *ReleaseContext) SelectServices(results update.Result, prefilters, post
 }
 
 func (rc *ReleaseContext) FindDefinedServices() (map[flux.ResourceID]*update.ControllerUpdate, error) {
-	rc.repo.RLock()
-	defer rc.repo.RUnlock()
-	services, err := rc.manifests.FindDefinedServices(rc.repo.ManifestDir())
+	defer rc.readLock()()
+	services, err := rc.manifests.FindDefinedServices(rc.manifestDirs()...)
 	if err != nil {
 		return nil, err
 	}
 
+	// Generated resources don't have a file of their own; the
+	// definition has to come from generating them.
+	var generated map[string]resource.Resource
+
 	var defined = map[flux.ResourceID]*update.ControllerUpdate{}
 	for id, paths := range services {
 		switch len(paths) {
 		case 1:
-			def, err := ioutil.ReadFile(paths[0])
-			if err != nil {
+			var def []byte
+			if gm, ok := rc.manifests.(cluster.GeneratedManifests); ok && gm.IsGenerated(paths[0]) {
+				if generated == nil {
+					if generated, err = gm.LoadManifests(rc.manifestDirs()...); err != nil {
+						return nil, err
+					}
+				}
+				res, ok := generated[id.String()]
+				if !ok {
+					return nil, fmt.Errorf("service %s was not generated from %s", id, paths[0])
+				}
+				def = res.Bytes()
+			} else if def, err = ioutil.ReadFile(paths[0]); err != nil {
 				return nil, err
 			}
 			defined[id] = &update.ControllerUpdate{
@@ -150,7 +183,25 @@ func (rc *ReleaseContext) FindDefinedServices() (map[flux.ResourceID]*update.Con
 
 // Shortcut for this
 func (rc *ReleaseContext) ServicesWithPolicies() (policy.ResourceMap, error) {
-	rc.repo.RLock()
-	defer rc.repo.RUnlock()
-	return rc.manifests.ServicesWithPolicies(rc.repo.ManifestDir())
+	defer rc.readLock()()
+	return rc.manifests.ServicesWithPolicies(rc.manifestDirs()...)
+}
+
+func (rc *ReleaseContext) manifestDirs() []string {
+	var dirs []string
+	for _, repo := range rc.repos {
+		dirs = append(dirs, repo.ManifestDirs()...)
+	}
+	return dirs
+}
+
+func (rc *ReleaseContext) readLock() func() {
+	for _, repo := range rc.repos {
+		repo.RLock()
+	}
+	return func() {
+		for _, repo := range rc.repos {
+			repo.RUnlock()
+		}
+	}
 }
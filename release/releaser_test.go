@@ -14,6 +14,7 @@ import (
 	"github.com/weaveworks/flux/git"
 	"github.com/weaveworks/flux/git/gittest"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
 	registryMock "github.com/weaveworks/flux/registry/mock"
 	"github.com/weaveworks/flux/update"
 )
@@ -339,7 +340,7 @@ func Test_FilterLogic(t *testing.T) {
 			cluster:   cluster,
 			manifests: mockManifests,
 			registry:  mockRegistry,
-			repo:      checkout,
+			repos:     []*git.Checkout{checkout},
 		}, tst.Spec, tst.Expected)
 	}
 }
@@ -416,13 +417,101 @@ func Test_ImageStatus(t *testing.T) {
 		ctx := &ReleaseContext{
 			cluster:   cluster,
 			manifests: mockManifests,
-			repo:      checkout,
+			repos:     []*git.Checkout{checkout},
 			registry:  upToDateRegistry,
 		}
 		testRelease(t, tst.Name, ctx, tst.Spec, tst.Expected)
 	}
 }
 
+func Test_DeploySchedule(t *testing.T) {
+	cluster := mockCluster(hwSvc, lockedSvc)
+	now := time.Now()
+	frozen := policy.Schedule{
+		Freezes: []policy.FreezePeriod{{From: now.Add(-time.Hour), To: now.Add(time.Hour)}},
+	}
+	closed := policy.Schedule{
+		Windows: []policy.Window{{Days: []time.Weekday{(now.Weekday() + 3) % 7}, Location: time.Local}},
+	}
+	spec := update.ReleaseSpec{
+		ServiceSpecs: []update.ResourceSpec{hwSvcSpec},
+		ImageSpec:    update.ImageSpecLatest,
+		Kind:         update.ReleaseKindExecute,
+		Excludes:     []flux.ResourceID{},
+	}
+	forced := spec
+	forced.Force = true
+
+	expected := func(hwResult update.ControllerResult) update.Result {
+		return update.Result{
+			flux.MustParseResourceID("default:deployment/helloworld"): hwResult,
+			flux.MustParseResourceID("default:deployment/locked-service"): update.ControllerResult{
+				Status: update.ReleaseStatusIgnored,
+				Error:  update.NotIncluded,
+			},
+			flux.MustParseResourceID("default:deployment/test-service"): update.ControllerResult{
+				Status: update.ReleaseStatusIgnored,
+				Error:  update.NotIncluded,
+			},
+		}
+	}
+	released := update.ControllerResult{
+		Status: update.ReleaseStatusSuccess,
+		PerContainer: []update.ContainerUpdate{
+			update.ContainerUpdate{
+				Container: helloContainer,
+				Current:   oldRef,
+				Target:    newHwRef,
+			},
+			update.ContainerUpdate{
+				Container: sidecarContainer,
+				Current:   sidecarRef,
+				Target:    newSidecarRef,
+			},
+		},
+	}
+
+	for _, tst := range []struct {
+		Name     string
+		Schedule policy.Schedule
+		Spec     update.ReleaseSpec
+		Expected update.Result
+	}{
+		{
+			Name:     "in change freeze",
+			Schedule: frozen,
+			Spec:     spec,
+			Expected: expected(update.ControllerResult{
+				Status: update.ReleaseStatusSkipped,
+				Error:  update.Frozen,
+			}),
+		}, {
+			Name:     "outside deployment window",
+			Schedule: closed,
+			Spec:     spec,
+			Expected: expected(update.ControllerResult{
+				Status: update.ReleaseStatusSkipped,
+				Error:  update.OutsideWindow,
+			}),
+		}, {
+			Name:     "forced, in change freeze",
+			Schedule: frozen,
+			Spec:     forced,
+			Expected: expected(released),
+		},
+	} {
+		checkout, cleanup := setup(t)
+		defer cleanup()
+		testRelease(t, tst.Name, &ReleaseContext{
+			cluster:   cluster,
+			manifests: mockManifests,
+			registry:  mockRegistry,
+			repos:     []*git.Checkout{checkout},
+			Schedule:  tst.Schedule,
+		}, tst.Spec, tst.Expected)
+	}
+}
+
 func testRelease(t *testing.T, name string, ctx *ReleaseContext, spec update.ReleaseSpec, expected update.Result) {
 	results, err := Release(ctx, spec, log.NewNopLogger())
 	if err != nil {
@@ -105,3 +105,75 @@ func (p *ErrorLoggingPlatform) GitRepoConfig(ctx context.Context, regenerate boo
 	}()
 	return p.Platform.GitRepoConfig(ctx, regenerate)
 }
+
+func (p *ErrorLoggingPlatform) Diff(ctx context.Context, spec update.ResourceSpec) (_ []flux.ResourceDiff, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "Diff", "error", err)
+		}
+	}()
+	return p.Platform.Diff(ctx, spec)
+}
+
+func (p *ErrorLoggingPlatform) SyncOutcome(ctx context.Context) (_ flux.SyncOutcome, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "SyncOutcome", "error", err)
+		}
+	}()
+	return p.Platform.SyncOutcome(ctx)
+}
+
+func (p *ErrorLoggingPlatform) ListResources(ctx context.Context, namespace string) (_ []flux.ResourceStatus, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "ListResources", "error", err)
+		}
+	}()
+	return p.Platform.ListResources(ctx, namespace)
+}
+
+func (p *ErrorLoggingPlatform) Suspend(ctx context.Context, s flux.Suspension) (_ flux.Suspension, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "Suspend", "error", err)
+		}
+	}()
+	return p.Platform.Suspend(ctx, s)
+}
+
+func (p *ErrorLoggingPlatform) Resume(ctx context.Context, s flux.Suspension) (_ flux.Suspension, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "Resume", "error", err)
+		}
+	}()
+	return p.Platform.Resume(ctx, s)
+}
+
+func (p *ErrorLoggingPlatform) ListPending(ctx context.Context) (_ []update.PendingRelease, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "ListPending", "error", err)
+		}
+	}()
+	return p.Platform.ListPending(ctx)
+}
+
+func (p *ErrorLoggingPlatform) ApprovePending(ctx context.Context, id string, cause update.Cause) (_ job.ID, err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "ApprovePending", "error", err)
+		}
+	}()
+	return p.Platform.ApprovePending(ctx, id, cause)
+}
+
+func (p *ErrorLoggingPlatform) RejectPending(ctx context.Context, id string, cause update.Cause) (err error) {
+	defer func() {
+		if err != nil {
+			p.Logger.Log("method", "RejectPending", "error", err)
+		}
+	}()
+	return p.Platform.RejectPending(ctx, id, cause)
+}
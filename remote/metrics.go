@@ -131,3 +131,83 @@ func (i *instrumentedPlatform) GitRepoConfig(ctx context.Context, regenerate boo
 	}(time.Now())
 	return i.p.GitRepoConfig(ctx, regenerate)
 }
+
+func (i *instrumentedPlatform) Diff(ctx context.Context, spec update.ResourceSpec) (_ []flux.ResourceDiff, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "Diff",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.Diff(ctx, spec)
+}
+
+func (i *instrumentedPlatform) SyncOutcome(ctx context.Context) (_ flux.SyncOutcome, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "SyncOutcome",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.SyncOutcome(ctx)
+}
+
+func (i *instrumentedPlatform) ListResources(ctx context.Context, namespace string) (_ []flux.ResourceStatus, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ListResources",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.ListResources(ctx, namespace)
+}
+
+func (i *instrumentedPlatform) Suspend(ctx context.Context, s flux.Suspension) (_ flux.Suspension, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "Suspend",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.Suspend(ctx, s)
+}
+
+func (i *instrumentedPlatform) Resume(ctx context.Context, s flux.Suspension) (_ flux.Suspension, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "Resume",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.Resume(ctx, s)
+}
+
+func (i *instrumentedPlatform) ListPending(ctx context.Context) (_ []update.PendingRelease, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ListPending",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.ListPending(ctx)
+}
+
+func (i *instrumentedPlatform) ApprovePending(ctx context.Context, id string, cause update.Cause) (_ job.ID, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ApprovePending",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.ApprovePending(ctx, id, cause)
+}
+
+func (i *instrumentedPlatform) RejectPending(ctx context.Context, id string, cause update.Cause) (err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "RejectPending",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.p.RejectPending(ctx, id, cause)
+}
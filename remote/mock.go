@@ -44,6 +44,29 @@ type MockPlatform struct {
 
 	GitRepoConfigAnswer flux.GitConfig
 	GitRepoConfigError  error
+
+	DiffAnswer []flux.ResourceDiff
+	DiffError  error
+
+	SyncOutcomeAnswer flux.SyncOutcome
+	SyncOutcomeError  error
+
+	ListResourcesAnswer []flux.ResourceStatus
+	ListResourcesError  error
+
+	SuspendAnswer flux.Suspension
+	SuspendError  error
+
+	ResumeAnswer flux.Suspension
+	ResumeError  error
+
+	ListPendingAnswer []update.PendingRelease
+	ListPendingError  error
+
+	ApprovePendingAnswer job.ID
+	ApprovePendingError  error
+
+	RejectPendingError error
 }
 
 func (p *MockPlatform) Ping(ctx context.Context) error {
@@ -91,12 +114,44 @@ func (p *MockPlatform) GitRepoConfig(ctx context.Context, regenerate bool) (flux
 	return p.GitRepoConfigAnswer, p.GitRepoConfigError
 }
 
+func (p *MockPlatform) Diff(context.Context, update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	return p.DiffAnswer, p.DiffError
+}
+
 var _ Platform = &MockPlatform{}
 
 // -- Battery of tests for a platform mechanism. Since these
 // essentially wrap the platform in various transports, we expect
 // arguments and answers to be preserved.
 
+func (p *MockPlatform) SyncOutcome(context.Context) (flux.SyncOutcome, error) {
+	return p.SyncOutcomeAnswer, p.SyncOutcomeError
+}
+
+func (p *MockPlatform) ListResources(context.Context, string) ([]flux.ResourceStatus, error) {
+	return p.ListResourcesAnswer, p.ListResourcesError
+}
+
+func (p *MockPlatform) Suspend(context.Context, flux.Suspension) (flux.Suspension, error) {
+	return p.SuspendAnswer, p.SuspendError
+}
+
+func (p *MockPlatform) Resume(context.Context, flux.Suspension) (flux.Suspension, error) {
+	return p.ResumeAnswer, p.ResumeError
+}
+
+func (p *MockPlatform) ListPending(context.Context) ([]update.PendingRelease, error) {
+	return p.ListPendingAnswer, p.ListPendingError
+}
+
+func (p *MockPlatform) ApprovePending(context.Context, string, update.Cause) (job.ID, error) {
+	return p.ApprovePendingAnswer, p.ApprovePendingError
+}
+
+func (p *MockPlatform) RejectPending(context.Context, string, update.Cause) error {
+	return p.RejectPendingError
+}
+
 func PlatformTestBattery(t *testing.T, wrap func(mock Platform) Platform) {
 	// set up
 	namespace := "the-space-of-names"
@@ -139,6 +194,45 @@ func PlatformTestBattery(t *testing.T, wrap func(mock Platform) Platform) {
 		},
 	}
 
+	diffAnswer := []flux.ResourceDiff{
+		flux.ResourceDiff{
+			ID:   flux.MustParseResourceID("foobar:deployment/hello"),
+			Type: flux.ResourceChanged,
+			Diff: "--- cluster/foobar:deployment/hello\n+++ repo/foobar:deployment/hello\n",
+		},
+	}
+
+	syncOutcomeAnswer := flux.SyncOutcome{
+		Revision: "abcdef",
+		Started:  time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		Result:   flux.SyncPartial,
+		Errors:   map[string]string{"foobar:deployment/hello": "apply failed"},
+	}
+
+	listResourcesAnswer := []flux.ResourceStatus{
+		flux.ResourceStatus{
+			ID: flux.MustParseResourceID("foobar:configmap/hello"),
+			Sync: flux.ResourceSyncStatus{
+				Revision: "abcdef",
+				Applied:  time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	suspendAnswer := flux.Suspension{
+		Sync:    true,
+		Reason:  "incident",
+		Expires: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	listPendingAnswer := []update.PendingRelease{
+		{
+			ID:         "pending-1",
+			ServiceID:  flux.MustParseResourceID("foobar:deployment/hello"),
+			ProposedAt: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
 	syncStatusAnswer := []string{
 		"commit 1",
 		"commit 2",
@@ -167,6 +261,12 @@ func PlatformTestBattery(t *testing.T, wrap func(mock Platform) Platform) {
 		UpdateManifestsArgTest: checkUpdateSpec,
 		UpdateManifestsAnswer:  job.ID(guid.New()),
 		SyncStatusAnswer:       syncStatusAnswer,
+		DiffAnswer:             diffAnswer,
+		SyncOutcomeAnswer:      syncOutcomeAnswer,
+		ListResourcesAnswer:    listResourcesAnswer,
+		SuspendAnswer:          suspendAnswer,
+		ListPendingAnswer:      listPendingAnswer,
+		ApprovePendingAnswer:   job.ID(guid.New()),
 	}
 
 	ctx := context.Background()
@@ -227,4 +327,96 @@ func PlatformTestBattery(t *testing.T, wrap func(mock Platform) Platform) {
 	if !reflect.DeepEqual(mock.SyncStatusAnswer, syncSt) {
 		t.Error(fmt.Errorf("expected: %#v\ngot: %#v"), mock.SyncStatusAnswer, syncSt)
 	}
+
+	diff, err := client.Diff(ctx, update.ResourceSpecAll)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(mock.DiffAnswer, diff) {
+		t.Error(fmt.Errorf("expected: %#v\ngot: %#v", mock.DiffAnswer, diff))
+	}
+	mock.DiffError = fmt.Errorf("diff error")
+	if _, err = client.Diff(ctx, update.ResourceSpecAll); err == nil {
+		t.Error("expected error from Diff, got nil")
+	}
+
+	outcome, err := client.SyncOutcome(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(mock.SyncOutcomeAnswer, outcome) {
+		t.Error(fmt.Errorf("expected: %#v\ngot: %#v", mock.SyncOutcomeAnswer, outcome))
+	}
+	mock.SyncOutcomeError = fmt.Errorf("sync outcome error")
+	if _, err = client.SyncOutcome(ctx); err == nil {
+		t.Error("expected error from SyncOutcome, got nil")
+	}
+
+	rs, err := client.ListResources(ctx, namespace)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(mock.ListResourcesAnswer, rs) {
+		t.Error(fmt.Errorf("expected: %#v\ngot: %#v", mock.ListResourcesAnswer, rs))
+	}
+	mock.ListResourcesError = fmt.Errorf("list resources error")
+	if _, err = client.ListResources(ctx, namespace); err == nil {
+		t.Error("expected error from ListResources, got nil")
+	}
+
+	suspension, err := client.Suspend(ctx, flux.Suspension{Sync: true, Reason: "incident"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(mock.SuspendAnswer, suspension) {
+		t.Error(fmt.Errorf("expected: %#v\ngot: %#v", mock.SuspendAnswer, suspension))
+	}
+	mock.SuspendError = fmt.Errorf("suspend error")
+	if _, err = client.Suspend(ctx, flux.Suspension{Sync: true}); err == nil {
+		t.Error("expected error from Suspend, got nil")
+	}
+
+	suspension, err = client.Resume(ctx, flux.Suspension{Sync: true})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(mock.ResumeAnswer, suspension) {
+		t.Error(fmt.Errorf("expected: %#v\ngot: %#v", mock.ResumeAnswer, suspension))
+	}
+	mock.ResumeError = fmt.Errorf("resume error")
+	if _, err = client.Resume(ctx, flux.Suspension{Sync: true}); err == nil {
+		t.Error("expected error from Resume, got nil")
+	}
+
+	pending, err := client.ListPending(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(mock.ListPendingAnswer, pending) {
+		t.Error(fmt.Errorf("expected: %#v\ngot: %#v", mock.ListPendingAnswer, pending))
+	}
+	mock.ListPendingError = fmt.Errorf("list pending error")
+	if _, err = client.ListPending(ctx); err == nil {
+		t.Error("expected error from ListPending, got nil")
+	}
+
+	approveID, err := client.ApprovePending(ctx, "pending-1", update.Cause{User: "alice"})
+	if err != nil {
+		t.Error(err)
+	}
+	if approveID != mock.ApprovePendingAnswer {
+		t.Error(fmt.Errorf("expected %q, got %q", mock.ApprovePendingAnswer, approveID))
+	}
+	mock.ApprovePendingError = fmt.Errorf("approve pending error")
+	if _, err = client.ApprovePending(ctx, "pending-1", update.Cause{User: "alice"}); err == nil {
+		t.Error("expected error from ApprovePending, got nil")
+	}
+
+	if err = client.RejectPending(ctx, "pending-1", update.Cause{User: "alice"}); err != nil {
+		t.Error(err)
+	}
+	mock.RejectPendingError = fmt.Errorf("reject pending error")
+	if err = client.RejectPending(ctx, "pending-1", update.Cause{User: "alice"}); err == nil {
+		t.Error("expected error from RejectPending, got nil")
+	}
 }
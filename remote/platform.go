@@ -75,10 +75,35 @@ type PlatformV9 interface {
 	NotifyChange(context.Context, Change) error
 }
 
+// PlatformV10 adds a way to see how the cluster differs from the
+// git repo, to suspend syncing and automation, and to approve or
+// reject automated releases.
+type PlatformV10 interface {
+	PlatformV9
+	// Diff reports the resources that differ between the cluster
+	// and the git repo.
+	Diff(context.Context, update.ResourceSpec) ([]flux.ResourceDiff, error)
+	// SyncOutcome reports how the most recent sync went.
+	SyncOutcome(context.Context) (flux.SyncOutcome, error)
+	// ListResources gives the sync status of each resource in the
+	// repo, in the namespace given (or all namespaces if empty).
+	ListResources(context.Context, string) ([]flux.ResourceStatus, error)
+	// Suspend stops the daemon syncing and/or automating, and
+	// Resume starts it again; each returns what's then suspended.
+	Suspend(context.Context, flux.Suspension) (flux.Suspension, error)
+	Resume(context.Context, flux.Suspension) (flux.Suspension, error)
+	// ListPending gives the automated releases waiting for
+	// approval; ApprovePending makes one, as a job, and
+	// RejectPending discards it.
+	ListPending(context.Context) ([]update.PendingRelease, error)
+	ApprovePending(context.Context, string, update.Cause) (job.ID, error)
+	RejectPending(context.Context, string, update.Cause) error
+}
+
 // Platform is the SPI for the daemon; i.e., it's all the things we
 // have to ask to the daemon, rather than the service.
 type Platform interface {
-	PlatformV9
+	PlatformV10
 }
 
 // Wrap errors in this to indicate that the platform should be
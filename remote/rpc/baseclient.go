@@ -55,3 +55,35 @@ func (bc baseClient) SyncStatus(context.Context, string) ([]string, error) {
 func (bc baseClient) GitRepoConfig(context.Context, bool) (flux.GitConfig, error) {
 	return flux.GitConfig{}, remote.UpgradeNeededError(errors.New("GitRepoConfig method not implemented"))
 }
+
+func (bc baseClient) Diff(context.Context, update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	return nil, remote.UpgradeNeededError(errors.New("Diff method not implemented"))
+}
+
+func (bc baseClient) SyncOutcome(context.Context) (flux.SyncOutcome, error) {
+	return flux.SyncOutcome{}, remote.UpgradeNeededError(errors.New("SyncOutcome method not implemented"))
+}
+
+func (bc baseClient) ListResources(context.Context, string) ([]flux.ResourceStatus, error) {
+	return nil, remote.UpgradeNeededError(errors.New("ListResources method not implemented"))
+}
+
+func (bc baseClient) Suspend(context.Context, flux.Suspension) (flux.Suspension, error) {
+	return flux.Suspension{}, remote.UpgradeNeededError(errors.New("Suspend method not implemented"))
+}
+
+func (bc baseClient) Resume(context.Context, flux.Suspension) (flux.Suspension, error) {
+	return flux.Suspension{}, remote.UpgradeNeededError(errors.New("Resume method not implemented"))
+}
+
+func (bc baseClient) ListPending(context.Context) ([]update.PendingRelease, error) {
+	return nil, remote.UpgradeNeededError(errors.New("ListPending method not implemented"))
+}
+
+func (bc baseClient) ApprovePending(context.Context, string, update.Cause) (job.ID, error) {
+	return "", remote.UpgradeNeededError(errors.New("ApprovePending method not implemented"))
+}
+
+func (bc baseClient) RejectPending(context.Context, string, update.Cause) error {
+	return remote.UpgradeNeededError(errors.New("RejectPending method not implemented"))
+}
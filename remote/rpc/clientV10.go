@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/remote"
+	"github.com/weaveworks/flux/update"
+)
+
+// RPCClientV10 adds Diff, to report how the cluster differs from the
+// git repo, SyncOutcome, ListResources, Suspend and Resume, and the
+// methods for pending releases.
+type RPCClientV10 struct {
+	*RPCClientV9
+}
+
+var _ remote.PlatformV10 = &RPCClientV10{}
+
+func NewClientV10(conn io.ReadWriteCloser) *RPCClientV10 {
+	return &RPCClientV10{NewClientV9(conn)}
+}
+
+func (p *RPCClientV10) Diff(ctx context.Context, spec update.ResourceSpec) ([]flux.ResourceDiff, error) {
+	var resp DiffResponse
+	err := p.client.Call("RPCServer.Diff", spec, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) SyncOutcome(ctx context.Context) (flux.SyncOutcome, error) {
+	var resp SyncOutcomeResponse
+	err := p.client.Call("RPCServer.SyncOutcome", struct{}{}, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) ListResources(ctx context.Context, namespace string) ([]flux.ResourceStatus, error) {
+	var resp ListResourcesResponse
+	err := p.client.Call("RPCServer.ListResources", namespace, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) Suspend(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	var resp SuspensionResponse
+	err := p.client.Call("RPCServer.Suspend", s, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) Resume(ctx context.Context, s flux.Suspension) (flux.Suspension, error) {
+	var resp SuspensionResponse
+	err := p.client.Call("RPCServer.Resume", s, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) ListPending(ctx context.Context) ([]update.PendingRelease, error) {
+	var resp ListPendingResponse
+	err := p.client.Call("RPCServer.ListPending", struct{}{}, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) ApprovePending(ctx context.Context, id string, cause update.Cause) (job.ID, error) {
+	var resp UpdateManifestsResponse
+	err := p.client.Call("RPCServer.ApprovePending", PendingArgs{ID: id, Cause: cause}, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
+
+func (p *RPCClientV10) RejectPending(ctx context.Context, id string, cause update.Cause) error {
+	var resp NotifyChangeResponse
+	err := p.client.Call("RPCServer.RejectPending", PendingArgs{ID: id, Cause: cause}, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return err
+}
@@ -30,6 +30,11 @@ func requireSpecKinds(s update.Spec, kinds []string) error {
 				return fmt.Errorf("Unsupported resource kind: %s", kind)
 			}
 		}
+	case update.RollbackSpec:
+		_, kind, _ := s.ServiceID.Components()
+		if !contains(kinds, kind) {
+			return fmt.Errorf("Unsupported resource kind: %s", kind)
+		}
 	case update.ReleaseSpec:
 		for _, ss := range s.ServiceSpecs {
 			if err := requireServiceSpecKinds(ss, kinds); err != nil {
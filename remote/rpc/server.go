@@ -182,3 +182,130 @@ func (p *RPCServer) GitRepoConfig(regenerate bool, resp *GitRepoConfigResponse)
 	}
 	return err
 }
+
+type DiffResponse struct {
+	Result           []flux.ResourceDiff
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) Diff(spec update.ResourceSpec, resp *DiffResponse) error {
+	v, err := p.p.Diff(context.Background(), spec)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type SyncOutcomeResponse struct {
+	Result           flux.SyncOutcome
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) SyncOutcome(_ struct{}, resp *SyncOutcomeResponse) error {
+	v, err := p.p.SyncOutcome(context.Background())
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ListResourcesResponse struct {
+	Result           []flux.ResourceStatus
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ListResources(namespace string, resp *ListResourcesResponse) error {
+	v, err := p.p.ListResources(context.Background(), namespace)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type SuspensionResponse struct {
+	Result           flux.Suspension
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) Suspend(s flux.Suspension, resp *SuspensionResponse) error {
+	v, err := p.p.Suspend(context.Background(), s)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+func (p *RPCServer) Resume(s flux.Suspension, resp *SuspensionResponse) error {
+	v, err := p.p.Resume(context.Background(), s)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ListPendingResponse struct {
+	Result           []update.PendingRelease
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ListPending(_ struct{}, resp *ListPendingResponse) error {
+	v, err := p.p.ListPending(context.Background())
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+// PendingArgs identifies a pending release to approve or reject, and
+// who is doing so.
+type PendingArgs struct {
+	ID    string
+	Cause update.Cause
+}
+
+func (p *RPCServer) ApprovePending(args PendingArgs, resp *UpdateManifestsResponse) error {
+	v, err := p.p.ApprovePending(context.Background(), args.ID, args.Cause)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+func (p *RPCServer) RejectPending(args PendingArgs, resp *NotifyChangeResponse) error {
+	err := p.p.RejectPending(context.Background(), args.ID, args.Cause)
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
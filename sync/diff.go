@@ -0,0 +1,211 @@
+package sync
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+)
+
+// Diff compares the resources defined in the repo with those running
+// in the cluster, and reports each resource that differs. Resources
+// that would be ignored by Sync are left out, as are those in the
+// cluster that weren't applied by Sync (i.e., don't carry its mark).
+func Diff(m cluster.Manifests, repoResources map[string]resource.Resource, clus cluster.Cluster, conf Config) ([]flux.ResourceDiff, error) {
+	clusterBytes, err := clus.Export()
+	if err != nil {
+		return nil, errors.Wrap(err, "exporting resource defs from cluster")
+	}
+	clusterResources, err := m.ParseManifests(clusterBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing exported resources")
+	}
+
+	var diffs []flux.ResourceDiff
+	for id, res := range repoResources {
+		if res.Policy().Contains(policy.Ignore) {
+			continue
+		}
+		cres, ok := clusterResources[id]
+		if ok && cres.Policy().Contains(policy.Ignore) {
+			continue
+		}
+		d, err := diffRepoResource(m, conf.setName(id), id, res, cres)
+		if err != nil {
+			return nil, err
+		}
+		if d.Diff != "" {
+			diffs = append(diffs, d)
+		}
+	}
+
+	for id, cres := range clusterResources {
+		if _, ok := repoResources[id]; ok {
+			continue
+		}
+		if cres.Policy().Contains(policy.Ignore) {
+			continue
+		}
+		// As with garbage collection, only what was applied from
+		// the repo counts as removed from it; the rest of the
+		// cluster (e.g., kube-system) was never in the repo.
+		if !conf.marked(id, cres) {
+			continue
+		}
+		d, err := diffResource(cres.ResourceID(), cres.Bytes(), nil)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d)
+	}
+
+	sortDiffs(diffs)
+	return diffs, nil
+}
+
+func sortDiffs(diffs []flux.ResourceDiff) {
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].ID.String() < diffs[j].ID.String()
+	})
+}
+
+// diffRepoResource diffs a resource from the repo with the same
+// resource in the cluster, which may be nil if it's not there. If
+// the resource in the cluster was marked when applied, the repo
+// resource is marked the same way, so that the mark itself doesn't
+// show up as a difference.
+func diffRepoResource(m cluster.Manifests, syncSetName, id string, res, cres resource.Resource) (flux.ResourceDiff, error) {
+	if cres == nil {
+		return diffResource(res.ResourceID(), nil, res.Bytes())
+	}
+	repoDef := res.Bytes()
+	if cres.Policy().Contains(policy.SyncMark) {
+		if marked, err := markDef(m, syncSetName, id, repoDef); err == nil {
+			repoDef = marked
+		}
+	}
+	return diffResource(res.ResourceID(), cres.Bytes(), repoDef)
+}
+
+// diffResource gives the unified diff between a resource's
+// definition in the cluster and its definition in the repo; either
+// may be nil, meaning the resource is absent there.
+func diffResource(id flux.ResourceID, clusterDef, repoDef []byte) (flux.ResourceDiff, error) {
+	d := flux.ResourceDiff{ID: id}
+
+	var clusterObj, repoObj interface{}
+	if clusterDef != nil {
+		if err := yaml.Unmarshal(clusterDef, &clusterObj); err != nil {
+			return d, errors.Wrapf(err, "parsing cluster definition of %s", id)
+		}
+		clusterObj = withoutRuntimeFields(clusterObj)
+	}
+	if repoDef != nil {
+		if err := yaml.Unmarshal(repoDef, &repoObj); err != nil {
+			return d, errors.Wrapf(err, "parsing repo definition of %s", id)
+		}
+	}
+
+	switch {
+	case clusterDef == nil:
+		d.Type = flux.ResourceAdded
+	case repoDef == nil:
+		d.Type = flux.ResourceRemoved
+	default:
+		d.Type = flux.ResourceChanged
+		// The cluster fills in lots of defaults; only compare the
+		// fields that are given in the repo.
+		clusterObj = prune(clusterObj, repoObj)
+		// The API version the cluster reports is just the one used
+		// when exporting, so it's not a real difference.
+		c, cok := clusterObj.(map[interface{}]interface{})
+		r, rok := repoObj.(map[interface{}]interface{})
+		if cok && rok {
+			if v, ok := r["apiVersion"]; ok {
+				c["apiVersion"] = v
+			}
+		}
+	}
+
+	clusterYAML, err := render(clusterObj)
+	if err != nil {
+		return d, err
+	}
+	repoYAML, err := render(repoObj)
+	if err != nil {
+		return d, err
+	}
+	if clusterYAML == repoYAML {
+		return d, nil
+	}
+
+	d.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(clusterYAML),
+		B:        difflib.SplitLines(repoYAML),
+		FromFile: "cluster/" + id.String(),
+		ToFile:   "repo/" + id.String(),
+		Context:  3,
+	})
+	return d, err
+}
+
+func render(obj interface{}) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	bytes, err := yaml.Marshal(obj)
+	return string(bytes), err
+}
+
+// withoutRuntimeFields removes the fields that are maintained by the
+// cluster, and would never appear in a repo.
+func withoutRuntimeFields(obj interface{}) interface{} {
+	m, ok := obj.(map[interface{}]interface{})
+	if !ok {
+		return obj
+	}
+	delete(m, "status")
+	if meta, ok := m["metadata"].(map[interface{}]interface{}); ok {
+		for _, field := range []string{"creationTimestamp", "generation", "resourceVersion", "selfLink", "uid"} {
+			delete(meta, field)
+		}
+	}
+	return m
+}
+
+// prune returns the cluster object with only those fields that also
+// appear in the repo object.
+func prune(clusterObj, repoObj interface{}) interface{} {
+	switch r := repoObj.(type) {
+	case map[interface{}]interface{}:
+		c, ok := clusterObj.(map[interface{}]interface{})
+		if !ok {
+			return clusterObj
+		}
+		pruned := map[interface{}]interface{}{}
+		for k, v := range r {
+			if cv, ok := c[k]; ok {
+				pruned[k] = prune(cv, v)
+			}
+		}
+		return pruned
+	case []interface{}:
+		c, ok := clusterObj.([]interface{})
+		if !ok || len(c) != len(r) {
+			return clusterObj
+		}
+		pruned := make([]interface{}, len(c))
+		for i := range c {
+			pruned[i] = prune(c[i], r[i])
+		}
+		return pruned
+	default:
+		return clusterObj
+	}
+}
@@ -0,0 +1,133 @@
+package sync
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/cluster/kubernetes"
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+func TestDiff(t *testing.T) {
+	checkout, cleanup := setup(t)
+	defer cleanup()
+
+	manifests := &kubernetes.Manifests{}
+	syncClus := &syncCluster{&cluster.Mock{}, map[string][]byte{}}
+	// Resources are only marked, and so only recognised as removed
+	// from the repo, when garbage collecting
+	conf := Config{SyncSetName: gitconf.SyncTag, GC: true}
+
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sync(manifests, resources, syncClus, conf, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := Diff(manifests, resources, syncClus, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("expected no differences after syncing, got %+v", diffs)
+	}
+
+	// Something in the cluster that was never in the repo, so
+	// doesn't count as removed from it
+	syncClus.resources[unmanagedID] = []byte(unmanagedDef)
+
+	// Something in the repo that isn't in the cluster, something
+	// that has changed, and something applied from the repo but
+	// since removed from it
+	var removedFromCluster, changedInCluster, removedFromRepo string
+	for id := range resources {
+		switch {
+		case removedFromCluster == "":
+			removedFromCluster = id
+			delete(syncClus.resources, id)
+		case changedInCluster == "":
+			changedInCluster = id
+			syncClus.resources[id] = []byte(strings.Replace(string(syncClus.resources[id]), "image: ", "image: changed/", 1))
+		case removedFromRepo == "":
+			removedFromRepo = id
+			delete(resources, id)
+		}
+	}
+
+	diffs, err = Diff(manifests, resources, syncClus, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]flux.ResourceDiffType{
+		removedFromCluster: flux.ResourceAdded,
+		changedInCluster:   flux.ResourceChanged,
+		removedFromRepo:    flux.ResourceRemoved,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d differences, got %+v", len(expected), diffs)
+	}
+	for _, d := range diffs {
+		if expected[d.ID.String()] != d.Type {
+			t.Errorf("expected %s to be %q, got %q", d.ID, expected[d.ID.String()], d.Type)
+		}
+		if d.Diff == "" {
+			t.Errorf("expected a diff for %s", d.ID)
+		}
+		if d.Type == flux.ResourceChanged && !strings.Contains(d.Diff, "image: changed/") {
+			t.Errorf("expected the changed image in the diff for %s, got:\n%s", d.ID, d.Diff)
+		}
+	}
+}
+
+func TestDiffIgnoresClusterFields(t *testing.T) {
+	checkout, cleanup := setup(t)
+	defer cleanup()
+
+	manifests := &kubernetes.Manifests{}
+	syncClus := &syncCluster{&cluster.Mock{}, map[string][]byte{}}
+
+	var file string
+	for f := range testfiles.Files {
+		file = f
+		break
+	}
+	if err := ioutil.WriteFile(filepath.Join(checkout.Dir, file), []byte(unmanagedDef), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := range resources {
+		if id != unmanagedID {
+			delete(resources, id)
+		}
+	}
+
+	// As it would come back from the cluster: with status, defaults
+	// filled in, and a different API version.
+	marked, err := markDef(manifests, gitconf.SyncTag, unmanagedID, []byte(unmanagedDef))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := strings.Replace(string(marked), "extensions/v1beta1", "apps/v1", 1)
+	exported = strings.Replace(exported, "spec:\n  template:", "spec:\n  replicas: 1\n  template:", 1)
+	exported += "status:\n  replicas: 1\n"
+	syncClus.resources[unmanagedID] = []byte(exported)
+
+	diffs, err := Diff(manifests, resources, syncClus, Config{SyncSetName: gitconf.SyncTag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, got %+v", diffs)
+	}
+}
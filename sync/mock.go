@@ -54,3 +54,38 @@ func mockResourceWithIgnorePolicy(kind, namespace, name string) rscIgnorePolicy
 	ri.Meta.Name = name
 	return ri
 }
+
+type rscSyncMark struct {
+	rsc
+	mark string
+}
+
+func (rm rscSyncMark) Policy() policy.Set {
+	p := policy.Set{}
+	p[policy.SyncMark] = rm.mark
+	return p
+}
+
+func mockResourceWithSyncMark(kind, namespace, name, mark string) rscSyncMark {
+	rm := rscSyncMark{rsc: rsc{Kind: kind}, mark: mark}
+	rm.Meta.Namespace = namespace
+	rm.Meta.Name = name
+	return rm
+}
+
+type rscReport struct {
+	rsc
+}
+
+func (rr rscReport) Policy() policy.Set {
+	p := policy.Set{}
+	p[policy.SyncMode] = policy.SyncReport
+	return p
+}
+
+func mockResourceWithReportPolicy(kind, namespace, name string) rscReport {
+	rr := rscReport{rsc{Kind: kind}}
+	rr.Meta.Namespace = namespace
+	rr.Meta.Name = name
+	return rr
+}
@@ -1,51 +1,133 @@
 package sync
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
 
+	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/resource"
 )
 
+// Config says how to mark the resources applied in a sync, and what
+// to do about resources that have been removed from the repo.
+type Config struct {
+	// SyncSetName identifies the resources this fluxd is responsible
+	// for (e.g., by using the git label); when garbage collecting
+	// (or pretending to), everything applied is marked with a value
+	// derived from it.
+	SyncSetName string
+	// SyncSets, if given, puts some of the resources in sync sets
+	// other than SyncSetName: it gives the IDs of the resources in
+	// each, by set name (e.g., a set for each git repo after the
+	// first). Resources marked as belonging to any of the sets are
+	// candidates for garbage collection.
+	SyncSets map[string][]string
+	// GC, when set, deletes resources from the cluster that carry
+	// the mark for SyncSetName (or any of SyncSets) but no longer
+	// appear in the repo.
+	GC bool
+	// GCDryRun logs what would be garbage collected, rather than
+	// deleting it.
+	GCDryRun bool
+	// ReportOnly, when set, means nothing is applied or deleted;
+	// every resource is treated as though it had the policy
+	// `sync: report`.
+	ReportOnly bool
+}
+
+// Result gives the resources that were not synced because they are
+// in report mode, and how those resources differ from the repo.
+type Result struct {
+	Reported []string
+	Drift    []flux.ResourceDiff
+}
+
 // Sync synchronises the cluster to the files in a directory
-func Sync(m cluster.Manifests, repoResources map[string]resource.Resource, clus cluster.Cluster, deletes bool, logger log.Logger) error {
+func Sync(m cluster.Manifests, repoResources map[string]resource.Resource, clus cluster.Cluster, conf Config, logger log.Logger) (Result, error) {
 	// Get a map of resources defined in the cluster
 	clusterBytes, err := clus.Export()
 
 	if err != nil {
-		return errors.Wrap(err, "exporting resource defs from cluster")
+		return Result{}, errors.Wrap(err, "exporting resource defs from cluster")
 	}
 	clusterResources, err := m.ParseManifests(clusterBytes)
 	if err != nil {
-		return errors.Wrap(err, "parsing exported resources")
+		return Result{}, errors.Wrap(err, "parsing exported resources")
 	}
 
-	// Everything that's in the cluster but not in the repo, delete;
-	// everything that's in the repo, apply. This is an approximation
-	// to figuring out what's changed, and applying that. We're
-	// relying on Kubernetes to decide for each application if it is a
-	// no-op.
+	// Everything that's in the cluster, marked as having been applied
+	// by us, but not in the repo, delete; everything that's in the
+	// repo, apply. This is an approximation to figuring out what's
+	// changed, and applying that. We're relying on Kubernetes to
+	// decide for each application if it is a no-op.
 	sync := cluster.SyncDef{}
+	// Resources in report mode, by ID; nil for those that are only
+	// in the cluster
+	reported := map[string]resource.Resource{}
 
-	// DANGER ZONE (tamara) This works and is dangerous. At the moment will delete Flux and
-	// other pods unless the relevant manifests are part of the user repo. Needs a lot of thought
-	// before this cleanup cluster feature can be unleashed on the world.
-	if deletes {
+	// Only resources that carry our mark are candidates for
+	// deletion. Anything else in the cluster -- including fluxd
+	// itself, unless it's in the repo -- is left alone.
+	if conf.GC || conf.GCDryRun {
 		for id, res := range clusterResources {
-			prepareSyncDelete(logger, repoResources, id, res, &sync)
+			prepareSyncDelete(logger, conf, repoResources, id, res, &sync, reported)
 		}
 	}
 
 	for id, res := range repoResources {
-		prepareSyncApply(logger, clusterResources, id, res, &sync)
+		prepareSyncApply(logger, conf, clusterResources, id, res, &sync, reported)
 	}
 
-	return clus.Sync(sync)
+	result, err := reportDrift(m, conf, clusterResources, reported)
+	if err != nil {
+		return result, err
+	}
+
+	// The mark is only needed to know what to garbage collect; don't
+	// annotate everything for those who haven't asked for that.
+	if conf.GC || conf.GCDryRun {
+		markApplied(logger, m, conf, &sync)
+	}
+
+	if err := clus.Sync(sync); err != nil {
+		if errs, ok := err.(cluster.SyncError); ok {
+			return result, &Failure{Errors: errs, Attempted: len(sync.Actions)}
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// Failure is returned from Sync when some of the resources could not
+// be synced.
+type Failure struct {
+	// Errors gives the error for each resource that failed, by ID
+	Errors cluster.SyncError
+	// Attempted is the number of resources Sync tried to apply or
+	// delete
+	Attempted int
+}
+
+func (f *Failure) Error() string {
+	return f.Errors.Error()
+}
+
+// Total says whether most or all of the resources failed, in which
+// case the sync as a whole is considered to have failed. Otherwise
+// it's a partial failure, and the sync can be regarded as done.
+func (f *Failure) Total() bool {
+	return len(f.Errors)*2 > f.Attempted
 }
 
-func prepareSyncDelete(logger log.Logger, repoResources map[string]resource.Resource, id string, res resource.Resource, sync *cluster.SyncDef) {
+func prepareSyncDelete(logger log.Logger, conf Config, repoResources map[string]resource.Resource, id string, res resource.Resource, sync *cluster.SyncDef, reported map[string]resource.Resource) {
 	if len(repoResources) == 0 {
 		return
 	}
@@ -53,27 +135,161 @@ func prepareSyncDelete(logger log.Logger, repoResources map[string]resource.Reso
 		logger.Log("resource", res.ResourceID(), "ignore", "delete")
 		return
 	}
-	if _, ok := repoResources[id]; !ok {
-		sync.Actions = append(sync.Actions, cluster.SyncAction{
-			ResourceID: id,
-			Delete:     res.Bytes(),
-		})
+	if _, ok := repoResources[id]; ok {
+		return
 	}
+	if !conf.marked(id, res) {
+		return
+	}
+	if reportOnly(conf, res) {
+		logger.Log("resource", res.ResourceID(), "report", "delete")
+		reported[id] = nil
+		return
+	}
+	if conf.GCDryRun {
+		logger.Log("resource", res.ResourceID(), "dry-run", "delete")
+		return
+	}
+	sync.Actions = append(sync.Actions, cluster.SyncAction{
+		ResourceID: id,
+		Delete:     res.Bytes(),
+	})
 }
 
-func prepareSyncApply(logger log.Logger, clusterResources map[string]resource.Resource, id string, res resource.Resource, sync *cluster.SyncDef) {
+func prepareSyncApply(logger log.Logger, conf Config, clusterResources map[string]resource.Resource, id string, res resource.Resource, sync *cluster.SyncDef, reported map[string]resource.Resource) {
 	if res.Policy().Contains(policy.Ignore) {
 		logger.Log("resource", res.ResourceID(), "ignore", "apply")
 		return
 	}
-	if cres, ok := clusterResources[id]; ok {
-		if cres.Policy().Contains(policy.Ignore) {
-			logger.Log("resource", res.ResourceID(), "ignore", "apply")
-			return
-		}
+	cres, inCluster := clusterResources[id]
+	if inCluster && cres.Policy().Contains(policy.Ignore) {
+		logger.Log("resource", res.ResourceID(), "ignore", "apply")
+		return
+	}
+	// The policy may be set in the cluster, e.g., by someone making
+	// changes by hand, as well as in the repo.
+	if reportOnly(conf, res) || (inCluster && reportOnly(conf, cres)) {
+		logger.Log("resource", res.ResourceID(), "report", "apply")
+		reported[id] = res
+		return
 	}
 	sync.Actions = append(sync.Actions, cluster.SyncAction{
 		ResourceID: id,
 		Apply:      res.Bytes(),
 	})
 }
+
+func reportOnly(conf Config, res resource.Resource) bool {
+	if conf.ReportOnly {
+		return true
+	}
+	mode, _ := res.Policy().Get(policy.SyncMode)
+	return mode == policy.SyncReport
+}
+
+// reportDrift works out how the resources in report mode differ
+// between the repo and the cluster.
+func reportDrift(m cluster.Manifests, conf Config, clusterResources, reported map[string]resource.Resource) (Result, error) {
+	var result Result
+	for id, res := range reported {
+		result.Reported = append(result.Reported, id)
+		cres := clusterResources[id]
+		var (
+			d   flux.ResourceDiff
+			err error
+		)
+		if res == nil {
+			d, err = diffResource(cres.ResourceID(), cres.Bytes(), nil)
+		} else {
+			d, err = diffRepoResource(m, conf.setName(id), id, res, cres)
+		}
+		if err != nil {
+			return result, err
+		}
+		if d.Diff != "" {
+			result.Drift = append(result.Drift, d)
+		}
+	}
+	sort.Strings(result.Reported)
+	sortDiffs(result.Drift)
+	return result, nil
+}
+
+// markApplied stamps each resource to be applied with the mark for
+// the sync set, so that it can be recognised as ours (and garbage
+// collected) later. A resource that can't be marked is still
+// applied; it just won't ever be a candidate for deletion.
+func markApplied(logger log.Logger, m cluster.Manifests, conf Config, sync *cluster.SyncDef) {
+	for i, action := range sync.Actions {
+		if action.Apply == nil {
+			continue
+		}
+		// The mark would go on the list, rather than the items in
+		// it, which are what end up in the cluster.
+		if isList(action.Apply) {
+			logger.Log("resource", action.ResourceID, "info", "not marking a list, so its items won't be garbage collected")
+			continue
+		}
+		marked, err := markDef(m, conf.setName(action.ResourceID), action.ResourceID, action.Apply)
+		if err != nil {
+			logger.Log("resource", action.ResourceID, "err", errors.Wrap(err, "marking resource"))
+			continue
+		}
+		sync.Actions[i].Apply = marked
+	}
+}
+
+// isList says whether a definition is a list of resources (e.g.,
+// `kind: List`), rather than a resource itself.
+func isList(def []byte) bool {
+	var obj struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := yaml.Unmarshal(def, &obj); err != nil {
+		return false
+	}
+	return strings.HasSuffix(obj.Kind, "List")
+}
+
+func markDef(m cluster.Manifests, syncSetName, id string, def []byte) ([]byte, error) {
+	return m.UpdatePolicies(def, policy.Update{
+		Add: policy.Set{policy.SyncMark: syncMark(syncSetName, id)},
+	})
+}
+
+// setName gives the name of the sync set a resource belongs to.
+func (c Config) setName(id string) string {
+	for name, ids := range c.SyncSets {
+		for _, i := range ids {
+			if i == id {
+				return name
+			}
+		}
+	}
+	return c.SyncSetName
+}
+
+// marked says whether a resource carries the mark of any of the sync
+// sets, i.e., whether it was applied by us.
+func (c Config) marked(id string, res resource.Resource) bool {
+	mark, ok := res.Policy().Get(policy.SyncMark)
+	if !ok {
+		return false
+	}
+	if mark == syncMark(c.SyncSetName, id) {
+		return true
+	}
+	for name := range c.SyncSets {
+		if mark == syncMark(name, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncMark makes the value used to mark a resource as belonging to a
+// sync set. It includes the resource ID so that the mark can't be
+// copied from one resource to another and have the same meaning.
+func syncMark(syncSetName, id string) string {
+	return fmt.Sprintf("sha256.%x", sha256.Sum256([]byte(syncSetName+"/"+id)))
+}
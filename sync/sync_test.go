@@ -3,6 +3,7 @@ package sync
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,14 +13,15 @@ import (
 
 	"github.com/go-kit/kit/log"
 
-	//	"github.com/weaveworks/flux"
 	"context"
 
+	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/cluster/kubernetes"
 	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
 	"github.com/weaveworks/flux/git"
 	"github.com/weaveworks/flux/git/gittest"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/resource"
 )
 
@@ -33,20 +35,26 @@ func TestSync(t *testing.T) {
 	// Start with nothing running. We should be told to apply all the things.
 	mockCluster := &cluster.Mock{}
 	manifests := &kubernetes.Manifests{}
-	var clus cluster.Cluster = &syncCluster{mockCluster, map[string][]byte{}}
+	syncClus := &syncCluster{mockCluster, map[string][]byte{}}
+	var clus cluster.Cluster = syncClus
+	conf := Config{SyncSetName: gitconf.SyncTag, GC: true}
 
-	resources, err := manifests.LoadManifests(checkout.ManifestDir())
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if err := Sync(manifests, resources, clus, true, log.NewNopLogger()); err != nil {
+	if _, err := Sync(manifests, resources, clus, conf, log.NewNopLogger()); err != nil {
 		t.Fatal(err)
 	}
-	checkClusterMatchesFiles(t, manifests, clus, checkout.ManifestDir())
+	checkClusterMatchesFiles(t, manifests, clus, checkout.ManifestDirs())
+
+	// Something created in the cluster by other means should be left
+	// alone, since it's not marked as having been applied by us.
+	syncClus.resources[unmanagedID] = []byte(unmanagedDef)
 
 	for file := range testfiles.Files {
-		if err := execCommand("rm", filepath.Join(checkout.ManifestDir(), file)); err != nil {
+		if err := execCommand("rm", filepath.Join(checkout.Dir, file)); err != nil {
 			t.Fatal(err)
 		}
 		commitAction := &git.CommitAction{Author: "", Message: "deleted " + file}
@@ -56,14 +64,186 @@ func TestSync(t *testing.T) {
 		break
 	}
 
-	resources, err = manifests.LoadManifests(checkout.ManifestDir())
+	resources, err = manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sync(manifests, resources, clus, conf, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := syncClus.resources[unmanagedID]; !ok {
+		t.Errorf("unmarked resource %s was deleted", unmanagedID)
+	}
+	delete(syncClus.resources, unmanagedID)
+	checkClusterMatchesFiles(t, manifests, clus, checkout.ManifestDirs())
+}
+
+// Without garbage collection, there's no need to mark what's applied
+func TestSync_NoMarkWithoutGC(t *testing.T) {
+	checkout, cleanup := setup(t)
+	defer cleanup()
+
+	manifests := &kubernetes.Manifests{}
+	syncClus := &syncCluster{&cluster.Mock{}, map[string][]byte{}}
+	conf := Config{SyncSetName: gitconf.SyncTag}
+
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := Sync(manifests, resources, clus, true, log.NewNopLogger()); err != nil {
+	if _, err := Sync(manifests, resources, syncClus, conf, log.NewNopLogger()); err != nil {
 		t.Fatal(err)
 	}
-	checkClusterMatchesFiles(t, manifests, clus, checkout.ManifestDir())
+	if !reflect.DeepEqual(resourcesToStrings(resources), bytesToStrings(syncClus.resources)) {
+		t.Errorf("expected resources to be applied as they are in the repo, got:\n%#v", bytesToStrings(syncClus.resources))
+	}
+
+	diffs, err := Diff(manifests, resources, syncClus, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences after syncing, got %+v", diffs)
+	}
+}
+
+// Each resource in a file with several is marked; a list isn't, since
+// the mark would go on the list rather than its items.
+func TestSync_MarkMultidocAndList(t *testing.T) {
+	checkout, cleanup := setup(t)
+	defer cleanup()
+
+	manifests := &kubernetes.Manifests{}
+	syncClus := &syncCluster{&cluster.Mock{}, map[string][]byte{}}
+	conf := Config{SyncSetName: gitconf.SyncTag, GC: true}
+
+	if err := ioutil.WriteFile(filepath.Join(checkout.Dir, "multi.yaml"), []byte(multidocDef), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(checkout.Dir, "list.yaml"), []byte(listDef), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listID string
+	for id, res := range resources {
+		if isList(res.Bytes()) {
+			listID = id
+		}
+	}
+	if listID == "" {
+		t.Fatal("expected to load the list")
+	}
+
+	var logged bytes.Buffer
+	if _, err := Sync(manifests, resources, syncClus, conf, log.NewLogfmtLogger(&logged)); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"default:configmap/multi-a", "default:configmap/multi-b"} {
+		res, err := manifests.ParseManifests(syncClus.resources[id])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mark, _ := res[id].Policy().Get(policy.SyncMark); mark != syncMark(conf.SyncSetName, id) {
+			t.Errorf("expected %s to be marked, got %q", id, mark)
+		}
+	}
+	if string(syncClus.resources[listID]) != listDef {
+		t.Errorf("expected the list to be applied unmarked, got:\n%s", syncClus.resources[listID])
+	}
+	if !strings.Contains(logged.String(), "not marking a list") {
+		t.Errorf("expected a log line about the list, got:\n%s", logged.String())
+	}
+}
+
+func TestSync_GCDryRun(t *testing.T) {
+	checkout, cleanup := setup(t)
+	defer cleanup()
+
+	mockCluster := &cluster.Mock{}
+	manifests := &kubernetes.Manifests{}
+	syncClus := &syncCluster{mockCluster, map[string][]byte{}}
+	conf := Config{SyncSetName: gitconf.SyncTag, GCDryRun: true}
+
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sync(manifests, resources, syncClus, conf, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	before := len(syncClus.resources)
+
+	for file := range testfiles.Files {
+		if err := execCommand("rm", filepath.Join(checkout.Dir, file)); err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	resources, err = manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logged bytes.Buffer
+	if _, err := Sync(manifests, resources, syncClus, conf, log.NewLogfmtLogger(&logged)); err != nil {
+		t.Fatal(err)
+	}
+	if len(syncClus.resources) != before {
+		t.Errorf("expected no resources to be deleted in a dry run; had %d, now have %d", before, len(syncClus.resources))
+	}
+	if !strings.Contains(logged.String(), "dry-run=delete") {
+		t.Errorf("expected dry run deletion to be logged, got:\n%s", logged.String())
+	}
+}
+
+func TestSync_ReportOnly(t *testing.T) {
+	checkout, cleanup := setup(t)
+	defer cleanup()
+
+	mockCluster := &cluster.Mock{}
+	manifests := &kubernetes.Manifests{}
+	syncClus := &syncCluster{mockCluster, map[string][]byte{}}
+	conf := Config{SyncSetName: gitconf.SyncTag, GC: true, ReportOnly: true}
+
+	resources, err := manifests.LoadManifests(checkout.ManifestDirs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Sync(manifests, resources, syncClus, conf, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syncClus.resources) != 0 {
+		t.Errorf("expected nothing to be applied, got %d resources", len(syncClus.resources))
+	}
+	if len(result.Reported) != len(resources) {
+		t.Errorf("expected all %d resources to be reported, got %v", len(resources), result.Reported)
+	}
+	if len(result.Drift) != len(resources) {
+		t.Fatalf("expected all %d resources to have drifted, got %d", len(resources), len(result.Drift))
+	}
+	for _, d := range result.Drift {
+		if d.Type != flux.ResourceAdded {
+			t.Errorf("expected %s to be missing from the cluster, got %q", d.ID, d.Type)
+		}
+	}
+
+	// Once the cluster matches, there's no drift to report
+	conf.ReportOnly = false
+	if _, err := Sync(manifests, resources, syncClus, conf, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	conf.ReportOnly = true
+	result, err = Sync(manifests, resources, syncClus, conf, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Drift) != 0 {
+		t.Errorf("expected no drift, got %+v", result.Drift)
+	}
 }
 
 func TestPrepareSyncDelete(t *testing.T) {
@@ -72,6 +252,8 @@ func TestPrepareSyncDelete(t *testing.T) {
 		repoRes  map[string]resource.Resource
 		id       string
 		res      resource.Resource
+		dryRun   bool
+		report   bool
 		expected *cluster.SyncDef
 	}{
 		{
@@ -96,7 +278,7 @@ func TestPrepareSyncDelete(t *testing.T) {
 			expected: &cluster.SyncDef{},
 		},
 		{
-			msg: "No policy to ignore during sync delete",
+			msg: "No policy to ignore during sync delete, but not marked as ours",
 			repoRes: map[string]resource.Resource{
 				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
 				"res2": mockResourceWithoutIgnorePolicy("namespace", "ns2", "ns2"),
@@ -107,18 +289,85 @@ func TestPrepareSyncDelete(t *testing.T) {
 			},
 			id:       "res7",
 			res:      mockResourceWithoutIgnorePolicy("service", "ns1", "s2"),
+			expected: &cluster.SyncDef{},
+		},
+		{
+			msg: "Marked as belonging to another sync set during sync delete",
+			repoRes: map[string]resource.Resource{
+				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
+			},
+			id:       "res7",
+			res:      mockResourceWithSyncMark("service", "ns1", "s2", syncMark("other-sync", "res7")),
+			expected: &cluster.SyncDef{},
+		},
+		{
+			msg: "Marked as copied from another resource during sync delete",
+			repoRes: map[string]resource.Resource{
+				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
+			},
+			id:       "res7",
+			res:      mockResourceWithSyncMark("service", "ns1", "s2", syncMark(gitconf.SyncTag, "res1")),
+			expected: &cluster.SyncDef{},
+		},
+		{
+			msg: "Marked as ours and still in the repo during sync delete",
+			repoRes: map[string]resource.Resource{
+				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
+				"res7": mockResourceWithoutIgnorePolicy("service", "ns1", "s2"),
+			},
+			id:       "res7",
+			res:      mockResourceWithSyncMark("service", "ns1", "s2", syncMark(gitconf.SyncTag, "res7")),
+			expected: &cluster.SyncDef{},
+		},
+		{
+			msg: "Marked as ours and a dry run during sync delete",
+			repoRes: map[string]resource.Resource{
+				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
+			},
+			id:       "res7",
+			res:      mockResourceWithSyncMark("service", "ns1", "s2", syncMark(gitconf.SyncTag, "res7")),
+			dryRun:   true,
+			expected: &cluster.SyncDef{},
+		},
+		{
+			msg: "Marked as ours and no policy to ignore during sync delete",
+			repoRes: map[string]resource.Resource{
+				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
+				"res2": mockResourceWithoutIgnorePolicy("namespace", "ns2", "ns2"),
+				"res3": mockResourceWithoutIgnorePolicy("namespace", "ns3", "ns3"),
+				"res4": mockResourceWithoutIgnorePolicy("deployment", "ns1", "d1"),
+				"res5": mockResourceWithoutIgnorePolicy("deployment", "ns2", "d2"),
+				"res6": mockResourceWithoutIgnorePolicy("service", "ns3", "s1"),
+			},
+			id:       "res7",
+			res:      mockResourceWithSyncMark("service", "ns1", "s2", syncMark(gitconf.SyncTag, "res7")),
 			expected: &cluster.SyncDef{Actions: []cluster.SyncAction{cluster.SyncAction{ResourceID: "res7", Delete: cluster.ResourceDef{}, Apply: cluster.ResourceDef(nil)}}},
 		},
+		{
+			msg: "Marked as ours and report only during sync delete",
+			repoRes: map[string]resource.Resource{
+				"res1": mockResourceWithoutIgnorePolicy("namespace", "ns1", "ns1"),
+			},
+			id:       "res7",
+			res:      mockResourceWithSyncMark("service", "ns1", "s2", syncMark(gitconf.SyncTag, "res7")),
+			report:   true,
+			expected: &cluster.SyncDef{},
+		},
 	}
 
 	logger := log.NewNopLogger()
 	for _, sc := range tests {
 		sync := &cluster.SyncDef{}
-		prepareSyncDelete(logger, sc.repoRes, sc.id, sc.res, sync)
+		reported := map[string]resource.Resource{}
+		conf := Config{SyncSetName: gitconf.SyncTag, GC: true, GCDryRun: sc.dryRun, ReportOnly: sc.report}
+		prepareSyncDelete(logger, conf, sc.repoRes, sc.id, sc.res, sync, reported)
 
 		if !reflect.DeepEqual(sc.expected, sync) {
 			t.Errorf("%s: expected %+v, got %+v\n", sc.msg, sc.expected, sync)
 		}
+		if _, ok := reported[sc.id]; ok != sc.report {
+			t.Errorf("%s: expected reported to be %v", sc.msg, sc.report)
+		}
 	}
 }
 
@@ -128,6 +377,7 @@ func TestPrepareSyncApply(t *testing.T) {
 		clusRes  map[string]resource.Resource
 		id       string
 		res      resource.Resource
+		report   bool
 		expected *cluster.SyncDef
 	}{
 		{
@@ -165,21 +415,86 @@ func TestPrepareSyncApply(t *testing.T) {
 			res:      mockResourceWithoutIgnorePolicy("service", "ns1", "s2"),
 			expected: &cluster.SyncDef{Actions: []cluster.SyncAction{cluster.SyncAction{ResourceID: "res7", Apply: cluster.ResourceDef{}, Delete: cluster.ResourceDef(nil)}}},
 		},
+		{
+			msg:      "Policy to report during sync apply",
+			clusRes:  map[string]resource.Resource{},
+			id:       "res7",
+			res:      mockResourceWithReportPolicy("service", "ns1", "s2"),
+			report:   true,
+			expected: &cluster.SyncDef{},
+		},
+		{
+			msg: "Policy to report in the cluster during sync apply",
+			clusRes: map[string]resource.Resource{
+				"res7": mockResourceWithReportPolicy("service", "ns1", "s2"),
+			},
+			id:       "res7",
+			res:      mockResourceWithoutIgnorePolicy("service", "ns1", "s2"),
+			report:   true,
+			expected: &cluster.SyncDef{},
+		},
 	}
 
 	logger := log.NewNopLogger()
 	for _, sc := range tests {
 		sync := &cluster.SyncDef{}
-		prepareSyncApply(logger, sc.clusRes, sc.id, sc.res, sync)
+		reported := map[string]resource.Resource{}
+		prepareSyncApply(logger, Config{}, sc.clusRes, sc.id, sc.res, sync, reported)
 
 		if !reflect.DeepEqual(sc.expected, sync) {
 			t.Errorf("%s: expected %+v, got %+v\n", sc.msg, sc.expected, sync)
 		}
+		if _, ok := reported[sc.id]; ok != sc.report {
+			t.Errorf("%s: expected reported to be %v", sc.msg, sc.report)
+		}
 	}
 }
 
 // ---
 
+const multidocDef = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: multi-a
+data:
+  a: "1"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: multi-b
+data:
+  b: "2"
+`
+
+const listDef = `apiVersion: v1
+kind: List
+metadata:
+  name: things
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: listed
+`
+
+const unmanagedID = "default:deployment/unmanaged"
+
+const unmanagedDef = `---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: unmanaged
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: unmanaged
+        image: quay.io/weaveworks/unmanaged:1
+`
+
 var gitconf = git.Config{
 	SyncTag:   "test-sync",
 	NotesRef:  "test-notes",
@@ -247,6 +562,14 @@ func (p *syncCluster) Export() ([]byte, error) {
 	return bytes.Join(configs, []byte("\n---\n")), nil
 }
 
+func bytesToStrings(resources map[string][]byte) map[string]string {
+	res := map[string]string{}
+	for k, r := range resources {
+		res[k] = string(r)
+	}
+	return res
+}
+
 func resourcesToStrings(resources map[string]resource.Resource) map[string]string {
 	res := map[string]string{}
 	for k, r := range resources {
@@ -257,7 +580,7 @@ func resourcesToStrings(resources map[string]resource.Resource) map[string]strin
 
 // Our invariant is that the model we can export from the platform
 // should always reflect what's in git. So, let's check that.
-func checkClusterMatchesFiles(t *testing.T, m cluster.Manifests, c cluster.Cluster, dir string) {
+func checkClusterMatchesFiles(t *testing.T, m cluster.Manifests, c cluster.Cluster, dirs []string) {
 	conf, err := c.Export()
 	if err != nil {
 		t.Fatal(err)
@@ -266,12 +589,22 @@ func checkClusterMatchesFiles(t *testing.T, m cluster.Manifests, c cluster.Clust
 	if err != nil {
 		t.Fatal(err)
 	}
-	files, err := m.LoadManifests(dir)
+	files, err := m.LoadManifests(dirs...)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	expected := resourcesToStrings(files)
+	// Everything applied will have been marked as ours
+	expected := map[string]string{}
+	for id, res := range files {
+		marked, err := m.UpdatePolicies(res.Bytes(), policy.Update{
+			Add: policy.Set{policy.SyncMark: syncMark(gitconf.SyncTag, id)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[id] = string(marked)
+	}
 	got := resourcesToStrings(resources)
 
 	if !reflect.DeepEqual(expected, got) {
@@ -3,6 +3,7 @@ package update
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/weaveworks/flux"
@@ -18,10 +19,45 @@ type Change struct {
 	ServiceID flux.ResourceID
 	Container cluster.Container
 	ImageID   image.Ref
+	// For a promotion, the controller the image is promoted from
+	Source flux.ResourceID
 }
 
 func (a *Automated) Add(service flux.ResourceID, container cluster.Container, image image.Ref) {
-	a.Changes = append(a.Changes, Change{service, container, image})
+	a.Changes = append(a.Changes, Change{ServiceID: service, Container: container, ImageID: image})
+}
+
+// AddPromotion adds a change promoting the image given from the
+// source controller.
+func (a *Automated) AddPromotion(service flux.ResourceID, container cluster.Container, image image.Ref, source flux.ResourceID) {
+	a.Changes = append(a.Changes, Change{ServiceID: service, Container: container, ImageID: image, Source: source})
+}
+
+// SoakingImage is an image seen running healthily in a container of
+// a controller that images are promoted from, and since when.
+type SoakingImage struct {
+	ServiceID flux.ResourceID `json:"serviceID"`
+	Container string          `json:"container"`
+	Image     image.Ref       `json:"image"`
+	Since     time.Time       `json:"since"`
+}
+
+// Promotions gives the changes that promote an image from another
+// controller.
+func (a *Automated) Promotions() []Change {
+	var promotions []Change
+	for _, change := range a.Changes {
+		if change.IsPromotion() {
+			promotions = append(promotions, change)
+		}
+	}
+	return promotions
+}
+
+// IsPromotion says whether the change promotes an image from another
+// controller, rather than releasing a new image.
+func (c Change) IsPromotion() bool {
+	return c.Source != flux.ResourceID{}
 }
 
 func (a *Automated) CalculateRelease(rc ReleaseContext, logger log.Logger) ([]*ControllerUpdate, Result, error) {
@@ -29,8 +65,16 @@ func (a *Automated) CalculateRelease(rc ReleaseContext, logger log.Logger) ([]*C
 		&IncludeFilter{a.serviceIDs()},
 	}
 
+	services, err := rc.ServicesWithPolicies()
+	if err != nil {
+		return nil, nil, err
+	}
+	postfilters := []ControllerFilter{
+		&WindowFilter{services, rc.DeploySchedule(), time.Now()},
+	}
+
 	result := Result{}
-	updates, err := rc.SelectServices(result, prefilters, nil)
+	updates, err := rc.SelectServices(result, prefilters, postfilters)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -53,11 +97,29 @@ func (a *Automated) ReleaseKind() ReleaseKind {
 }
 
 func (a *Automated) CommitMessage() string {
-	var images []string
-	for _, image := range a.Images() {
-		images = append(images, image.String())
+	promotions := a.Promotions()
+	if len(promotions) == 0 {
+		var images []string
+		for _, image := range a.Images() {
+			images = append(images, image.String())
+		}
+		return fmt.Sprintf("Release %s to automated", strings.Join(images, ", "))
+	}
+
+	var released Automated
+	for _, change := range a.Changes {
+		if !change.IsPromotion() {
+			released.Changes = append(released.Changes, change)
+		}
+	}
+	var parts []string
+	if len(released.Changes) > 0 {
+		parts = append(parts, released.CommitMessage())
+	}
+	for _, p := range promotions {
+		parts = append(parts, fmt.Sprintf("Promote %s from %s to %s", p.ImageID, p.Source, p.ServiceID))
 	}
-	return fmt.Sprintf("Release %s to automated", strings.Join(images, ", "))
+	return strings.Join(parts, "; ")
 }
 
 func (a *Automated) Images() []image.Ref {
@@ -110,7 +172,9 @@ func (a *Automated) calculateImageUpdates(rc ReleaseContext, candidates []*Contr
 					continue
 				}
 
-				newImageID := currentImageID.WithNewTag(change.ImageID.Tag)
+				// The change says whether to pin the image, by
+				// giving a digest or not
+				newImageID := currentImageID.WithNewTag(change.ImageID.Tag).WithDigest(change.ImageID.Digest)
 				u.ManifestBytes, err = rc.Manifests().UpdateDefinition(u.ManifestBytes, container.Name, newImageID)
 				if err != nil {
 					return nil, err
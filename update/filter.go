@@ -1,8 +1,11 @@
 package update
 
 import (
+	"time"
+
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
 )
 
 const (
@@ -15,6 +18,8 @@ const (
 	ImageNotFound   = "cannot find one or more images"
 	ImageUpToDate   = "image(s) up to date"
 	DoesNotUseImage = "does not use image(s)"
+	OutsideWindow   = "outside deployment window"
+	Frozen          = "in change freeze"
 )
 
 type SpecificImageFilter struct {
@@ -91,3 +96,35 @@ func (f *LockedFilter) Filter(u ControllerUpdate) ControllerResult {
 	}
 	return ControllerResult{}
 }
+
+// WindowFilter skips controllers that may not be released at the
+// time given, according to their deployment windows and freezes (or
+// those of the default schedule).
+type WindowFilter struct {
+	Policies policy.ResourceMap
+	Default  policy.Schedule
+	Now      time.Time
+}
+
+func (f *WindowFilter) Filter(u ControllerUpdate) ControllerResult {
+	schedule, err := f.Default.WithPolicies(f.Policies[u.ResourceID])
+	if err != nil {
+		return ControllerResult{
+			Status: ReleaseStatusFailed,
+			Error:  err.Error(),
+		}
+	}
+	switch {
+	case schedule.InFreeze(f.Now):
+		return ControllerResult{
+			Status: ReleaseStatusSkipped,
+			Error:  Frozen,
+		}
+	case !schedule.InWindow(f.Now):
+		return ControllerResult{
+			Status: ReleaseStatusSkipped,
+			Error:  OutsideWindow,
+		}
+	}
+	return ControllerResult{}
+}
@@ -2,15 +2,14 @@ package update
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
-	glob "github.com/ryanuber/go-glob"
 
 	"github.com/weaveworks/flux/cluster"
 	fluxerr "github.com/weaveworks/flux/errors"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/registry"
 )
 
@@ -21,26 +20,48 @@ type ImageMap struct {
 }
 
 // LatestImage returns the latest releasable image for a repository
-// for which the tag matches a given pattern. A releasable image is
-// one that is not tagged "latest". (Assumes the available images are
-// in descending order of latestness.) If no such image exists,
+// for which the tag matches a given pattern, as the pattern decides
+// which is latest (by creation time, or by tag; see
+// policy.Ordering). A releasable image is one that is not tagged
+// "latest", unless that's what the pattern asks for. (Assumes the
+// available images are in descending order of creation, so the
+// first of those equally late is taken.) If no such image exists,
 // returns a zero value and `false`, and the caller can decide whether
 // that's an error or not.
-func (m ImageMap) LatestImage(repo image.Name, tagGlob string) (image.Info, bool) {
+func (m ImageMap) LatestImage(repo image.Name, pattern policy.Pattern) (image.Info, bool) {
+	var latest image.Info
+	var found bool
 	for _, available := range m.images[repo.CanonicalName()] {
-		tag := available.ID.Tag
-		// Ignore latest if and only if it's not what the user wants.
-		if !strings.EqualFold(tagGlob, "latest") && strings.EqualFold(tag, "latest") {
+		if !pattern.Matches(available.ID.Tag) {
 			continue
 		}
-		if glob.Glob(tagGlob, tag) {
-			var im image.Info
-			im = available
-			im.ID = repo.ToRef(tag)
-			return im, true
+		if found && !pattern.Newer(&available, &latest) {
+			continue
 		}
+		latest, found = available, true
+	}
+	if found {
+		latest.ID = repo.ToRef(latest.ID.Tag)
+	}
+	return latest, found
+}
+
+// TargetImage gives the image to release to a container running the
+// current image given, to bring it up to the latest image; and
+// whether that's any different. If pinning images by digest, the
+// target is pinned to the latest image's digest, so that a tag
+// pushed again (or a tag before pinned) makes for a new image; if
+// not, a digest already in place is left alone, as long as the tag
+// is the same.
+func TargetImage(current image.Ref, latest image.Info, pin bool) (image.Ref, bool) {
+	target := current
+	if latest.ID.Tag != current.Tag {
+		target = current.WithNewTag(latest.ID.Tag)
+	}
+	if pin && latest.Digest != "" {
+		target = target.WithDigest(latest.Digest)
 	}
-	return image.Info{}, false
+	return target, target != current
 }
 
 // Available returns image.Info entries for all the images in the
@@ -100,24 +121,17 @@ func exactImages(reg registry.Registry, images []image.Ref) (ImageMap, error) {
 	m := infoMap{}
 	for _, id := range images {
 		// We must check that the exact images requested actually exist. Otherwise we risk pushing invalid images to git.
-		exist, err := imageExists(reg, id)
+		info, err := reg.GetImage(id)
 		if err != nil {
-			return ImageMap{}, errors.Wrap(image.ErrInvalidImageID, err.Error())
-		}
-		if !exist {
 			return ImageMap{}, errors.Wrap(image.ErrInvalidImageID, fmt.Sprintf("image %q does not exist", id))
 		}
-		m[id.CanonicalName()] = []image.Info{{ID: id}}
+		// An image given with a digest is released with that digest,
+		// whatever the tag points to now
+		info.ID = id.WithDigest("")
+		if id.Digest != "" {
+			info.Digest = id.Digest
+		}
+		m[id.CanonicalName()] = []image.Info{info}
 	}
 	return ImageMap{m}, nil
 }
-
-// Checks whether the given image exists in the repository.
-// Return true if exist, false otherwise
-func imageExists(reg registry.Registry, imageID image.Ref) (bool, error) {
-	_, err := reg.GetImage(imageID)
-	if err != nil {
-		return false, nil
-	}
-	return true, nil
-}
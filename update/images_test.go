@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
 )
 
 var (
@@ -24,14 +25,14 @@ func TestDecanon(t *testing.T) {
 		name: infos,
 	}}
 
-	latest, ok := m.LatestImage(mustParseName("weaveworks/helloworld"), "*")
+	latest, ok := m.LatestImage(mustParseName("weaveworks/helloworld"), policy.PatternAll)
 	if !ok {
 		t.Error("did not find latest image")
 	} else if latest.ID.Name != mustParseName("weaveworks/helloworld") {
 		t.Error("name did not match what was asked")
 	}
 
-	latest, ok = m.LatestImage(mustParseName("index.docker.io/weaveworks/helloworld"), "*")
+	latest, ok = m.LatestImage(mustParseName("index.docker.io/weaveworks/helloworld"), policy.PatternAll)
 	if !ok {
 		t.Error("did not find latest image")
 	} else if latest.ID.Name != mustParseName("index.docker.io/weaveworks/helloworld") {
@@ -49,6 +50,69 @@ func TestDecanon(t *testing.T) {
 	}
 }
 
+// TestSemverLatest checks that a semver pattern picks the highest
+// matching version, rather than the image created most recently.
+func TestSemverLatest(t *testing.T) {
+	now := time.Now()
+	m := ImageMap{infoMap{name: {
+		{ID: name.ToRef("latest"), CreatedAt: now},
+		{ID: name.ToRef("1.2.9"), CreatedAt: now.Add(-time.Minute)}, // a rebuild
+		{ID: name.ToRef("1.4.0-rc.1"), CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: name.ToRef("2.0.0"), CreatedAt: now.Add(-time.Hour)},
+		{ID: name.ToRef("1.3.0"), CreatedAt: now.Add(-2 * time.Hour)},
+	}}}
+	helloworld := mustParseName("weaveworks/helloworld")
+
+	for pattern, expected := range map[string]string{
+		"semver:<2":           "1.3.0",
+		"semver:~1.2":         "1.2.9",
+		"semver:>=1.4.0-rc.0": "2.0.0",
+		"semver:1.4.x":        "",
+		"glob:1.*":            "1.2.9",
+	} {
+		latest, ok := m.LatestImage(helloworld, policy.NewPattern(pattern))
+		switch {
+		case expected == "" && ok:
+			t.Errorf("%s: expected no image, got %s", pattern, latest.ID)
+		case expected != "" && (!ok || latest.ID.Tag != expected):
+			t.Errorf("%s: expected %s, got %s", pattern, expected, latest.ID)
+		}
+	}
+}
+
+func TestTargetImage(t *testing.T) {
+	const oldDigest, newDigest = "sha256:0ld", "sha256:n3w"
+	current := mustParseRef("helloworld:v1")
+	pinned := current.WithDigest(oldDigest)
+	for _, c := range []struct {
+		current  image.Ref
+		latest   image.Info
+		pin      bool
+		expected string
+	}{
+		{current, image.Info{ID: current, Digest: oldDigest}, false, ""},
+		{current, image.Info{ID: name.ToRef("v2"), Digest: newDigest}, false, "helloworld:v2"},
+		{current, image.Info{ID: current, Digest: oldDigest}, true, "helloworld:v1@" + oldDigest},
+		{current, image.Info{ID: name.ToRef("v2"), Digest: newDigest}, true, "helloworld:v2@" + newDigest},
+		// the tag has been pushed again
+		{pinned, image.Info{ID: current, Digest: newDigest}, true, "helloworld:v1@" + newDigest},
+		{pinned, image.Info{ID: current, Digest: oldDigest}, true, ""},
+		// a digest in place is left alone, if not pinning
+		{pinned, image.Info{ID: current, Digest: newDigest}, false, ""},
+		{pinned, image.Info{ID: name.ToRef("v2"), Digest: newDigest}, false, "helloworld:v2"},
+		// nothing to pin to
+		{current, image.Info{ID: name.ToRef("v2")}, true, "helloworld:v2"},
+	} {
+		target, changed := TargetImage(c.current, c.latest, c.pin)
+		switch {
+		case c.expected == "" && changed:
+			t.Errorf("%s, latest %s@%s: expected no change, got %s", c.current, c.latest.ID, c.latest.Digest, target)
+		case c.expected != "" && (!changed || target.String() != c.expected):
+			t.Errorf("%s, latest %s@%s: expected %s, got %s", c.current, c.latest.ID, c.latest.Digest, c.expected, target)
+		}
+	}
+}
+
 func TestAvail(t *testing.T) {
 	m := ImageMap{infoMap{name: infos}}
 	avail := m.Available(mustParseName("weaveworks/goodbyeworld"))
@@ -0,0 +1,40 @@
+package update
+
+import (
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+// PendingRelease is an automated release of a controller that has
+// been proposed rather than made, because the controller's automated
+// releases must be approved. It waits until it's approved, when it's
+// made as any other release, or rejected.
+type PendingRelease struct {
+	ID         string          `json:"id"`
+	ServiceID  flux.ResourceID `json:"serviceID"`
+	Spec       Automated       `json:"spec"`
+	ProposedAt time.Time       `json:"proposedAt"`
+	// A rejected release is kept (but not listed), so that it isn't
+	// proposed again; until there's something newer to release
+	Rejected   bool   `json:"rejected,omitempty"`
+	RejectedBy string `json:"rejectedBy,omitempty"`
+}
+
+// SameChanges says whether the release would make the same changes
+// as those given.
+func (p PendingRelease) SameChanges(changes []Change) bool {
+	if len(p.Spec.Changes) != len(changes) {
+		return false
+	}
+	proposed := map[string]string{}
+	for _, c := range p.Spec.Changes {
+		proposed[c.Container.Name] = c.ImageID.String()
+	}
+	for _, c := range changes {
+		if image, ok := proposed[c.Container.Name]; !ok || image != c.ImageID.String() {
+			return false
+		}
+	}
+	return true
+}
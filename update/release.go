@@ -3,6 +3,7 @@ package update
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -50,6 +51,11 @@ type ReleaseContext interface {
 	ServicesWithPolicies() (policy.ResourceMap, error)
 	Registry() registry.Registry
 	Manifests() cluster.Manifests
+	DeploySchedule() policy.Schedule
+	// DigestPinning says whether to pin all released images by
+	// digest, rather than just those for controllers with a
+	// pin_digest policy
+	DigestPinning() bool
 }
 
 // NB: these get sent from fluxctl, so we have to maintain the json format of
@@ -59,6 +65,9 @@ type ReleaseSpec struct {
 	ImageSpec    ImageSpec
 	Kind         ReleaseKind
 	Excludes     []flux.ResourceID
+	// Release even those controllers outside their deployment window
+	// or in a change freeze
+	Force bool `json:",omitempty"`
 }
 
 // ReleaseType gives a one-word description of the release, mainly
@@ -156,9 +165,14 @@ func (s ReleaseSpec) filters(rc ReleaseContext) ([]ControllerFilter, []Controlle
 	if err != nil {
 		return nil, nil, err
 	}
-	lockedSet := services.OnlyWithPolicy(policy.Locked)
+	lockedSet := services.OnlyLocked(time.Now())
 	postfilters = append(postfilters, &LockedFilter{lockedSet.ToSlice()})
 
+	// Deployment window filter
+	if !s.Force {
+		postfilters = append(postfilters, &WindowFilter{services, rc.DeploySchedule(), time.Now()})
+	}
+
 	return prefilters, postfilters, nil
 }
 
@@ -190,6 +204,7 @@ func (s ReleaseSpec) calculateImageUpdates(rc ReleaseContext, candidates []*Cont
 	// Compile an `ImageMap` of all relevant images
 	var images ImageMap
 	var singleRepo image.CanonicalName
+	var singleDigest string // if an image is given with a digest, it's pinned to that
 	var err error
 
 	switch s.ImageSpec {
@@ -200,6 +215,7 @@ func (s ReleaseSpec) calculateImageUpdates(rc ReleaseContext, candidates []*Cont
 		ref, err = s.ImageSpec.AsRef()
 		if err == nil {
 			singleRepo = ref.CanonicalName()
+			singleDigest = ref.Digest
 			images, err = exactImages(rc.Registry(), []image.Ref{ref})
 		}
 	}
@@ -208,6 +224,11 @@ func (s ReleaseSpec) calculateImageUpdates(rc ReleaseContext, candidates []*Cont
 		return nil, err
 	}
 
+	services, err := rc.ServicesWithPolicies()
+	if err != nil {
+		return nil, err
+	}
+
 	// Look through all the services' containers to see which have an
 	// image that could be updated.
 	var updates []*ControllerUpdate
@@ -226,6 +247,7 @@ func (s ReleaseSpec) calculateImageUpdates(rc ReleaseContext, candidates []*Cont
 		// for the purpose of filtering the output.
 		ignoredOrSkipped := ReleaseStatusIgnored
 		var containerUpdates []ContainerUpdate
+		pin := singleDigest != "" || rc.DigestPinning() || services[u.ResourceID].Contains(policy.PinDigest)
 
 		for _, container := range containers {
 			currentImageID, err := image.ParseRef(container.Image)
@@ -235,7 +257,7 @@ func (s ReleaseSpec) calculateImageUpdates(rc ReleaseContext, candidates []*Cont
 				return nil, err
 			}
 
-			latestImage, ok := images.LatestImage(currentImageID.Name, "*")
+			latestImage, ok := images.LatestImage(currentImageID.Name, policy.PatternAll)
 			if !ok {
 				if currentImageID.CanonicalName() != singleRepo {
 					ignoredOrSkipped = ReleaseStatusIgnored
@@ -245,15 +267,14 @@ func (s ReleaseSpec) calculateImageUpdates(rc ReleaseContext, candidates []*Cont
 				continue
 			}
 
-			if currentImageID == latestImage.ID {
-				ignoredOrSkipped = ReleaseStatusSkipped
-				continue
-			}
-
 			// We want to update the image with respect to the form it
 			// appears in the manifest, whereas what we have is the
 			// canonical form.
-			newImageID := currentImageID.WithNewTag(latestImage.ID.Tag)
+			newImageID, changed := TargetImage(currentImageID, latestImage, pin)
+			if !changed {
+				ignoredOrSkipped = ReleaseStatusSkipped
+				continue
+			}
 
 			u.ManifestBytes, err = rc.Manifests().UpdateDefinition(u.ManifestBytes, container.Name, newImageID)
 			if err != nil {
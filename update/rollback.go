@@ -0,0 +1,111 @@
+package update
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+)
+
+// RollbackSpec asks for a controller's containers to be set back to
+// the images they had before a release (as recorded in the notes on
+// its commit).
+type RollbackSpec struct {
+	ServiceID flux.ResourceID
+	// The release to roll back, given as the ID of its job or (a
+	// prefix of) its commit; if empty, the latest release of the
+	// controller.
+	To string `json:",omitempty"`
+	// Lock the controller, so it isn't released again (e.g., by
+	// automation) before whatever was wrong is fixed
+	Lock bool `json:",omitempty"`
+}
+
+// RollbackRelease is a RollbackSpec resolved against the history of
+// releases: the release being rolled back, and the image for each
+// container from before it.
+type RollbackRelease struct {
+	Spec     RollbackSpec
+	Revision string
+	Images   map[string]image.Ref // by container name
+}
+
+func (r *RollbackRelease) CalculateRelease(rc ReleaseContext, logger log.Logger) ([]*ControllerUpdate, Result, error) {
+	prefilters := []ControllerFilter{
+		&IncludeFilter{[]flux.ResourceID{r.Spec.ServiceID}},
+	}
+
+	result := Result{}
+	updates, err := rc.SelectServices(result, prefilters, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := result[r.Spec.ServiceID]; !ok {
+		result[r.Spec.ServiceID] = ControllerResult{
+			Status: ReleaseStatusSkipped,
+			Error:  NotInRepo,
+		}
+	}
+
+	var rollbacks []*ControllerUpdate
+	for _, u := range updates {
+		containers, err := u.Controller.ContainersOrError()
+		if err != nil {
+			result[u.ResourceID] = ControllerResult{
+				Status: ReleaseStatusFailed,
+				Error:  err.Error(),
+			}
+			continue
+		}
+
+		var containerUpdates []ContainerUpdate
+		for _, container := range containers {
+			previous, ok := r.Images[container.Name]
+			if !ok {
+				continue
+			}
+			currentImageID, err := image.ParseRef(container.Image)
+			if err != nil {
+				return nil, nil, err
+			}
+			u.ManifestBytes, err = rc.Manifests().UpdateDefinition(u.ManifestBytes, container.Name, previous)
+			if err != nil {
+				return nil, nil, err
+			}
+			containerUpdates = append(containerUpdates, ContainerUpdate{
+				Container: container.Name,
+				Current:   currentImageID,
+				Target:    previous,
+			})
+		}
+
+		if len(containerUpdates) > 0 {
+			u.Updates = containerUpdates
+			rollbacks = append(rollbacks, u)
+			result[u.ResourceID] = ControllerResult{
+				Status:       ReleaseStatusSuccess,
+				PerContainer: containerUpdates,
+			}
+		} else {
+			result[u.ResourceID] = ControllerResult{
+				Status: ReleaseStatusIgnored,
+				Error:  DoesNotUseImage,
+			}
+		}
+	}
+	return rollbacks, result, nil
+}
+
+func (r *RollbackRelease) ReleaseType() ReleaseType {
+	return "rollback"
+}
+
+func (r *RollbackRelease) ReleaseKind() ReleaseKind {
+	return ReleaseKindExecute
+}
+
+func (r *RollbackRelease) CommitMessage() string {
+	return fmt.Sprintf("Roll back %s to images from before %.7s", r.Spec.ServiceID, r.Revision)
+}
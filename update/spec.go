@@ -8,9 +8,10 @@ import (
 )
 
 const (
-	Images = "image"
-	Policy = "policy"
-	Auto   = "auto"
+	Images   = "image"
+	Policy   = "policy"
+	Auto     = "auto"
+	Rollback = "rollback"
 )
 
 // How did this update get triggered?
@@ -19,7 +20,7 @@ type Cause struct {
 	User    string
 }
 
-// A tagged union for all (both) kinds of update. The type is just so
+// A tagged union for all kinds of update. The type is just so
 // we know how to decode the rest of the struct.
 type Spec struct {
 	Type  string      `json:"type"`
@@ -58,6 +59,12 @@ func (spec *Spec) UnmarshalJSON(in []byte) error {
 			return err
 		}
 		spec.Spec = update
+	case Rollback:
+		var update RollbackSpec
+		if err := json.Unmarshal(wire.SpecBytes, &update); err != nil {
+			return err
+		}
+		spec.Spec = update
 	default:
 		return errors.New("unknown spec type: " + wire.Type)
 	}